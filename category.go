@@ -4,6 +4,8 @@ import (
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigateway"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsdynamodb"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsevents"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awseventstargets"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
 
@@ -13,11 +15,13 @@ import (
 
 type CategoryStackProps struct {
 	awscdk.StackProps
-	s3bucket awss3.Bucket
-	version  awsapigateway.Resource
+	s3bucket   awss3.Bucket
+	imageTable awsdynamodb.Table
+	slugTable  awsdynamodb.Table
+	version    awsapigateway.Resource
 }
 
-func NewCategoryStack(stack constructs.Construct, props *CategoryStackProps) {
+func NewCategoryStack(stack constructs.Construct, props *CategoryStackProps) awsdynamodb.Table {
 	table := awsdynamodb.NewTable(stack, jsii.String("CategoryDynamodb"), &awsdynamodb.TableProps{
 		TableName:     jsii.String("category"),
 		RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
@@ -32,25 +36,78 @@ func NewCategoryStack(stack constructs.Construct, props *CategoryStackProps) {
 		Code:         awslambda.AssetCode_FromAsset(jsii.String("./category/assets/lambda.zip"), nil),
 		Runtime:      awslambda.Runtime_PROVIDED_AL2023(),
 		Architecture: awslambda.Architecture_ARM_64(),
-		MemorySize:   jsii.Number(128),
+		MemorySize:   jsii.Number(512),
 		Handler:      jsii.String("bootstrap"),
 		Timeout:      awscdk.Duration_Seconds(jsii.Number(30)),
 		Environment: &map[string]*string{
-			"BUCKET_NAME": props.s3bucket.BucketName(),
+			"BUCKET_NAME":  props.s3bucket.BucketName(),
+			"IMAGE_WIDTHS": jsii.String("150,600,1200"),
 		},
 	})
 
+	// category renames are copied to the products through GSI_CATEGORY
+	productTable := awsdynamodb.Table_FromTableAttributes(stack, jsii.String("CategoryProductDynamodb"), &awsdynamodb.TableAttributes{
+		TableName:     jsii.String("product"),
+		GlobalIndexes: jsii.Strings("GSI_CATEGORY"),
+	})
+
 	table.GrantReadWriteData(lambdaFunc)
+	productTable.GrantReadWriteData(lambdaFunc)
+	props.imageTable.GrantReadWriteData(lambdaFunc)
+	props.slugTable.GrantReadWriteData(lambdaFunc)
 	props.s3bucket.GrantReadWrite(lambdaFunc, nil)
 
+	purgeFunc := awslambda.NewFunction(stack, jsii.String("CategoryPurgeLambda"), &awslambda.FunctionProps{
+		FunctionName: jsii.String("purge-category"),
+		Code:         awslambda.AssetCode_FromAsset(jsii.String("./category/assets/purge.zip"), nil),
+		Runtime:      awslambda.Runtime_PROVIDED_AL2023(),
+		Architecture: awslambda.Architecture_ARM_64(),
+		MemorySize:   jsii.Number(128),
+		Handler:      jsii.String("bootstrap"),
+		Timeout:      awscdk.Duration_Minutes(jsii.Number(5)),
+		Environment: &map[string]*string{
+			"BUCKET_NAME":    props.s3bucket.BucketName(),
+			"RETENTION_DAYS": jsii.String("30"),
+		},
+	})
+
+	table.GrantReadWriteData(purgeFunc)
+	props.imageTable.GrantReadWriteData(purgeFunc)
+	props.slugTable.GrantReadWriteData(purgeFunc)
+	props.s3bucket.GrantReadWrite(purgeFunc, nil)
+
+	awsevents.NewRule(stack, jsii.String("CategoryPurgeSchedule"), &awsevents.RuleProps{
+		Schedule: awsevents.Schedule_Cron(&awsevents.CronOptions{
+			Minute: jsii.String("0"),
+			Hour:   jsii.String("3"),
+		}),
+		Targets: &[]awsevents.IRuleTarget{
+			awseventstargets.NewLambdaFunction(purgeFunc, nil),
+		},
+	})
+
 	var (
-		categories     = props.version.AddResource(jsii.String("categories"), nil)
-		categoriesUuid = categories.ResourceForPath(jsii.String("{uuid}"))
-		options        = &awsapigateway.LambdaIntegrationOptions{
+		categories        = props.version.AddResource(jsii.String("categories"), nil)
+		categoriesUuid    = categories.ResourceForPath(jsii.String("{uuid}"))
+		categoriesTrash   = categories.AddResource(jsii.String("trash"), nil)
+		categoriesTree    = categories.AddResource(jsii.String("tree"), nil)
+		categoriesOrder   = categories.AddResource(jsii.String("order"), nil)
+		categoriesRestore = categoriesUuid.AddResource(jsii.String("restore"), nil)
+		categoriesSlug    = categories.AddResource(jsii.String("by-slug"), nil).AddResource(jsii.String("{slug}"), nil)
+		options           = &awsapigateway.LambdaIntegrationOptions{
 			AllowTestInvoke: jsii.Bool(false),
 		}
 	)
 	categories.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
 	categories.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	categoriesOrder.AddMethod(jsii.String("PUT"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	categoriesUuid.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	categoriesUuid.AddMethod(jsii.String("PUT"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
 	categoriesUuid.AddMethod(jsii.String("DELETE"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	categoriesSlug.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	categoriesTree.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	categoriesTrash.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	categoriesRestore.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+
+	return table
 }
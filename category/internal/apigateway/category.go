@@ -6,51 +6,62 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"os"
 	"shopy/internal/domain"
 	"shopy/internal/models"
+	"shopy/pkg/imagex"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/google/uuid"
 )
 
 type Service interface {
 	GetCategories(ctx context.Context) (models.Categories, error)
+	GetCategoryTree(ctx context.Context) (models.CategoryTree, error)
+	GetCategory(ctx context.Context, uuid string) (*models.Category, error)
+	GetCategoryBySlug(ctx context.Context, slug string) (*models.Category, error)
+	ReorderCategories(ctx context.Context, order domain.CategoryOrder) error
 	AddCategory(ctx context.Context, params domain.CategoryParams) (*models.Category, error)
-	DelCategory(ctx context.Context, uuid string) error
+	PutCategory(ctx context.Context, params domain.CategoryParams) (*models.CategoryUpdate, error)
+	DelCategory(ctx context.Context, params domain.CategoryDelete) error
+	GetTrashCategories(ctx context.Context) (models.Categories, error)
+	RestoreCategory(ctx context.Context, uuid string) (*models.Category, error)
 }
 
 type Category struct {
 	logger  *slog.Logger
 	service Service
+	limits  imagex.Limits
 }
 
 func NewCategory(logger *slog.Logger, service Service) *Category {
 	return &Category{
 		logger:  logger,
 		service: service,
+		limits:  imagex.NewLimits(os.Getenv("IMAGE_MAX_BYTES"), os.Getenv("IMAGE_MAX_DIMENSION")),
 	}
 }
 
 func (c *Category) Router() APIGatewayFunc {
-	return func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-		switch event.HTTPMethod {
-		case http.MethodGet:
-			return c.HandleGetCategories(ctx, event)
-		case http.MethodPost:
-			return c.HandleAddCategory(ctx, event)
-		case http.MethodDelete:
-			return c.HandleDelCategory(ctx, event)
-		}
-		return events.APIGatewayProxyResponse{
-			Body:       "method is not valid",
-			StatusCode: http.StatusMethodNotAllowed,
-		}, nil
+	routes := Routes{
+		"GET /categories":                 c.HandleGetCategories,
+		"GET /categories/tree":            c.HandleGetCategoryTree,
+		"GET /categories/{uuid}":          c.HandleGetCategory,
+		"GET /categories/by-slug/{slug}":  c.HandleGetCategoryBySlug,
+		"PUT /categories/order":           c.HandleReorderCategories,
+		"POST /categories":                c.HandleAddCategory,
+		"PUT /categories/{uuid}":          c.HandlePutCategory,
+		"DELETE /categories/{uuid}":       c.HandleDelCategory,
+		"GET /categories/trash":           c.HandleGetTrashCategories,
+		"POST /categories/{uuid}/restore": c.HandleRestoreCategory,
 	}
+	return routes.Router()
 }
 
 // @Summary 	Get categories.
-// @Description Get product categories by store.
+// @Description Get product categories sorted by position, then by name, with the number of products outside the trash in each.
 // @Tags 		Categories
 // @Router 		/categories [get]
 // @Accept 		json
@@ -75,44 +86,185 @@ func (c *Category) HandleGetCategories(ctx context.Context, event events.APIGate
 	return JSON(response, http.StatusOK)
 }
 
+// @Summary 	Get category tree.
+// @Description Get product categories nested under their parent, sorted by name. Categories whose parent is in the trash are listed at the top level.
+// @Tags 		Categories
+// @Router 		/categories/tree [get]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Success     200	{object} SelectedCategoryTree "Success"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (c *Category) HandleGetCategoryTree(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	tree, err := c.service.GetCategoryTree(ctx)
+	if err != nil {
+		c.logger.Error("error getting category tree", "error", err)
+		return Error(err)
+	}
+
+	var response = SelectedCategoryTree{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Categories:   tree,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
+// @Summary 	Get category.
+// @Description Get a product category with its position and the number of products outside the trash in it.
+// @Tags 		Categories
+// @Router 		/categories/{uuid} [get]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Category UUID"
+// @Success     200	{object} SelectedCategory "Success"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (c *Category) HandleGetCategory(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	category, err := c.service.GetCategory(ctx, event.PathParameters["uuid"])
+	if err != nil {
+		c.logger.Error("error getting category", "error", err)
+		return Error(err)
+	}
+
+	var response = SelectedCategory{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Category:     category,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
+// @Summary 	Get category by slug.
+// @Description Get a product category by its slug. A slug the category had before a rename redirects to its current slug.
+// @Tags 		Categories
+// @Router 		/categories/by-slug/{slug} [get]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       slug path string true "Category slug"
+// @Success     200	{object} SelectedCategory "Success"
+// @Success     301	{object} Redirected "Moved Permanently"
+// @Header      301	{string} Location "Current slug"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (c *Category) HandleGetCategoryBySlug(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	slug := event.PathParameters["slug"]
+	category, err := c.service.GetCategoryBySlug(ctx, slug)
+	if err != nil {
+		c.logger.Error("error getting category by slug", "slug", slug, "error", err)
+		return Error(err)
+	}
+
+	if category.Slug != slug {
+		// the location is relative to the old slug, whatever the stage prefix
+		return Redirect(category.Slug)
+	}
+
+	var response = SelectedCategory{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Category:     category,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
+// @Summary 	Reorder categories.
+// @Description Set the position of the listed categories to their index in the list, categories left out keep their position. Every listed category must be outside the trash and appear once.
+// @Tags 		Categories
+// @Router 		/categories/order [put]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param	    params body  CategoryOrderRequest true "Category UUIDs in their new order"
+// @Success     200	{object} CategoriesReordered "Success"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (c *Category) HandleReorderCategories(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var request CategoryOrderRequest
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		c.logger.Error("invalid category order body", "error", err)
+		return Error(domain.ErrRequest)
+	}
+
+	if err := request.Validate(); err != nil {
+		c.logger.Error("invalid category order params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	err := c.service.ReorderCategories(ctx, domain.CategoryOrder{
+		Uuids:     request.Categories,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		c.logger.Error("error reordering categories", "error", err)
+		return Error(err)
+	}
+
+	categories, err := c.service.GetCategories(ctx)
+	if err != nil {
+		c.logger.Error("error getting categories", "error", err)
+		return Error(err)
+	}
+
+	var response = CategoriesReordered{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Categories:   categories,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
 // @Summary 	Add category.
-// @Description Add new product category.
+// @Description Add new product category, optionally below the parent_uuid category. The slug is made from the name unless one is sent. The image is sent base64 encoded in image or uploaded beforehand with POST /uploads and referenced by upload_uuid. The body can also be multipart/form-data with the same fields and the image as a binary image part.
 // @Tags 		Categories
 // @Router 		/categories [post]
-// @Accept 		json
+// @Accept 		json,mpfd
 // @Produce 	json
 // @Security    JWT
 // @Param	    params body  CategoryAddRequest true "Category"
 // @Success     201	{object} CategoryAdded "Success"
 // @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     413	{object} ErrorResponse "Request Entity Too Large"
 // @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     409	{object} ErrorResponse "Conflict"
 // @Failure     500	{object} ErrorResponse "Error Internal Server"
 func (c *Category) HandleAddCategory(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	var request CategoryAddRequest
+	var (
+		err     error
+		request CategoryAddRequest
+	)
 
-	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+	if err = c.bindAdd(event, &request); err != nil {
 		c.logger.Error("invalid category body", "error", err)
 		return Error(domain.ErrRequest)
 	}
 
-	if err := request.Validate(); err != nil {
+	if err = request.Validate(); err != nil {
 		c.logger.Error("invalid category params", "error", err)
 		return Error(domain.ErrParams.Wrap(err))
 	}
 
-	image, err := base64.StdEncoding.DecodeString(request.Image)
+	image, err := c.requestImage(request.Image, request.ImageFile)
 	if err != nil {
-		c.logger.Error("error decoding image", "error", err)
-		return Error(domain.ErrRequest)
+		return Error(err)
 	}
 
 	now := time.Now().UTC()
 	category, err := c.service.AddCategory(ctx, domain.CategoryParams{
-		Uuid:      uuid.New().String(),
-		Name:      request.Name,
-		Image:     image,
-		CreatedAt: now,
-		UpdatedAt: now,
+		Uuid:       uuid.New().String(),
+		Name:       request.Name,
+		Slug:       request.Slug,
+		ParentUuid: request.ParentUuid,
+		Image:      image,
+		Upload:     request.UploadUuid,
+		CreatedAt:  now,
+		UpdatedAt:  now,
 	})
 	if err != nil {
 		c.logger.Error("error adding category", "error", err)
@@ -127,21 +279,100 @@ func (c *Category) HandleAddCategory(ctx context.Context, event events.APIGatewa
 	return JSON(response, http.StatusCreated)
 }
 
+// @Summary 	Update category.
+// @Description Update the category name and parent, a category without parent_uuid moves to the top level and a parent below the category itself is rejected. A new name moves the category to a new slug unless one is sent, the old slug redirects to it. An image or upload_uuid replaces its image. The name is copied to every product of the category once it is written, products_renamed counts the products renamed and rename_complete is false when the propagation failed half way, retrying the request completes it. The body can also be multipart/form-data with the same fields and the image as a binary image part.
+// @Tags 		Categories
+// @Router 		/categories/{uuid} [put]
+// @Accept 		json,mpfd
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Category UUID"
+// @Param	    params body  CategoryPutRequest true "Category"
+// @Success     200	{object} CategoryUpdated "Success"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     413	{object} ErrorResponse "Request Entity Too Large"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     409	{object} ErrorResponse "Conflict"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (c *Category) HandlePutCategory(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var (
+		err     error
+		request CategoryPutRequest
+	)
+
+	if err = c.bindPut(event, &request); err != nil {
+		c.logger.Error("invalid category body", "error", err)
+		return Error(domain.ErrRequest)
+	}
+
+	if err = request.Validate(); err != nil {
+		c.logger.Error("invalid category params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	image, err := c.requestImage(request.Image, request.ImageFile)
+	if err != nil {
+		return Error(err)
+	}
+
+	update, err := c.service.PutCategory(ctx, domain.CategoryParams{
+		Uuid:       event.PathParameters["uuid"],
+		Name:       request.Name,
+		Slug:       request.Slug,
+		ParentUuid: request.ParentUuid,
+		Image:      image,
+		Upload:     request.UploadUuid,
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		c.logger.Error("error updating category", "error", err)
+		return Error(err)
+	}
+
+	var response = CategoryUpdated{
+		BaseResponse:    NewBaseResponse(http.StatusOK),
+		Category:        update.Category,
+		ProductsRenamed: update.ProductsRenamed,
+		RenameComplete:  update.RenameComplete,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
 // @Summary 	Delete category.
-// @Description Delete product category.
+// @Description Move product category to the trash, the image is kept until the category is purged. Categories referenced by products, trashed ones included, can only be deleted with a cascade: reassign moves the products to the category given in to, delete moves them to the trash.
 // @Tags 		Categories
 // @Router 		/categories/{uuid} [delete]
 // @Accept 		json
 // @Produce 	json
 // @Security    JWT
 // @Param       uuid path string true "Category UUID"
+// @Param       cascade query string false "What happens to the products of the category" Enums(reassign, delete)
+// @Param       to query string false "Category UUID the products are reassigned to"
 // @Success     200	{object} CategoryDeleted "Success"
 // @Failure     400	{object} ErrorResponse "Bad Request"
 // @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     409	{object} ErrorResponse "Conflict"
 // @Failure     500	{object} ErrorResponse "Error Internal Server"
 func (c *Category) HandleDelCategory(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	uuid := event.PathParameters["uuid"]
-	if err := c.service.DelCategory(ctx, uuid); err != nil {
+	request := CategoryDeleteRequest{
+		Cascade: event.QueryStringParameters["cascade"],
+		To:      event.QueryStringParameters["to"],
+	}
+	if err := request.Validate(); err != nil {
+		c.logger.Error("invalid category params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	err := c.service.DelCategory(ctx, domain.CategoryDelete{
+		Uuid:      event.PathParameters["uuid"],
+		Cascade:   request.Cascade,
+		To:        request.To,
+		DeletedAt: time.Now().UTC(),
+	})
+	if err != nil {
 		c.logger.Error("error deleting category", "error", err)
 		return Error(err)
 	}
@@ -153,3 +384,146 @@ func (c *Category) HandleDelCategory(ctx context.Context, event events.APIGatewa
 
 	return JSON(response, http.StatusOK)
 }
+
+// @Summary 	Get deleted categories.
+// @Description Get the product categories in the trash.
+// @Tags 		Categories
+// @Router 		/categories/trash [get]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Success     200	{object} SelectedCategories "Success"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (c *Category) HandleGetTrashCategories(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	categories, err := c.service.GetTrashCategories(ctx)
+	if err != nil {
+		c.logger.Error("error getting deleted categories", "error", err)
+		return Error(err)
+	}
+
+	var response = SelectedCategories{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Categories:   categories,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
+// @Summary 	Restore category.
+// @Description Restore a product category from the trash.
+// @Tags 		Categories
+// @Router 		/categories/{uuid}/restore [post]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Category UUID"
+// @Success     200	{object} CategoryAdded "Success"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (c *Category) HandleRestoreCategory(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	uuid := event.PathParameters["uuid"]
+	category, err := c.service.RestoreCategory(ctx, uuid)
+	if err != nil {
+		c.logger.Error("error restoring category", "error", err)
+		return Error(err)
+	}
+
+	var response = CategoryAdded{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Category:     category,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
+// bindAdd decodes a JSON or multipart/form-data body into request. Form
+// fields are named after the JSON fields, the image may also be sent as a
+// binary file part.
+func (c *Category) bindAdd(event events.APIGatewayProxyRequest, request *CategoryAddRequest) error {
+	if !IsMultipart(event) {
+		body, err := Body(event)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, request)
+	}
+
+	form, err := ParseForm(event)
+	if err != nil {
+		return err
+	}
+
+	request.Name = form.FormValue("name")
+	request.Slug = form.FormValue("slug")
+	request.ParentUuid = form.FormValue("parent_uuid")
+	request.Image = form.FormValue("image")
+	request.UploadUuid = form.FormValue("upload_uuid")
+
+	request.ImageFile, err = FormFile(form, "image", c.limits.MaxBytes)
+	return err
+}
+
+// bindPut decodes a JSON or multipart/form-data body into request, the form
+// fields are those of bindAdd.
+func (c *Category) bindPut(event events.APIGatewayProxyRequest, request *CategoryPutRequest) error {
+	if !IsMultipart(event) {
+		body, err := Body(event)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, request)
+	}
+
+	form, err := ParseForm(event)
+	if err != nil {
+		return err
+	}
+
+	request.Name = form.FormValue("name")
+	request.Slug = form.FormValue("slug")
+	request.ParentUuid = form.FormValue("parent_uuid")
+	request.Image = form.FormValue("image")
+	request.UploadUuid = form.FormValue("upload_uuid")
+
+	request.ImageFile, err = FormFile(form, "image", c.limits.MaxBytes)
+	return err
+}
+
+// requestImage returns the image sent as a multipart file or base64 encoded,
+// if any.
+func (c *Category) requestImage(encoded string, file []byte) ([]byte, error) {
+	switch {
+	case file != nil:
+		return c.checkImage(file)
+	case encoded != "":
+		return c.decodeImage(encoded)
+	}
+	return nil, nil
+}
+
+// decodeImage decodes a base64 image and checks it against the upload limits.
+func (c *Category) decodeImage(encoded string) ([]byte, error) {
+	image, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		c.logger.Error("error decoding image", "error", err)
+		return nil, domain.ErrRequest
+	}
+	return c.checkImage(image)
+}
+
+// checkImage checks an image against the upload limits, so invalid or
+// oversized images never reach the storage.
+func (c *Category) checkImage(image []byte) ([]byte, error) {
+	if err := c.limits.Validate(image); err != nil {
+		c.logger.Error("invalid image", "error", err)
+		errs := validation.Errors{"image": err}
+		if imagex.TooLarge(err) {
+			return nil, domain.ErrImageTooLarge.Wrap(errs)
+		}
+		return nil, domain.ErrImage.Wrap(errs)
+	}
+
+	return image, nil
+}
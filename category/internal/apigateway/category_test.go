@@ -0,0 +1,150 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"shopy/pkg/imagex"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type formPart struct {
+	name, file, value string
+}
+
+// formEvent builds an API Gateway event with a multipart/form-data body
+// holding parts in order.
+func formEvent(t *testing.T, parts ...formPart) events.APIGatewayProxyRequest {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range parts {
+		var err error
+		if part.file != "" {
+			w, ferr := writer.CreateFormFile(part.name, part.file)
+			if ferr != nil {
+				t.Fatal(ferr)
+			}
+			_, err = w.Write([]byte(part.value))
+		} else {
+			err = writer.WriteField(part.name, part.value)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return events.APIGatewayProxyRequest{
+		Headers: map[string]string{"content-type": writer.FormDataContentType()},
+		Body:    body.String(),
+	}
+}
+
+func testCategory(maxBytes int) *Category {
+	return &Category{
+		logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		limits: imagex.Limits{MaxBytes: maxBytes, MaxDimension: imagex.DefaultMaxDimension},
+	}
+}
+
+func TestBindAddForm(t *testing.T) {
+	event := formEvent(t,
+		formPart{name: "name", value: "Boissons chaudes"},
+		formPart{name: "slug", value: "boissons-chaudes"},
+		formPart{name: "parent_uuid", value: "8c1a8a5e-8bd6-4b0e-9d5c-0a4c43f7d0a1"},
+		formPart{name: "image", file: "cafe.png", value: "png"},
+	)
+
+	var request CategoryAddRequest
+	if err := testCategory(1024).bindAdd(event, &request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Name != "Boissons chaudes" || request.Slug != "boissons-chaudes" ||
+		request.ParentUuid != "8c1a8a5e-8bd6-4b0e-9d5c-0a4c43f7d0a1" || string(request.ImageFile) != "png" {
+		t.Errorf("request = %+v", request)
+	}
+}
+
+func TestBindPutFormMissingFile(t *testing.T) {
+	event := formEvent(t,
+		formPart{name: "name", value: "Boissons"},
+		formPart{name: "upload_uuid", value: "0b8d5c4e-2a43-4f4b-9f0c-6a9b8f2e5d11"},
+	)
+
+	var request CategoryPutRequest
+	if err := testCategory(1024).bindPut(event, &request); err != nil {
+		t.Fatal(err)
+	}
+	if request.ImageFile != nil {
+		t.Errorf("image file = %q, want nil", request.ImageFile)
+	}
+	if request.UploadUuid != "0b8d5c4e-2a43-4f4b-9f0c-6a9b8f2e5d11" {
+		t.Errorf("upload uuid = %q", request.UploadUuid)
+	}
+}
+
+func TestBindAddFormRepeatedFields(t *testing.T) {
+	event := formEvent(t,
+		formPart{name: "name", value: "first"},
+		formPart{name: "name", value: "second"},
+		formPart{name: "image", file: "a.png", value: "a"},
+		formPart{name: "image", file: "b.png", value: "b"},
+	)
+
+	var request CategoryAddRequest
+	if err := testCategory(1024).bindAdd(event, &request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Name != "first" {
+		t.Errorf("name = %q, want the first value", request.Name)
+	}
+	if string(request.ImageFile) != "a" {
+		t.Errorf("image file = %q, want the first file", request.ImageFile)
+	}
+}
+
+func TestBindAddFormMalformed(t *testing.T) {
+	event := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Content-Type": "multipart/form-data; boundary=x"},
+		Body:    "--x\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\nunterminated",
+	}
+
+	var request CategoryAddRequest
+	if err := testCategory(1024).bindAdd(event, &request); err == nil {
+		t.Error("bindAdd succeeded on a truncated body, want an error")
+	}
+}
+
+func TestAddCategoryOversizedFile(t *testing.T) {
+	const maxBytes = 16
+
+	event := formEvent(t,
+		formPart{name: "name", value: "Boissons"},
+		formPart{name: "image", file: "big.png", value: string(make([]byte, 4*maxBytes))},
+	)
+
+	c := testCategory(maxBytes)
+	var request CategoryAddRequest
+	if err := c.bindAdd(event, &request); err != nil {
+		t.Fatal(err)
+	}
+	if len(request.ImageFile) != maxBytes+1 {
+		t.Errorf("read %d bytes of the image, want %d", len(request.ImageFile), maxBytes+1)
+	}
+
+	response, err := c.HandleAddCategory(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d: %s", response.StatusCode, http.StatusRequestEntityTooLarge, response.Body)
+	}
+}
@@ -1,13 +1,20 @@
 package apigateway
 
 import (
+	"shopy/internal/domain"
+	"shopy/pkg/slug"
+
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
 )
 
 type CategoryAddRequest struct {
-	Name  string `json:"name"`
-	Image string `json:"image"`
+	Name       string `json:"name"`
+	Slug       string `json:"slug" example:"cafe-creme"`
+	ParentUuid string `json:"parent_uuid"`
+	Image      string `json:"image"`
+	ImageFile  []byte `json:"-" swaggerignore:"true"`
+	UploadUuid string `json:"upload_uuid"`
 }
 
 func (c CategoryAddRequest) Validate() error {
@@ -16,9 +23,99 @@ func (c CategoryAddRequest) Validate() error {
 			validation.Required,
 			validation.Length(1, 50),
 		),
+		validation.Field(&c.Slug,
+			validation.Length(1, slug.MaxLength),
+			validation.By(slug.Validate),
+		),
+		validation.Field(&c.ParentUuid,
+			is.UUID,
+		),
 		validation.Field(&c.Image,
+			validation.When(c.UploadUuid == "" && c.ImageFile == nil,
+				validation.Required,
+			).Else(
+				validation.Empty,
+			),
+			is.Base64,
+		),
+		validation.Field(&c.UploadUuid,
+			validation.When(c.ImageFile != nil,
+				validation.Empty,
+			),
+			is.UUID,
+		),
+	)
+}
+
+type CategoryPutRequest struct {
+	Name       string `json:"name"`
+	Slug       string `json:"slug" example:"cafe-creme"`
+	ParentUuid string `json:"parent_uuid"`
+	Image      string `json:"image"`
+	ImageFile  []byte `json:"-" swaggerignore:"true"`
+	UploadUuid string `json:"upload_uuid"`
+}
+
+func (c CategoryPutRequest) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Name,
 			validation.Required,
+			validation.Length(1, 50),
+		),
+		validation.Field(&c.Slug,
+			validation.Length(1, slug.MaxLength),
+			validation.By(slug.Validate),
+		),
+		validation.Field(&c.ParentUuid,
+			is.UUID,
+		),
+		validation.Field(&c.Image,
+			validation.When(c.UploadUuid != "" || c.ImageFile != nil,
+				validation.Empty,
+			),
 			is.Base64,
 		),
+		validation.Field(&c.UploadUuid,
+			validation.When(c.ImageFile != nil,
+				validation.Empty,
+			),
+			is.UUID,
+		),
+	)
+}
+
+// CategoryDeleteRequest holds the query parameters of a category deletion.
+type CategoryDeleteRequest struct {
+	Cascade string `json:"cascade"`
+	To      string `json:"to"`
+}
+
+func (c CategoryDeleteRequest) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Cascade,
+			validation.In(domain.CascadeReassign, domain.CascadeDelete),
+		),
+		validation.Field(&c.To,
+			validation.When(c.Cascade == domain.CascadeReassign,
+				validation.Required,
+			).Else(
+				validation.Empty,
+			),
+			is.UUID,
+		),
+	)
+}
+
+type CategoryOrderRequest struct {
+	Categories []string `json:"categories"`
+}
+
+func (c CategoryOrderRequest) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Categories,
+			validation.Required,
+			validation.Length(1, 100),
+			validation.Each(is.UUID),
+		),
 	)
 }
@@ -24,11 +24,39 @@ type SelectedCategories struct {
 	Categories models.Categories `json:"categories"`
 }
 
+type SelectedCategoryTree struct {
+	BaseResponse
+	Categories models.CategoryTree `json:"categories"`
+}
+
+type SelectedCategory struct {
+	BaseResponse
+	Category *models.Category `json:"category"`
+}
+
+// Redirected points the client to the current slug of an item.
+type Redirected struct {
+	BaseResponse
+	Location string `json:"location"`
+}
+
 type CategoryAdded struct {
 	BaseResponse
 	Category *models.Category `json:"category"`
 }
 
+type CategoryUpdated struct {
+	BaseResponse
+	Category        *models.Category `json:"category"`
+	ProductsRenamed int              `json:"products_renamed"`
+	RenameComplete  bool             `json:"rename_complete"`
+}
+
+type CategoriesReordered struct {
+	BaseResponse
+	Categories models.Categories `json:"categories"`
+}
+
 type CategoryDeleted struct {
 	BaseResponse
 	Category string `json:"category"`
@@ -1,12 +1,17 @@
 package apigateway
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"io"
+	"mime"
 	"net/http"
 	"shopy/internal/domain"
 	"shopy/pkg/errorx"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -15,6 +20,29 @@ import (
 
 type APIGatewayFunc func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 
+// Routes maps "METHOD /resource" keys, without the API version prefix, to handlers.
+type Routes map[string]APIGatewayFunc
+
+func (r Routes) Router() APIGatewayFunc {
+	return func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		if handler, ok := r[event.HTTPMethod+" "+resource(event.Resource)]; ok {
+			return handler(ctx, event)
+		}
+		return events.APIGatewayProxyResponse{
+			Body:       "method is not valid",
+			StatusCode: http.StatusMethodNotAllowed,
+		}, nil
+	}
+}
+
+// resource removes the version segment from a resource such as "/v1/categories/{uuid}".
+func resource(resource string) string {
+	if i := strings.Index(strings.TrimPrefix(resource, "/"), "/"); i >= 0 {
+		return resource[i+1:]
+	}
+	return resource
+}
+
 type ErrorResponse struct {
 	BaseResponse
 	Message string            `json:"message"`
@@ -44,6 +72,13 @@ func Error(err error) (events.APIGatewayProxyResponse, error) {
 			}
 		case domain.CodeNotFound:
 			response.Code = http.StatusNotFound
+		case domain.CodeConflict:
+			response.Code = http.StatusConflict
+		case domain.CodePayloadTooLarge:
+			response.Code = http.StatusRequestEntityTooLarge
+			if errors.As(errx, &errs) {
+				response.Errors = errs
+			}
 		}
 	}
 	return JSON(response, response.Code)
@@ -64,3 +99,77 @@ func JSON(response any, statusCode int) (events.APIGatewayProxyResponse, error)
 		},
 	}, nil
 }
+
+// Body returns the request body, decoding the base64 bodies API Gateway sends
+// for binary media types.
+func Body(event events.APIGatewayProxyRequest) ([]byte, error) {
+	if event.IsBase64Encoded {
+		return base64.StdEncoding.DecodeString(event.Body)
+	}
+	return []byte(event.Body), nil
+}
+
+// IsMultipart reports whether the request body is multipart/form-data.
+func IsMultipart(event events.APIGatewayProxyRequest) bool {
+	mediaType, _, err := mime.ParseMediaType(Header(event, "Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// ParseForm parses a multipart/form-data body into a request whose text
+// fields are read with FormValue and files with FormFile.
+func ParseForm(event events.APIGatewayProxyRequest) (*http.Request, error) {
+	body, err := Body(event)
+	if err != nil {
+		return nil, err
+	}
+
+	form, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	form.Header.Set("Content-Type", Header(event, "Content-Type"))
+
+	// the body is already in memory, its files are kept there too
+	if err = form.ParseMultipartForm(int64(len(body))); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+// FormFile reads the file part name of a parsed form, nil when it's missing.
+// At most maxBytes+1 bytes are read so oversized files are still detected.
+func FormFile(form *http.Request, name string, maxBytes int) ([]byte, error) {
+	file, _, err := form.FormFile(name)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(io.LimitReader(file, int64(maxBytes)+1))
+}
+
+// Header returns the value of a request header ignoring the case of its name.
+func Header(event events.APIGatewayProxyRequest, name string) string {
+	for key, value := range event.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Redirect answers with a permanent redirect to location.
+func Redirect(location string) (events.APIGatewayProxyResponse, error) {
+	result, err := JSON(Redirected{
+		BaseResponse: NewBaseResponse(http.StatusMovedPermanently),
+		Location:     location,
+	}, http.StatusMovedPermanently)
+	if err != nil {
+		return result, err
+	}
+	result.Headers["Location"] = location
+	return result, nil
+}
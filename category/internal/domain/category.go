@@ -1,12 +1,74 @@
 package domain
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"shopy/pkg/imagex"
+	"time"
+)
 
 type CategoryParams struct {
-	Uuid      string
-	Name      string
-	Image     []byte
-	Location  string
-	CreatedAt time.Time
+	Uuid       string
+	Name       string
+	Slug       string
+	ParentUuid string
+	Position   int
+	Image      []byte
+	Upload     string
+	Hash       string
+	Location   string
+	Renditions Renditions
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// CategoryOrder sets the position of the listed categories to their index,
+// categories left out keep their position.
+type CategoryOrder struct {
+	Uuids     []string
 	UpdatedAt time.Time
 }
+
+// Cascades applied to the products of a deleted category.
+const (
+	CascadeReassign = "reassign"
+	CascadeDelete   = "delete"
+)
+
+// CategoryDelete moves a category to the trash, Cascade tells what happens to
+// its products: none may reference it when empty, they move to the To
+// category with CascadeReassign or to the trash with CascadeDelete.
+type CategoryDelete struct {
+	Uuid      string
+	Cascade   string
+	To        string
+	DeletedAt time.Time
+}
+
+// Rendition is a resized copy of an uploaded image in one format.
+type Rendition struct {
+	Width    int
+	Format   string
+	Location string
+}
+
+type Renditions []Rendition
+
+// Location returns the URL of the widest JPEG rendition, used wherever a
+// single image URL is expected.
+func (r Renditions) Location() string {
+	var widest Rendition
+	for _, rendition := range r {
+		if rendition.Format == string(imagex.FormatJPEG) && rendition.Width >= widest.Width {
+			widest = rendition
+		}
+	}
+	return widest.Location
+}
+
+// ImageHash returns the hex encoded SHA-256 of an uploaded image, its
+// renditions are stored under this hash so identical uploads share them.
+func ImageHash(image []byte) string {
+	sum := sha256.Sum256(image)
+	return hex.EncodeToString(sum[:])
+}
@@ -5,10 +5,22 @@ import "shopy/pkg/errorx"
 const (
 	CodeBadRequest errorx.Code = iota
 	CodeNotFound
+	CodePayloadTooLarge
+	CodeConflict
 )
 
 var (
 	ErrRequest  = errorx.NewErrorf(CodeBadRequest, "invalid body request")
 	ErrParams   = errorx.NewErrorf(CodeBadRequest, "invalid params")
 	ErrNotFound = errorx.NewErrorf(CodeNotFound, "item not found")
+	ErrCategory = errorx.NewErrorf(CodeBadRequest, "category not found")
+	ErrCycle    = errorx.NewErrorf(CodeBadRequest, "category can't be moved below itself")
+	ErrOrder    = errorx.NewErrorf(CodeBadRequest, "categories must be listed once and be outside the trash")
+
+	ErrCategoryInUse = errorx.NewErrorf(CodeConflict, "category is referenced by products")
+	ErrSlug          = errorx.NewErrorf(CodeConflict, "slug is already in use")
+
+	ErrImage         = errorx.NewErrorf(CodeBadRequest, "invalid image")
+	ErrImageTooLarge = errorx.NewErrorf(CodePayloadTooLarge, "image is too large")
+	ErrUpload        = errorx.NewErrorf(CodeBadRequest, "upload not found")
 )
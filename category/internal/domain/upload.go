@@ -0,0 +1,10 @@
+package domain
+
+// UploadFolder holds the images uploaded to a presigned URL, created through
+// the product API, until a category references them.
+const UploadFolder = "uploads"
+
+// UploadKey returns the storage key of an upload.
+func UploadKey(uuid string) string {
+	return UploadFolder + "/" + uuid
+}
@@ -7,6 +7,8 @@ import (
 	"log/slog"
 	"shopy/internal/domain"
 	"shopy/internal/models"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -15,23 +17,33 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+// notDeleted filters out categories moved to the trash.
+const notDeleted = "attribute_not_exists(deleted_at)"
+
 type Category struct {
-	logger *slog.Logger
-	client *dynamodb.Client
-	table  string
+	logger       *slog.Logger
+	client       *dynamodb.Client
+	table        string
+	imageTable   string
+	productTable string
+	slugTable    string
 }
 
 func NewCategory(logger *slog.Logger, client *dynamodb.Client) *Category {
 	return &Category{
-		logger: logger,
-		client: client,
-		table:  "category",
+		logger:       logger,
+		client:       client,
+		table:        "category",
+		imageTable:   "image_reference",
+		productTable: "product",
+		slugTable:    "slug",
 	}
 }
 
 func (c *Category) GetCategories(ctx context.Context) (models.Categories, error) {
 	input := &dynamodb.ScanInput{
-		TableName: aws.String(c.table),
+		TableName:        aws.String(c.table),
+		FilterExpression: aws.String(notDeleted),
 	}
 
 	result, err := c.client.Scan(ctx, input)
@@ -49,11 +61,16 @@ func (c *Category) GetCategories(ctx context.Context) (models.Categories, error)
 
 func (c *Category) AddCategory(ctx context.Context, params domain.CategoryParams) (*models.Category, error) {
 	category := &models.Category{
-		Uuid:      params.Uuid,
-		Name:      params.Name,
-		Image:     params.Location,
-		CreatedAt: params.CreatedAt.Format(time.DateTime),
-		UpdatedAt: params.UpdatedAt.Format(time.DateTime),
+		Uuid:       params.Uuid,
+		Name:       params.Name,
+		Slug:       params.Slug,
+		ParentUuid: params.ParentUuid,
+		Position:   params.Position,
+		Image:      params.Location,
+		ImageHash:  params.Hash,
+		Renditions: renditions(params.Renditions),
+		CreatedAt:  params.CreatedAt.Format(time.DateTime),
+		UpdatedAt:  params.UpdatedAt.Format(time.DateTime),
 	}
 
 	item, err := attributevalue.MarshalMap(category)
@@ -61,30 +78,284 @@ func (c *Category) AddCategory(ctx context.Context, params domain.CategoryParams
 		return nil, fmt.Errorf("error marshaling item: %w", err)
 	}
 
-	_, err = c.client.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(c.table),
-		Item:      item,
+	guard, err := c.slugGuard(params.Slug, params.Uuid, params.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Put: &types.Put{
+					TableName: aws.String(c.table),
+					Item:      item,
+				},
+			},
+			guard,
+		},
 	})
 	if err != nil {
+		var errf *types.TransactionCanceledException
+		if errors.As(err, &errf) && failed(errf, 1) {
+			return nil, domain.ErrSlug
+		}
+
 		return nil, fmt.Errorf("error adding item: %w", err)
 	}
 
 	return category, nil
 }
 
-func (c *Category) DelCategory(ctx context.Context, uuid string) (*models.Category, error) {
-	input := &dynamodb.DeleteItemInput{
+// GetCategory returns a category, categories in the trash are not found.
+func (c *Category) GetCategory(ctx context.Context, uuid string) (*models.Category, error) {
+	input := &dynamodb.GetItemInput{
 		TableName: aws.String(c.table),
 		Key: map[string]types.AttributeValue{
 			"uuid": &types.AttributeValueMemberS{Value: uuid},
 		},
-		ConditionExpression: aws.String("attribute_exists(#uuid)"),
+	}
+
+	result, err := c.client.GetItem(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error getting item: %w", err)
+	}
+
+	if len(result.Item) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	var category models.Category
+	if err = attributevalue.UnmarshalMap(result.Item, &category); err != nil {
+		return nil, fmt.Errorf("error unmarshaling item: %w", err)
+	}
+
+	if category.DeletedAt != "" {
+		return nil, domain.ErrNotFound
+	}
+
+	return &category, nil
+}
+
+// PutCategory updates the name and parent of a category, and its slug and
+// image when they are given, an empty parent makes it a top level category.
+// It returns the category as it was before the update so the replaced image
+// is the one actually overwritten.
+func (c *Category) PutCategory(ctx context.Context, params domain.CategoryParams) (*models.Category, error) {
+	var (
+		set    = []string{"#name = :name", "updated_at = :updated_at"}
+		remove []string
+
+		expressionAttributeValues = map[string]types.AttributeValue{
+			":name":       &types.AttributeValueMemberS{Value: params.Name},
+			":updated_at": &types.AttributeValueMemberS{Value: params.UpdatedAt.Format(time.DateTime)},
+		}
+	)
+	if params.ParentUuid != "" {
+		set = append(set, "parent_uuid = :parent_uuid")
+		expressionAttributeValues[":parent_uuid"] = &types.AttributeValueMemberS{Value: params.ParentUuid}
+	} else {
+		remove = append(remove, "parent_uuid")
+	}
+	if params.Slug != "" {
+		set = append(set, "slug = :slug")
+		expressionAttributeValues[":slug"] = &types.AttributeValueMemberS{Value: params.Slug}
+	}
+	if params.Location != "" {
+		stored, err := attributevalue.Marshal(renditions(params.Renditions))
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling renditions: %w", err)
+		}
+
+		set = append(set, "image = :image", "image_hash = :image_hash", "renditions = :renditions")
+		expressionAttributeValues[":image"] = &types.AttributeValueMemberS{Value: params.Location}
+		expressionAttributeValues[":image_hash"] = &types.AttributeValueMemberS{Value: params.Hash}
+		expressionAttributeValues[":renditions"] = stored
+	}
+
+	expression := "SET " + strings.Join(set, ", ")
+	if len(remove) > 0 {
+		expression += " REMOVE " + strings.Join(remove, ", ")
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: params.Uuid},
+		},
+		UpdateExpression:          aws.String(expression),
+		ConditionExpression:       aws.String("attribute_exists(#uuid) AND " + notDeleted),
+		ExpressionAttributeValues: expressionAttributeValues,
 		ExpressionAttributeNames: map[string]string{
 			"#uuid": "uuid",
+			"#name": "name",
 		},
 		ReturnValues: types.ReturnValueAllOld,
 	}
 
+	if params.Slug != "" {
+		return c.updateSlug(ctx, input, params.Uuid, params.Slug, params.UpdatedAt)
+	}
+	return c.updateCategory(ctx, input)
+}
+
+// ReorderCategories sets the position of the listed categories in a single
+// transaction, failing when any of them is missing or in the trash.
+func (c *Category) ReorderCategories(ctx context.Context, order domain.CategoryOrder) error {
+	items := make([]types.TransactWriteItem, len(order.Uuids))
+	for i, uuid := range order.Uuids {
+		items[i] = types.TransactWriteItem{
+			Update: &types.Update{
+				TableName: aws.String(c.table),
+				Key: map[string]types.AttributeValue{
+					"uuid": &types.AttributeValueMemberS{Value: uuid},
+				},
+				UpdateExpression:    aws.String("SET #position = :position, updated_at = :updated_at"),
+				ConditionExpression: aws.String("attribute_exists(#uuid) AND " + notDeleted),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":position":   &types.AttributeValueMemberN{Value: strconv.Itoa(i)},
+					":updated_at": &types.AttributeValueMemberS{Value: order.UpdatedAt.Format(time.DateTime)},
+				},
+				ExpressionAttributeNames: map[string]string{
+					"#uuid":     "uuid",
+					"#position": "position",
+				},
+			},
+		}
+	}
+
+	_, err := c.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if err != nil {
+		var errf *types.TransactionCanceledException
+		if errors.As(err, &errf) {
+			for i := range errf.CancellationReasons {
+				if failed(errf, i) {
+					return domain.ErrNotFound
+				}
+			}
+		}
+
+		return fmt.Errorf("error updating items: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Category) GetTrashCategories(ctx context.Context) (models.Categories, error) {
+	paginator := dynamodb.NewScanPaginator(c.client, &dynamodb.ScanInput{
+		TableName:        aws.String(c.table),
+		FilterExpression: aws.String("attribute_exists(deleted_at)"),
+	})
+
+	categories := models.Categories{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error executing query: %w", err)
+		}
+
+		var trashed models.Categories
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &trashed); err != nil {
+			return nil, fmt.Errorf("error unmarshaling items: %w", err)
+		}
+		categories = append(categories, trashed...)
+	}
+
+	return categories, nil
+}
+
+// TrashCategory moves a category to the trash while its product count is 0.
+// Product writes check the category in their transaction and keep the count,
+// so a product added or moved into the category meanwhile either fails or
+// makes the trash fail with ErrCategoryInUse.
+func (c *Category) TrashCategory(ctx context.Context, uuid string, deletedAt time.Time) (*models.Category, error) {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+		UpdateExpression:    aws.String("SET deleted_at = :deleted_at, updated_at = :deleted_at"),
+		ConditionExpression: aws.String("attribute_exists(#uuid) AND " + notDeleted + " AND (attribute_not_exists(product_count) OR product_count = :zero)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":deleted_at": &types.AttributeValueMemberS{Value: deletedAt.Format(time.DateTime)},
+			":zero":       &types.AttributeValueMemberN{Value: "0"},
+		},
+		ExpressionAttributeNames: map[string]string{
+			"#uuid": "uuid",
+		},
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}
+
+	result, err := c.client.UpdateItem(ctx, input)
+	if err != nil {
+		var errf *types.ConditionalCheckFailedException
+		if errors.As(err, &errf) {
+			if _, deleted := errf.Item["deleted_at"]; len(errf.Item) == 0 || deleted {
+				return nil, domain.ErrNotFound
+			}
+			return nil, domain.ErrCategoryInUse
+		}
+
+		return nil, fmt.Errorf("error updating item: %w", err)
+	}
+
+	var category models.Category
+	if err = attributevalue.UnmarshalMap(result.Attributes, &category); err != nil {
+		return nil, fmt.Errorf("error marshaling item: %w", err)
+	}
+
+	return &category, nil
+}
+
+func (c *Category) RestoreCategory(ctx context.Context, uuid string, updatedAt time.Time) (*models.Category, error) {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+		UpdateExpression:    aws.String("SET updated_at = :updated_at REMOVE deleted_at"),
+		ConditionExpression: aws.String("attribute_exists(deleted_at)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":updated_at": &types.AttributeValueMemberS{Value: updatedAt.Format(time.DateTime)},
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	}
+
+	return c.updateCategory(ctx, input)
+}
+
+func (c *Category) updateCategory(ctx context.Context, input *dynamodb.UpdateItemInput) (*models.Category, error) {
+	result, err := c.client.UpdateItem(ctx, input)
+	if err != nil {
+		var errf *types.ConditionalCheckFailedException
+		if errors.As(err, &errf) {
+			return nil, domain.ErrNotFound
+		}
+
+		return nil, fmt.Errorf("error updating item: %w", err)
+	}
+
+	var category models.Category
+	if err = attributevalue.UnmarshalMap(result.Attributes, &category); err != nil {
+		return nil, fmt.Errorf("error marshaling item: %w", err)
+	}
+
+	return &category, nil
+}
+
+// DelCategory permanently removes a category, only categories in the trash can be deleted.
+func (c *Category) DelCategory(ctx context.Context, uuid string) (*models.Category, error) {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+		ConditionExpression: aws.String("attribute_exists(deleted_at)"),
+		ReturnValues:        types.ReturnValueAllOld,
+	}
+
 	result, err := c.client.DeleteItem(ctx, input)
 	if err != nil {
 		var errf *types.ConditionalCheckFailedException
@@ -102,3 +373,18 @@ func (c *Category) DelCategory(ctx context.Context, uuid string) (*models.Catego
 
 	return &category, nil
 }
+
+func renditions(renditions domain.Renditions) []*models.Rendition {
+	if len(renditions) == 0 {
+		return nil
+	}
+	converted := make([]*models.Rendition, len(renditions))
+	for i, rendition := range renditions {
+		converted[i] = &models.Rendition{
+			Width:  rendition.Width,
+			Format: rendition.Format,
+			Url:    rendition.Location,
+		}
+	}
+	return converted
+}
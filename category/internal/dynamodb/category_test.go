@@ -0,0 +1,78 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shopy/internal/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func TestTrashCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		// stored is the category, nil when missing
+		stored map[string]any
+		want   error
+	}{
+		{name: "empty", stored: map[string]any{"uuid": map[string]string{"S": "drinks"}, "product_count": map[string]string{"N": "0"}}},
+		{name: "without a count", stored: map[string]any{"uuid": map[string]string{"S": "drinks"}}},
+		{name: "with products", stored: map[string]any{"uuid": map[string]string{"S": "drinks"}, "product_count": map[string]string{"N": "2"}}, want: domain.ErrCategoryInUse},
+		{name: "trashed", stored: map[string]any{"uuid": map[string]string{"S": "drinks"}, "deleted_at": map[string]string{"S": "2024-05-01 11:00:00"}}, want: domain.ErrNotFound},
+		{name: "missing", want: domain.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var request struct {
+					ConditionExpression                 string
+					ReturnValuesOnConditionCheckFailure string
+				}
+				json.NewDecoder(r.Body).Decode(&request)
+
+				w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+				if !strings.Contains(request.ConditionExpression, "product_count = :zero") || request.ReturnValuesOnConditionCheckFailure != "ALL_OLD" {
+					t.Errorf("condition = %q returning %q, want one on the product count returning the item", request.ConditionExpression, request.ReturnValuesOnConditionCheckFailure)
+				}
+
+				_, deleted := tt.stored["deleted_at"]
+				count, _ := tt.stored["product_count"].(map[string]string)
+				if tt.stored == nil || deleted || count != nil && count["N"] != "0" {
+					response := map[string]any{
+						"__type":  "com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException",
+						"message": "The conditional request failed",
+					}
+					if tt.stored != nil {
+						response["Item"] = tt.stored
+					}
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(response)
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]any{"Attributes": tt.stored})
+			}))
+			defer server.Close()
+
+			client := dynamodb.New(dynamodb.Options{
+				BaseEndpoint: aws.String(server.URL),
+				Region:       "us-east-1",
+				Credentials:  aws.AnonymousCredentials{},
+			})
+			repository := NewCategory(slog.New(slog.NewTextHandler(io.Discard, nil)), client)
+
+			_, err := repository.TrashCategory(context.Background(), "drinks", time.Now())
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
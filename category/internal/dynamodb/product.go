@@ -0,0 +1,373 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxTransactItems is the most items a DynamoDB transaction holds.
+const maxTransactItems = 100
+
+// productItem holds the attributes of a product a category write depends on.
+type productItem struct {
+	Uuid      string `dynamodbav:"uuid"`
+	DeletedAt string `dynamodbav:"deleted_at,omitempty"`
+}
+
+// HasProducts reports whether any product, trashed ones included, is in the
+// category.
+func (c *Category) HasProducts(ctx context.Context, uuid string) (bool, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(c.productTable),
+		IndexName:              aws.String("GSI_CATEGORY"),
+		KeyConditionExpression: aws.String("category_uuid = :uuid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+		Select: types.SelectCount,
+		Limit:  aws.Int32(1),
+	}
+
+	result, err := c.client.Query(ctx, input)
+	if err != nil {
+		return false, fmt.Errorf("error executing query: %w", err)
+	}
+
+	return result.Count > 0, nil
+}
+
+// RenameProducts copies the name of a category to the products in it, trashed
+// ones included, writing up to maxTransactItems products per transaction. Like
+// every product write, the rename bumps the product version. Each
+// product records the time of the rename it applied so a retried or late
+// rename never overwrites a newer one, making the propagation safe to repeat.
+// It returns the number of products renamed, also when it fails half way.
+func (c *Category) RenameProducts(ctx context.Context, uuid, name string, updatedAt time.Time) (int, error) {
+	var renamed int
+	err := c.productPages(ctx, uuid, func(products []productItem) error {
+		items := make([]types.TransactWriteItem, len(products))
+		for i, product := range products {
+			items[i] = types.TransactWriteItem{
+				Update: &types.Update{
+					TableName: aws.String(c.productTable),
+					Key: map[string]types.AttributeValue{
+						"uuid": &types.AttributeValueMemberS{Value: product.Uuid},
+					},
+					UpdateExpression:    aws.String("SET category_name = :name, category_updated_at = :updated_at, updated_at = :updated_at, #version = if_not_exists(#version, :zero) + :one"),
+					ConditionExpression: aws.String("category_uuid = :uuid AND (attribute_not_exists(category_updated_at) OR category_updated_at <= :updated_at)"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":uuid":       &types.AttributeValueMemberS{Value: uuid},
+						":name":       &types.AttributeValueMemberS{Value: name},
+						":updated_at": &types.AttributeValueMemberS{Value: updatedAt.Format(time.DateTime)},
+						":zero":       &types.AttributeValueMemberN{Value: "0"},
+						":one":        &types.AttributeValueMemberN{Value: "1"},
+					},
+					ExpressionAttributeNames: map[string]string{
+						"#version": "version",
+					},
+				},
+			}
+		}
+
+		for len(items) > 0 {
+			batch := items[:min(len(items), maxTransactItems)]
+			items = items[len(batch):]
+
+			written, err := c.writeEach(ctx, batch)
+			renamed += written
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return renamed, err
+}
+
+// writeEach writes items updating one product each in a single transaction.
+// Items whose condition fails have changed since the query and are dropped,
+// the others are written again. It returns the number of items written.
+func (c *Category) writeEach(ctx context.Context, items []types.TransactWriteItem) (int, error) {
+	for len(items) > 0 {
+		_, err := c.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+		var errf *types.TransactionCanceledException
+		if !errors.As(err, &errf) {
+			if err != nil {
+				return 0, fmt.Errorf("error updating items: %w", err)
+			}
+			return len(items), nil
+		}
+
+		kept := make([]types.TransactWriteItem, 0, len(items))
+		for i, item := range items {
+			if !failed(errf, i) {
+				kept = append(kept, item)
+			}
+		}
+		if len(kept) == len(items) {
+			// canceled for another reason such as a conflicting write
+			return 0, fmt.Errorf("error updating items: %w", err)
+		}
+		items = kept
+	}
+	return 0, nil
+}
+
+// ReassignProducts moves the products of a category, trashed ones included,
+// to another category and copies its name. The product counts of both
+// categories follow the products outside the trash in the same transaction.
+// Products already moved are skipped so the reassignment is safe to repeat.
+func (c *Category) ReassignProducts(ctx context.Context, uuid string, to *models.Category, updatedAt time.Time) (int, error) {
+	return c.updateProducts(ctx, uuid, func(product productItem) []types.TransactWriteItem {
+		state := notDeleted
+		if product.DeletedAt != "" {
+			state = "attribute_exists(deleted_at)"
+		}
+
+		items := []types.TransactWriteItem{{
+			Update: &types.Update{
+				TableName: aws.String(c.productTable),
+				Key: map[string]types.AttributeValue{
+					"uuid": &types.AttributeValueMemberS{Value: product.Uuid},
+				},
+				UpdateExpression:    aws.String("SET category_uuid = :to, category_name = :name, category_updated_at = :updated_at, updated_at = :updated_at, #version = if_not_exists(#version, :zero) + :one"),
+				ConditionExpression: aws.String("category_uuid = :uuid AND " + state),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":uuid":       &types.AttributeValueMemberS{Value: uuid},
+					":to":         &types.AttributeValueMemberS{Value: to.Uuid},
+					":name":       &types.AttributeValueMemberS{Value: to.Name},
+					":updated_at": &types.AttributeValueMemberS{Value: updatedAt.Format(time.DateTime)},
+					":zero":       &types.AttributeValueMemberN{Value: "0"},
+					":one":        &types.AttributeValueMemberN{Value: "1"},
+				},
+				ExpressionAttributeNames: map[string]string{
+					"#version": "version",
+				},
+			},
+		}}
+		if product.DeletedAt == "" {
+			items = append(items,
+				c.productCount(uuid, -1, "attribute_exists(#uuid)"),
+				c.productCount(to.Uuid, 1, "attribute_exists(#uuid) AND "+notDeleted),
+			)
+		}
+		return items
+	})
+}
+
+// TrashProducts moves the products of a category to the trash, where the
+// product purge deletes them with their variants and images, and takes them
+// out of the product count. Products already in the trash are skipped so the
+// deletion is safe to repeat.
+func (c *Category) TrashProducts(ctx context.Context, uuid string, deletedAt time.Time) (int, error) {
+	return c.updateProducts(ctx, uuid, func(product productItem) []types.TransactWriteItem {
+		if product.DeletedAt != "" {
+			return nil
+		}
+
+		return []types.TransactWriteItem{
+			{
+				Update: &types.Update{
+					TableName: aws.String(c.productTable),
+					Key: map[string]types.AttributeValue{
+						"uuid": &types.AttributeValueMemberS{Value: product.Uuid},
+					},
+					UpdateExpression:    aws.String("SET deleted_at = :deleted_at, updated_at = :deleted_at, #version = if_not_exists(#version, :zero) + :one"),
+					ConditionExpression: aws.String("category_uuid = :uuid AND " + notDeleted),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":uuid":       &types.AttributeValueMemberS{Value: uuid},
+						":deleted_at": &types.AttributeValueMemberS{Value: deletedAt.Format(time.DateTime)},
+						":zero":       &types.AttributeValueMemberN{Value: "0"},
+						":one":        &types.AttributeValueMemberN{Value: "1"},
+					},
+					ExpressionAttributeNames: map[string]string{
+						"#version": "version",
+					},
+				},
+			},
+			c.productCount(uuid, -1, "attribute_exists(#uuid)"),
+		}
+	})
+}
+
+// CountProducts recomputes the product count of every category from the
+// products outside the trash, for categories stored before counts were kept.
+// Products written while it runs may leave a count off by their change.
+func (c *Category) CountProducts(ctx context.Context) (int, error) {
+	paginator := dynamodb.NewScanPaginator(c.client, &dynamodb.ScanInput{
+		TableName:            aws.String(c.table),
+		ProjectionExpression: aws.String("#uuid"),
+		ExpressionAttributeNames: map[string]string{
+			"#uuid": "uuid",
+		},
+	})
+
+	var counted int
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return counted, fmt.Errorf("error executing scan: %w", err)
+		}
+
+		var categories []productItem
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &categories); err != nil {
+			return counted, fmt.Errorf("error unmarshaling items: %w", err)
+		}
+
+		for _, category := range categories {
+			if err = c.countProducts(ctx, category.Uuid); err != nil {
+				return counted, err
+			}
+			counted++
+		}
+	}
+
+	return counted, nil
+}
+
+// countProducts sets the product count of a category.
+func (c *Category) countProducts(ctx context.Context, uuid string) error {
+	paginator := dynamodb.NewQueryPaginator(c.client, &dynamodb.QueryInput{
+		TableName:              aws.String(c.productTable),
+		IndexName:              aws.String("GSI_CATEGORY"),
+		KeyConditionExpression: aws.String("category_uuid = :uuid"),
+		FilterExpression:       aws.String(notDeleted),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+		Select: types.SelectCount,
+	})
+
+	var count int32
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("error executing query: %w", err)
+		}
+		count += page.Count
+	}
+
+	_, err := c.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+		UpdateExpression: aws.String("SET product_count = :count"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":count": &types.AttributeValueMemberN{Value: strconv.Itoa(int(count))},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error updating item: %w", err)
+	}
+	return nil
+}
+
+// productCount adds delta to the product count of a category under
+// condition, so a count is never written to a category that is gone.
+func (c *Category) productCount(uuid string, delta int, condition string) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(c.table),
+			Key: map[string]types.AttributeValue{
+				"uuid": &types.AttributeValueMemberS{Value: uuid},
+			},
+			UpdateExpression:    aws.String("ADD product_count :delta"),
+			ConditionExpression: aws.String(condition),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":delta": &types.AttributeValueMemberN{Value: strconv.Itoa(delta)},
+			},
+			ExpressionAttributeNames: map[string]string{
+				"#uuid": "uuid",
+			},
+		},
+	}
+}
+
+// updateProducts writes the transaction built by update for every product of
+// a category. Products whose own condition fails have changed since the
+// query and are skipped, a failed category count means the other category is
+// gone. It returns the number of products written.
+func (c *Category) updateProducts(ctx context.Context, uuid string, update func(product productItem) []types.TransactWriteItem) (int, error) {
+	var updated int
+	err := c.productPages(ctx, uuid, func(products []productItem) error {
+		for _, product := range products {
+			items := update(product)
+			if len(items) == 0 {
+				continue
+			}
+
+			_, err := c.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+				TransactItems: items,
+			})
+			var errf *types.TransactionCanceledException
+			if errors.As(err, &errf) {
+				if failed(errf, 0) {
+					continue
+				}
+				if failed(errf, 1) || failed(errf, 2) {
+					return domain.ErrCategory
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("error updating item: %w", err)
+			}
+			updated++
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// productPages calls page with the products of a category found through
+// GSI_CATEGORY, trashed ones included, one query page at a time.
+func (c *Category) productPages(ctx context.Context, uuid string, page func(products []productItem) error) error {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(c.productTable),
+		IndexName:              aws.String("GSI_CATEGORY"),
+		KeyConditionExpression: aws.String("category_uuid = :uuid"),
+		ProjectionExpression:   aws.String("#uuid, deleted_at"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+		ExpressionAttributeNames: map[string]string{
+			"#uuid": "uuid",
+		},
+	}
+
+	paginator := dynamodb.NewQueryPaginator(c.client, input)
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("error executing query: %w", err)
+		}
+
+		var products []productItem
+		if err = attributevalue.UnmarshalListOfMaps(result.Items, &products); err != nil {
+			return fmt.Errorf("error unmarshaling items: %w", err)
+		}
+
+		if err = page(products); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// failed reports whether the condition of the item at index failed.
+func failed(err *types.TransactionCanceledException, index int) bool {
+	return index < len(err.CancellationReasons) && aws.ToString(err.CancellationReasons[index].Code) == "ConditionalCheckFailed"
+}
@@ -0,0 +1,148 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// fakeProducts serves the product queries and transactions of a category
+// rename from memory. Updates of stale products fail their condition.
+type fakeProducts struct {
+	mu       sync.Mutex
+	products []string
+	stale    map[string]bool
+	// conflict cancels every transaction for a reason other than a condition
+	conflict bool
+	renamed  map[string]bool
+	// unversioned counts the renames that leave the product version as is
+	unversioned int
+	batches     []int
+}
+
+func (f *fakeProducts) repository(t *testing.T) *Category {
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	client := dynamodb.New(dynamodb.Options{
+		BaseEndpoint: aws.String(server.URL),
+		Region:       "us-east-1",
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	return NewCategory(slog.New(slog.NewTextHandler(io.Discard, nil)), client)
+}
+
+func (f *fakeProducts) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.") {
+	case "Query":
+		items := make([]map[string]any, len(f.products))
+		for i, uuid := range f.products {
+			items[i] = map[string]any{"uuid": map[string]string{"S": uuid}}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"Items": items, "Count": len(items)})
+	case "TransactWriteItems":
+		var request struct {
+			TransactItems []struct {
+				Update struct {
+					Key                      map[string]map[string]string
+					UpdateExpression         string
+					ExpressionAttributeNames map[string]string
+				}
+			}
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		f.batches = append(f.batches, len(request.TransactItems))
+
+		var (
+			reasons  = make([]map[string]string, len(request.TransactItems))
+			canceled = f.conflict
+		)
+		for i, write := range request.TransactItems {
+			reasons[i] = map[string]string{"Code": "None"}
+			if f.conflict {
+				reasons[i]["Code"] = "TransactionConflict"
+			}
+			if f.stale[write.Update.Key["uuid"]["S"]] {
+				reasons[i]["Code"] = "ConditionalCheckFailed"
+				canceled = true
+			}
+		}
+		if canceled {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{
+				"__type":              "com.amazonaws.dynamodb.v20120810#TransactionCanceledException",
+				"message":             "Transaction cancelled",
+				"CancellationReasons": reasons,
+			})
+			return
+		}
+
+		for _, write := range request.TransactItems {
+			f.renamed[write.Update.Key["uuid"]["S"]] = true
+			if !strings.Contains(write.Update.UpdateExpression, "#version = if_not_exists(#version, :zero) + :one") || write.Update.ExpressionAttributeNames["#version"] != "version" {
+				f.unversioned++
+			}
+		}
+		json.NewEncoder(w).Encode(struct{}{})
+	default:
+		json.NewEncoder(w).Encode(struct{}{})
+	}
+}
+
+func TestRenameProducts(t *testing.T) {
+	tests := []struct {
+		name        string
+		products    int
+		stale       []int
+		conflict    bool
+		wantRenamed int
+		wantBatches []int
+		wantErr     bool
+	}{
+		{name: "no products", wantBatches: nil},
+		{name: "one batch", products: 3, wantRenamed: 3, wantBatches: []int{3}},
+		{name: "batches of 100", products: 250, wantRenamed: 250, wantBatches: []int{100, 100, 50}},
+		{name: "stale products are skipped", products: 120, stale: []int{5, 110}, wantRenamed: 118, wantBatches: []int{100, 99, 20, 19}},
+		{name: "conflicts fail", products: 3, conflict: true, wantBatches: []int{3}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeProducts{stale: map[string]bool{}, renamed: map[string]bool{}, conflict: tt.conflict}
+			for i := 0; i < tt.products; i++ {
+				fake.products = append(fake.products, fmt.Sprintf("product-%03d", i))
+			}
+			for _, i := range tt.stale {
+				fake.stale[fake.products[i]] = true
+			}
+
+			renamed, err := fake.repository(t).RenameProducts(context.Background(), "category", "Boissons", time.Now())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %t", err, tt.wantErr)
+			}
+			if renamed != tt.wantRenamed || len(fake.renamed) != tt.wantRenamed {
+				t.Errorf("renamed = %d, stored %d, want %d", renamed, len(fake.renamed), tt.wantRenamed)
+			}
+			if fake.unversioned > 0 {
+				t.Errorf("%d renames left the version as is", fake.unversioned)
+			}
+			if fmt.Sprint(fake.batches) != fmt.Sprint(tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", fake.batches, tt.wantBatches)
+			}
+		})
+	}
+}
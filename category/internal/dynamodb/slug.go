@@ -0,0 +1,175 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// slugKind prefixes the slugs of categories in the slug table, which is shared
+// with the products.
+const slugKind = "category#"
+
+// slugItem reserves a slug for the category holding it, the item is kept
+// after a rename so the old slug still leads to the category.
+type slugItem struct {
+	Slug      string `dynamodbav:"slug"`
+	Uuid      string `dynamodbav:"uuid"`
+	CreatedAt string `dynamodbav:"created_at"`
+}
+
+// GetSlugOwner returns the UUID of the category holding a slug, either as its
+// current slug or as one it had before a rename.
+func (c *Category) GetSlugOwner(ctx context.Context, slug string) (string, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(c.slugTable),
+		Key: map[string]types.AttributeValue{
+			"slug": &types.AttributeValueMemberS{Value: slugKind + slug},
+		},
+	}
+
+	result, err := c.client.GetItem(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("error getting item: %w", err)
+	}
+
+	if len(result.Item) == 0 {
+		return "", domain.ErrNotFound
+	}
+
+	var item slugItem
+	if err = attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return "", fmt.Errorf("error unmarshaling item: %w", err)
+	}
+
+	return item.Uuid, nil
+}
+
+// DelSlugs frees the current and former slugs of a purged category.
+func (c *Category) DelSlugs(ctx context.Context, uuid string) error {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(c.slugTable),
+		IndexName:              aws.String("GSI_UUID"),
+		KeyConditionExpression: aws.String("#uuid = :uuid"),
+		ExpressionAttributeNames: map[string]string{
+			"#uuid": "uuid",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+	}
+
+	paginator := dynamodb.NewQueryPaginator(c.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("error executing query: %w", err)
+		}
+
+		for _, item := range page.Items {
+			_, err = c.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(c.slugTable),
+				Key: map[string]types.AttributeValue{
+					"slug": item["slug"],
+				},
+				// the slug may have been taken since the query
+				ConditionExpression: aws.String("#uuid = :uuid"),
+				ExpressionAttributeNames: map[string]string{
+					"#uuid": "uuid",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":uuid": &types.AttributeValueMemberS{Value: uuid},
+				},
+			})
+			var errf *types.ConditionalCheckFailedException
+			if err != nil && !errors.As(err, &errf) {
+				return fmt.Errorf("error deleting item: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateSlug runs a category update in a transaction with the guard of its
+// new slug. Transactions don't return the replaced item, so the category is
+// read just before the update; an image replaced in between is left to the
+// image collector.
+func (c *Category) updateSlug(ctx context.Context, input *dynamodb.UpdateItemInput, uuid, slug string, updatedAt time.Time) (*models.Category, error) {
+	current, err := c.GetCategory(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	guard, err := c.slugGuard(slug, uuid, updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Update: &types.Update{
+					TableName:                 input.TableName,
+					Key:                       input.Key,
+					UpdateExpression:          input.UpdateExpression,
+					ConditionExpression:       input.ConditionExpression,
+					ExpressionAttributeNames:  input.ExpressionAttributeNames,
+					ExpressionAttributeValues: input.ExpressionAttributeValues,
+				},
+			},
+			guard,
+		},
+	})
+	if err != nil {
+		var errf *types.TransactionCanceledException
+		if errors.As(err, &errf) {
+			if failed(errf, 0) {
+				return nil, domain.ErrNotFound
+			}
+			if failed(errf, 1) {
+				return nil, domain.ErrSlug
+			}
+		}
+
+		return nil, fmt.Errorf("error updating item: %w", err)
+	}
+
+	return current, nil
+}
+
+// slugGuard reserves slug for a category in the transaction writing it, it
+// fails when another category holds the slug. A category may take back one of
+// its former slugs.
+func (c *Category) slugGuard(slug, uuid string, createdAt time.Time) (types.TransactWriteItem, error) {
+	item, err := attributevalue.MarshalMap(slugItem{
+		Slug:      slugKind + slug,
+		Uuid:      uuid,
+		CreatedAt: createdAt.Format(time.DateTime),
+	})
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("error marshaling item: %w", err)
+	}
+
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(c.slugTable),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(slug) OR #uuid = :uuid"),
+			ExpressionAttributeNames: map[string]string{
+				"#uuid": "uuid",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":uuid": &types.AttributeValueMemberS{Value: uuid},
+			},
+		},
+	}, nil
+}
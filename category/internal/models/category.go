@@ -2,9 +2,46 @@ package models
 
 type Categories []*Category
 type Category struct {
-	Uuid      string `json:"uuid" dynamodbav:"uuid"`
-	Name      string `json:"name" dynamodbav:"name"`
-	Image     string `json:"image" dynamodbav:"image"`
-	CreatedAt string `json:"created_at" dynamodbav:"created_at"`
-	UpdatedAt string `json:"updated_at" dynamodbav:"updated_at"`
+	Uuid         string       `json:"uuid" dynamodbav:"uuid"`
+	Name         string       `json:"name" dynamodbav:"name"`
+	Slug         string       `json:"slug" dynamodbav:"slug,omitempty"`
+	ParentUuid   string       `json:"parent_uuid,omitempty" dynamodbav:"parent_uuid,omitempty"`
+	Position     int          `json:"position" dynamodbav:"position"`
+	ProductCount int          `json:"product_count" dynamodbav:"product_count"`
+	Image        string       `json:"image" dynamodbav:"image"`
+	ImageHash    string       `json:"-" dynamodbav:"image_hash,omitempty"`
+	Renditions   []*Rendition `json:"renditions,omitempty" dynamodbav:"renditions,omitempty"`
+	CreatedAt    string       `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt    string       `json:"updated_at" dynamodbav:"updated_at"`
+	DeletedAt    string       `json:"deleted_at,omitempty" dynamodbav:"deleted_at,omitempty"`
+}
+
+// CategoryUpdate is an updated category with the propagation of its name to
+// its products, an incomplete propagation is completed by repeating the update.
+type CategoryUpdate struct {
+	Category        *Category
+	ProductsRenamed int
+	RenameComplete  bool
+}
+
+// CategoryNode is a category with its subcategories.
+type CategoryNode struct {
+	*Category
+	Children []*CategoryNode `json:"children"`
+}
+
+type CategoryTree []*CategoryNode
+
+type Rendition struct {
+	Width  int    `json:"width" dynamodbav:"width"`
+	Format string `json:"format" dynamodbav:"format"`
+	Url    string `json:"url" dynamodbav:"url"`
+}
+
+type ImageReference struct {
+	Hash       string
+	References int64
+	Renditions []*Rendition
+	CreatedAt  string
+	UpdatedAt  string
 }
@@ -5,9 +5,10 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"net/http"
+	"net/url"
 	"os"
-	"path"
+	"shopy/internal/domain"
+	"shopy/pkg/imagex"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -15,51 +16,84 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
-const folderName = "category"
+const (
+	folderName = "category"
+	// imageFolder holds the renditions of every image keyed by the SHA-256 of
+	// the upload, shared with the products.
+	imageFolder = "images"
+)
+
+// cacheControl lets clients and CDNs cache renditions forever, the content
+// behind a key never changes.
+const cacheControl = "public, max-age=31536000, immutable"
 
 type Category struct {
-	logger *slog.Logger
-	client *s3.Client
-	bucket string
+	logger    *slog.Logger
+	client    *s3.Client
+	bucket    string
+	processor *imagex.Processor
 }
 
 func NewCategory(logger *slog.Logger, client *s3.Client) *Category {
 	bucket := os.Getenv("BUCKET_NAME")
+	widths, err := imagex.ParseWidths(os.Getenv("IMAGE_WIDTHS"))
+	if err != nil {
+		logger.Error("invalid image widths, using defaults", "error", err)
+	}
 	return &Category{
-		logger: logger,
-		client: client,
-		bucket: bucket,
+		logger:    logger,
+		client:    client,
+		bucket:    bucket,
+		processor: imagex.NewProcessor(widths),
 	}
 }
 
-func (c *Category) UploadImage(ctx context.Context, uuid string, image []byte) (string, error) {
+// UploadImage renders the image into its renditions and stores them under
+// the content hash of the upload.
+func (c *Category) UploadImage(ctx context.Context, hash string, image []byte) (domain.Renditions, error) {
+	processed, err := c.processor.Process(image)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
-		contentType = http.DetectContentType(image)
-		extension   = strings.Split(contentType, "/")[1]
-		filename    = fmt.Sprintf("%s.%s", uuid, extension)
-		filepath    = fmt.Sprintf("%s/%s", folderName, filename)
-		uploader    = manager.NewUploader(c.client)
+		renditions = make(domain.Renditions, len(processed))
+		uploader   = manager.NewUploader(c.client)
 	)
+	for i, rendition := range processed {
+		var (
+			filename = fmt.Sprintf("%d.%s", rendition.Width, rendition.Format.Extension())
+			filepath = fmt.Sprintf("%s/%s/%s", imageFolder, hash, filename)
+		)
 
-	result, err := uploader.Upload(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(c.bucket),
-		Key:         aws.String(filepath),
-		Body:        bytes.NewReader(image),
-		ContentType: aws.String(contentType),
-	})
-	if err != nil {
-		return "", fmt.Errorf("error uploading image: %w", err)
+		result, err := uploader.Upload(ctx, &s3.PutObjectInput{
+			Bucket:       aws.String(c.bucket),
+			Key:          aws.String(filepath),
+			Body:         bytes.NewReader(rendition.Data),
+			ContentType:  aws.String(rendition.Format.ContentType()),
+			CacheControl: aws.String(cacheControl),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error uploading image: %w", err)
+		}
+
+		renditions[i] = domain.Rendition{
+			Width:    rendition.Width,
+			Format:   string(rendition.Format),
+			Location: result.Location,
+		}
 	}
 
-	return result.Location, nil
+	return renditions, nil
 }
 
 func (c *Category) DeleteImage(ctx context.Context, location string) error {
-	var (
-		filename = path.Base(location)
-		filepath = fmt.Sprintf("%s/%s", folderName, filename)
-	)
-	_, err := c.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+	filepath, err := objectKey(location)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(c.bucket),
 		Key:    aws.String(filepath),
 	})
@@ -69,3 +103,19 @@ func (c *Category) DeleteImage(ctx context.Context, location string) error {
 
 	return nil
 }
+
+// objectKey returns the key of the object behind an image URL, for both
+// virtual-hosted and path-style URLs.
+func objectKey(location string) (string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("error parsing image location: %w", err)
+	}
+
+	for _, folder := range []string{imageFolder, folderName} {
+		if i := strings.Index(u.Path, "/"+folder+"/"); i >= 0 {
+			return u.Path[i+1:], nil
+		}
+	}
+	return "", fmt.Errorf("image %q is not in the %s or %s folder", location, imageFolder, folderName)
+}
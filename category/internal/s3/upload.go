@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"shopy/internal/domain"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// GetUpload reads an uploaded image, at most maxBytes+1 bytes are read so
+// oversized objects are detected without loading them whole.
+func (c *Category) GetUpload(ctx context.Context, uuid string, maxBytes int) ([]byte, error) {
+	object, err := c.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(domain.UploadKey(uuid)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, domain.ErrUpload
+		}
+		return nil, fmt.Errorf("error getting upload: %w", err)
+	}
+	defer object.Body.Close()
+
+	image, err := io.ReadAll(io.LimitReader(object.Body, int64(maxBytes)+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading upload: %w", err)
+	}
+
+	return image, nil
+}
+
+func (c *Category) DeleteUpload(ctx context.Context, uuid string) error {
+	_, err := c.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(domain.UploadKey(uuid)),
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting upload: %w", err)
+	}
+
+	return nil
+}
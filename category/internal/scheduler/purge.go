@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const defaultRetentionDays = 30
+
+type PurgeService interface {
+	PurgeCategories(ctx context.Context, before time.Time) (int, error)
+}
+
+type Purge struct {
+	logger    *slog.Logger
+	service   PurgeService
+	retention time.Duration
+}
+
+func NewPurge(logger *slog.Logger, service PurgeService) *Purge {
+	days, err := strconv.Atoi(os.Getenv("RETENTION_DAYS"))
+	if err != nil || days < 0 {
+		logger.Warn("invalid retention days, using default", "default", defaultRetentionDays)
+		days = defaultRetentionDays
+	}
+	return &Purge{
+		logger:    logger,
+		service:   service,
+		retention: time.Duration(days) * 24 * time.Hour,
+	}
+}
+
+// Handler permanently deletes the categories that have been in the trash longer than the retention period.
+func (p *Purge) Handler() func(ctx context.Context, event events.EventBridgeEvent) error {
+	return func(ctx context.Context, event events.EventBridgeEvent) error {
+		before := time.Now().UTC().Add(-p.retention)
+		purged, err := p.service.PurgeCategories(ctx, before)
+		if err != nil {
+			p.logger.Error("error purging categories", "purged", purged, "error", err)
+			return err
+		}
+
+		p.logger.Info("categories purged", "purged", purged, "before", before.Format(time.DateTime))
+		return nil
+	}
+}
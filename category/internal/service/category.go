@@ -2,26 +2,53 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"os"
 	"shopy/internal/domain"
 	"shopy/internal/models"
+	"shopy/pkg/imagex"
+	"shopy/pkg/saga"
+	"sort"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
 
 type Repository interface {
 	GetCategories(ctx context.Context) (models.Categories, error)
+	GetCategory(ctx context.Context, uuid string) (*models.Category, error)
+	ReorderCategories(ctx context.Context, order domain.CategoryOrder) error
+	GetSlugOwner(ctx context.Context, slug string) (string, error)
+	DelSlugs(ctx context.Context, uuid string) error
 	AddCategory(ctx context.Context, params domain.CategoryParams) (*models.Category, error)
+	PutCategory(ctx context.Context, params domain.CategoryParams) (*models.Category, error)
+	HasProducts(ctx context.Context, uuid string) (bool, error)
+	RenameProducts(ctx context.Context, uuid, name string, updatedAt time.Time) (int, error)
+	ReassignProducts(ctx context.Context, uuid string, to *models.Category, updatedAt time.Time) (int, error)
+	TrashProducts(ctx context.Context, uuid string, deletedAt time.Time) (int, error)
+	GetTrashCategories(ctx context.Context) (models.Categories, error)
+	TrashCategory(ctx context.Context, uuid string, deletedAt time.Time) (*models.Category, error)
+	RestoreCategory(ctx context.Context, uuid string, updatedAt time.Time) (*models.Category, error)
 	DelCategory(ctx context.Context, uuid string) (*models.Category, error)
+	AcquireImage(ctx context.Context, hash string, updatedAt time.Time) (*models.ImageReference, error)
+	PutImageRenditions(ctx context.Context, hash string, renditions domain.Renditions) error
+	ReleaseImage(ctx context.Context, hash string) (bool, error)
 }
 
 type Storage interface {
-	UploadImage(ctx context.Context, uuid string, image []byte) (string, error)
+	UploadImage(ctx context.Context, hash string, image []byte) (domain.Renditions, error)
 	DeleteImage(ctx context.Context, image string) error
+	GetUpload(ctx context.Context, uuid string, maxBytes int) ([]byte, error)
+	DeleteUpload(ctx context.Context, uuid string) error
 }
 
 type Category struct {
 	logger     *slog.Logger
 	repository Repository
 	storage    Storage
+	limits     imagex.Limits
 }
 
 func NewCategory(logger *slog.Logger, repository Repository, storage Storage) *Category {
@@ -29,32 +56,370 @@ func NewCategory(logger *slog.Logger, repository Repository, storage Storage) *C
 		logger:     logger,
 		repository: repository,
 		storage:    storage,
+		limits:     imagex.NewLimits(os.Getenv("IMAGE_MAX_BYTES"), os.Getenv("IMAGE_MAX_DIMENSION")),
 	}
 }
 
+// GetCategories returns the categories sorted by position, then by name.
 func (c *Category) GetCategories(ctx context.Context) (models.Categories, error) {
-	return c.repository.GetCategories(ctx)
+	categories, err := c.repository.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(categories, func(i, j int) bool {
+		return before(categories[i], categories[j])
+	})
+	return categories, nil
+}
+
+func (c *Category) GetCategory(ctx context.Context, uuid string) (*models.Category, error) {
+	return c.repository.GetCategory(ctx, uuid)
 }
 
+// AddCategory adds a category after the existing ones.
 func (c *Category) AddCategory(ctx context.Context, params domain.CategoryParams) (*models.Category, error) {
-	location, err := c.storage.UploadImage(ctx, params.Uuid, params.Image)
+	categories, err := c.repository.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkParent(categories, params.Uuid, params.ParentUuid); err != nil {
+		return nil, err
+	}
+
+	for _, category := range categories {
+		params.Position = max(params.Position, category.Position+1)
+	}
+
+	custom := params.Slug
+	params.Slug, err = c.newSlug(ctx, params.Uuid, params.Name, custom)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := c.uploadedImage(ctx, params.Image, params.Upload)
 	if err != nil {
 		return nil, err
 	}
 
-	params.Location = location
-	category, err := c.repository.AddCategory(ctx, params)
+	tx := saga.New(c.logger)
+	params.Hash, params.Renditions, err = c.storeImage(ctx, tx, image)
 	if err != nil {
 		return nil, err
 	}
 
+	params.Location = params.Renditions.Location()
+	var category *models.Category
+	err = tx.Do(ctx, "add category", func(ctx context.Context) error {
+		return retrySlug(params.Slug, custom == "", func() (string, error) {
+			return c.newSlug(ctx, params.Uuid, params.Name, "")
+		}, func(slug string) (err error) {
+			params.Slug = slug
+			category, err = c.repository.AddCategory(ctx, params)
+			return err
+		})
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.discardUpload(ctx, params.Upload)
 	return category, nil
 }
 
-func (c *Category) DelCategory(ctx context.Context, uuid string) error {
-	category, err := c.repository.DelCategory(ctx, uuid)
+// PutCategory updates the name and parent of a category and replaces its image
+// when one is sent, then copies the name to its products. A rename moves the
+// category to a new slug, the old one still leads to it. The propagation runs
+// on every update and is idempotent: once the category is written a failed
+// propagation doesn't fail the update, which is reported incomplete so that
+// retrying it completes the propagation.
+func (c *Category) PutCategory(ctx context.Context, params domain.CategoryParams) (*models.CategoryUpdate, error) {
+	if params.ParentUuid != "" {
+		categories, err := c.repository.GetCategories(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = checkParent(categories, params.Uuid, params.ParentUuid); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		err    error
+		custom = params.Slug
+	)
+	params.Slug, err = c.renamedSlug(ctx, params.Uuid, params.Name, custom)
 	if err != nil {
+		return nil, err
+	}
+
+	image, err := c.uploadedImage(ctx, params.Image, params.Upload)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := saga.New(c.logger)
+	if image != nil {
+		params.Hash, params.Renditions, err = c.storeImage(ctx, tx, image)
+		if err != nil {
+			return nil, err
+		}
+		params.Location = params.Renditions.Location()
+	}
+
+	var replaced *models.Category
+	err = tx.Do(ctx, "put category", func(ctx context.Context) error {
+		return retrySlug(params.Slug, custom == "" && params.Slug != "", func() (string, error) {
+			return c.renamedSlug(ctx, params.Uuid, params.Name, "")
+		}, func(slug string) (err error) {
+			params.Slug = slug
+			replaced, err = c.repository.PutCategory(ctx, params)
+			return err
+		})
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	category := *replaced
+	category.Name = params.Name
+	category.ParentUuid = params.ParentUuid
+	if params.Slug != "" {
+		category.Slug = params.Slug
+	}
+	category.UpdatedAt = params.UpdatedAt.Format(time.DateTime)
+	if image != nil {
+		c.discardUpload(ctx, params.Upload)
+		tx.Finally(ctx, "delete replaced image", func(ctx context.Context) error {
+			return c.deleteImage(ctx, replaced)
+		})
+
+		category.Image = params.Location
+		category.ImageHash = params.Hash
+		category.Renditions = categoryRenditions(params.Renditions)
+	}
+
+	renamed, err := c.repository.RenameProducts(ctx, params.Uuid, params.Name, params.UpdatedAt)
+	if err != nil {
+		c.logger.Error("error renaming category products", "uuid", params.Uuid, "products_renamed", renamed, "error", err)
+	}
+
+	c.logger.Info("category updated", "uuid", params.Uuid, "products_renamed", renamed)
+	return &models.CategoryUpdate{
+		Category:        &category,
+		ProductsRenamed: renamed,
+		RenameComplete:  err == nil,
+	}, nil
+}
+
+// ReorderCategories sets the position of the listed categories to their index.
+func (c *Category) ReorderCategories(ctx context.Context, order domain.CategoryOrder) error {
+	listed := make(map[string]bool, len(order.Uuids))
+	for _, uuid := range order.Uuids {
+		if listed[uuid] {
+			return domain.ErrOrder
+		}
+		listed[uuid] = true
+	}
+
+	err := c.repository.ReorderCategories(ctx, order)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrOrder
+	}
+	return err
+}
+
+// DelCategory moves a category to the trash once no product references it.
+// Products are first reassigned or trashed when a cascade is given, both are
+// idempotent so a deletion that failed half way can be sent again. The trash
+// itself only applies while the product count is 0, so products written
+// concurrently fail it with ErrCategoryInUse.
+func (c *Category) DelCategory(ctx context.Context, params domain.CategoryDelete) error {
+	if _, err := c.repository.GetCategory(ctx, params.Uuid); err != nil {
+		return err
+	}
+
+	switch params.Cascade {
+	case domain.CascadeReassign:
+		to, err := c.repository.GetCategory(ctx, params.To)
+		if errors.Is(err, domain.ErrNotFound) || err == nil && to.Uuid == params.Uuid {
+			return domain.ErrCategory.Wrap(validation.Errors{"to": errors.New("must reference another existing category")})
+		}
+		if err != nil {
+			return err
+		}
+
+		reassigned, err := c.repository.ReassignProducts(ctx, params.Uuid, to, params.DeletedAt)
+		if err != nil {
+			return err
+		}
+		c.logger.Info("category products reassigned", "uuid", params.Uuid, "to", to.Uuid, "products", reassigned)
+	case domain.CascadeDelete:
+		trashed, err := c.repository.TrashProducts(ctx, params.Uuid, params.DeletedAt)
+		if err != nil {
+			return err
+		}
+		c.logger.Info("category products trashed", "uuid", params.Uuid, "products", trashed)
+	default:
+		// the product count leaves out trashed products, which can still
+		// be restored, and categories stored before counts were kept
+		referenced, err := c.repository.HasProducts(ctx, params.Uuid)
+		if err != nil {
+			return err
+		}
+		if referenced {
+			return domain.ErrCategoryInUse
+		}
+	}
+
+	_, err := c.repository.TrashCategory(ctx, params.Uuid, params.DeletedAt)
+	return err
+}
+
+func (c *Category) GetTrashCategories(ctx context.Context) (models.Categories, error) {
+	return c.repository.GetTrashCategories(ctx)
+}
+
+func (c *Category) RestoreCategory(ctx context.Context, uuid string) (*models.Category, error) {
+	return c.repository.RestoreCategory(ctx, uuid, time.Now().UTC())
+}
+
+// PurgeCategories permanently deletes the categories and images trashed before the given time.
+func (c *Category) PurgeCategories(ctx context.Context, before time.Time) (int, error) {
+	categories, err := c.repository.GetTrashCategories(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		purged int
+		errs   []error
+		cutoff = before.Format(time.DateTime)
+	)
+	for _, category := range categories {
+		if category.DeletedAt >= cutoff {
+			continue
+		}
+
+		if _, err = c.repository.DelCategory(ctx, category.Uuid); err != nil {
+			// a category failing to purge is retried on the next run
+			c.logger.Error("error purging category", "uuid", category.Uuid, "error", err)
+			errs = append(errs, fmt.Errorf("purging category %s: %w", category.Uuid, err))
+			continue
+		}
+
+		tx := saga.New(c.logger)
+		tx.Finally(ctx, "delete image", func(ctx context.Context) error {
+			return c.deleteImage(ctx, category)
+		})
+		tx.Finally(ctx, "free slugs", func(ctx context.Context) error {
+			return c.repository.DelSlugs(ctx, category.Uuid)
+		})
+
+		c.logger.Info("category purged", "uuid", category.Uuid, "deleted_at", category.DeletedAt)
+		purged++
+	}
+
+	return purged, errors.Join(errs...)
+}
+
+// storeImage stores an image under its content hash as steps of tx and
+// returns the hash with the renditions, which are only rendered and uploaded
+// for the first reference to the content. When tx is compensated the
+// reference is released and the renditions deleted if it was the last one.
+func (c *Category) storeImage(ctx context.Context, tx *saga.Saga, image []byte) (string, domain.Renditions, error) {
+	var (
+		hash   = domain.ImageHash(image)
+		stored domain.Renditions
+	)
+
+	err := tx.Do(ctx, "acquire image", func(ctx context.Context) error {
+		reference, err := c.repository.AcquireImage(ctx, hash, time.Now().UTC())
+		if err == nil && len(reference.Renditions) > 0 {
+			stored = renditions(reference.Renditions)
+		}
 		return err
+	}, func(ctx context.Context) error {
+		return c.deleteImage(ctx, &models.Category{ImageHash: hash, Renditions: categoryRenditions(stored)})
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if len(stored) > 0 {
+		return hash, stored, nil
+	}
+
+	err = tx.Do(ctx, "upload image", func(ctx context.Context) (err error) {
+		stored, err = c.storage.UploadImage(ctx, hash, image)
+		return err
+	}, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = tx.Do(ctx, "record renditions", func(ctx context.Context) error {
+		return c.repository.PutImageRenditions(ctx, hash, stored)
+	}, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return hash, stored, nil
+}
+
+// deleteImage deletes every rendition of the category image, or the single
+// image stored before renditions were introduced. Content addressed images
+// are only deleted with their last reference.
+func (c *Category) deleteImage(ctx context.Context, category *models.Category) error {
+	if category.ImageHash != "" {
+		last, err := c.repository.ReleaseImage(ctx, category.ImageHash)
+		if err != nil || !last {
+			return err
+		}
+	}
+
+	locations := make([]string, 0, len(category.Renditions))
+	for _, rendition := range category.Renditions {
+		locations = append(locations, rendition.Url)
+	}
+	if len(locations) == 0 && category.Image != "" {
+		locations = append(locations, category.Image)
+	}
+
+	// one object failing to delete doesn't keep the others
+	var errs []error
+	for _, location := range locations {
+		if err := c.storage.DeleteImage(ctx, location); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func renditions(renditions []*models.Rendition) domain.Renditions {
+	converted := make(domain.Renditions, len(renditions))
+	for i, rendition := range renditions {
+		converted[i] = domain.Rendition{
+			Width:    rendition.Width,
+			Format:   rendition.Format,
+			Location: rendition.Url,
+		}
+	}
+	return converted
+}
+
+// categoryRenditions converts domain renditions to the renditions of a
+// category, the inverse of renditions.
+func categoryRenditions(renditions domain.Renditions) []*models.Rendition {
+	converted := make([]*models.Rendition, len(renditions))
+	for i, rendition := range renditions {
+		converted[i] = &models.Rendition{
+			Width:  rendition.Width,
+			Format: rendition.Format,
+			Url:    rendition.Location,
+		}
 	}
-	return c.storage.DeleteImage(ctx, category.Image)
+	return converted
 }
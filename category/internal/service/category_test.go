@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"testing"
+	"time"
+)
+
+const testCategoryUuid = "8c1a8a5e-8bd6-4b0e-9d5c-0a4c43f7d0a1"
+
+func TestAddCategoryImage(t *testing.T) {
+	image := []byte("image")
+	hash := domain.ImageHash(image)
+
+	tests := []struct {
+		name           string
+		writeErr       error
+		wantErr        error
+		wantReferences int64
+		wantObjects    int
+	}{
+		{name: "new image", wantReferences: 1, wantObjects: 2},
+		{name: "write fails deletes the uploaded image", writeErr: errFake, wantErr: errFake},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeRepository()
+			repository.writeErr = tt.writeErr
+			storage := newFakeStorage()
+
+			_, err := testService(repository, storage).AddCategory(context.Background(), domain.CategoryParams{
+				Uuid:  testCategoryUuid,
+				Name:  "Boissons",
+				Image: image,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			var references int64
+			if reference, ok := repository.references[hash]; ok {
+				references = reference.References
+			}
+			if references != tt.wantReferences {
+				t.Errorf("references = %d, want %d", references, tt.wantReferences)
+			}
+			if len(storage.objects) != tt.wantObjects {
+				t.Errorf("stored objects = %v, want %d", storage.objects, tt.wantObjects)
+			}
+		})
+	}
+}
+
+func TestPutCategoryReplacedImageDeleteFails(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		repository = newFakeRepository()
+		storage    = newFakeStorage()
+		service    = testService(repository, storage)
+	)
+	if _, err := service.AddCategory(ctx, domain.CategoryParams{Uuid: testCategoryUuid, Name: "Boissons", Image: []byte("old")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the category is written before the replaced image is deleted
+	storage.deleteErr = errFake
+	update, err := service.PutCategory(ctx, domain.CategoryParams{
+		Uuid:      testCategoryUuid,
+		Name:      "Boissons",
+		Image:     []byte("new"),
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("error = %v, want the update to succeed", err)
+	}
+	if update.Category.ImageHash != domain.ImageHash([]byte("new")) {
+		t.Errorf("image hash = %q, want the new image", update.Category.ImageHash)
+	}
+}
+
+func TestPutCategoryRenameFails(t *testing.T) {
+	tests := []struct {
+		name         string
+		renameErr    error
+		wantComplete bool
+	}{
+		{name: "complete", wantComplete: true},
+		{name: "failed half way", renameErr: errFake},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				ctx        = context.Background()
+				repository = newFakeRepository()
+				service    = testService(repository, newFakeStorage())
+			)
+			if _, err := service.AddCategory(ctx, domain.CategoryParams{Uuid: testCategoryUuid, Name: "Boissons"}); err != nil {
+				t.Fatal(err)
+			}
+			repository.renamed = 150
+			repository.renameErr = tt.renameErr
+
+			// the category is written before its products are renamed
+			update, err := service.PutCategory(ctx, domain.CategoryParams{
+				Uuid:      testCategoryUuid,
+				Name:      "Boissons chaudes",
+				UpdatedAt: time.Now().UTC(),
+			})
+			if err != nil {
+				t.Fatalf("error = %v, want the update to succeed", err)
+			}
+			if update.Category.Name != "Boissons chaudes" {
+				t.Errorf("name = %q, want the new name", update.Category.Name)
+			}
+			if update.ProductsRenamed != 150 || update.RenameComplete != tt.wantComplete {
+				t.Errorf("renamed = %d complete = %t, want 150 %t", update.ProductsRenamed, update.RenameComplete, tt.wantComplete)
+			}
+		})
+	}
+}
+
+func TestPurgeCategoriesImageDeleteFails(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		repository = newFakeRepository()
+		storage    = newFakeStorage()
+		service    = testService(repository, storage)
+	)
+	if _, err := service.AddCategory(ctx, domain.CategoryParams{Uuid: testCategoryUuid, Name: "Boissons", Slug: "boissons", Image: []byte("image")}); err != nil {
+		t.Fatal(err)
+	}
+	repository.categories[testCategoryUuid].DeletedAt = "2026-01-01 00:00:00"
+	repository.categories[testCategoryUuid].Renditions = []*models.Rendition{
+		{Url: "https://cdn.example.com/a.webp"},
+	}
+
+	// the category is deleted before its image
+	storage.deleteErr = errFake
+	purged, err := service.PurgeCategories(ctx, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("error = %v, want the purge to succeed", err)
+	}
+	if purged != 1 {
+		t.Errorf("purged = %d, want 1", purged)
+	}
+	if _, ok := repository.categories[testCategoryUuid]; ok {
+		t.Error("category still stored")
+	}
+	if _, ok := repository.slugs["boissons"]; ok {
+		t.Error("slug still held after the image failed to delete")
+	}
+}
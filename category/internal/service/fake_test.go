@@ -0,0 +1,222 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"shopy/pkg/imagex"
+	"time"
+)
+
+var errFake = errors.New("fake failure")
+
+// fakeRepository keeps categories, slugs and image references in memory. The
+// methods a test doesn't use panic through the nil Repository.
+type fakeRepository struct {
+	Repository
+
+	categories map[string]*models.Category
+	slugs      map[string]string
+	references map[string]*models.ImageReference
+
+	// writeErr fails the category writes
+	writeErr error
+	// race runs before each category write with the slug being written,
+	// standing for a concurrent write
+	race func(slug string)
+	// renamed products are reported by RenameProducts, which then fails
+	// with renameErr
+	renamed   int
+	renameErr error
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		categories: map[string]*models.Category{},
+		slugs:      map[string]string{},
+		references: map[string]*models.ImageReference{},
+	}
+}
+
+func (r *fakeRepository) GetCategories(context.Context) (models.Categories, error) {
+	categories := models.Categories{}
+	for _, category := range r.categories {
+		if category.DeletedAt == "" {
+			categories = append(categories, category)
+		}
+	}
+	return categories, nil
+}
+
+func (r *fakeRepository) GetCategory(_ context.Context, uuid string) (*models.Category, error) {
+	category, ok := r.categories[uuid]
+	if !ok || category.DeletedAt != "" {
+		return nil, domain.ErrNotFound
+	}
+	return category, nil
+}
+
+func (r *fakeRepository) GetSlugOwner(_ context.Context, slug string) (string, error) {
+	owner, ok := r.slugs[slug]
+	if !ok {
+		return "", domain.ErrNotFound
+	}
+	return owner, nil
+}
+
+func (r *fakeRepository) DelSlugs(_ context.Context, uuid string) error {
+	for slug, owner := range r.slugs {
+		if owner == uuid {
+			delete(r.slugs, slug)
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) AddCategory(_ context.Context, params domain.CategoryParams) (*models.Category, error) {
+	if r.race != nil {
+		r.race(params.Slug)
+	}
+	if r.writeErr != nil {
+		return nil, r.writeErr
+	}
+	if _, ok := r.slugs[params.Slug]; ok {
+		return nil, domain.ErrSlug
+	}
+	category := &models.Category{
+		Uuid:      params.Uuid,
+		Name:      params.Name,
+		Slug:      params.Slug,
+		Image:     params.Location,
+		ImageHash: params.Hash,
+	}
+	r.categories[params.Uuid] = category
+	r.slugs[params.Slug] = params.Uuid
+	return category, nil
+}
+
+// PutCategory returns the category as it was before the update, like the
+// DynamoDB repository does.
+func (r *fakeRepository) PutCategory(_ context.Context, params domain.CategoryParams) (*models.Category, error) {
+	if r.writeErr != nil {
+		return nil, r.writeErr
+	}
+	replaced, ok := r.categories[params.Uuid]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	category := *replaced
+	category.Name = params.Name
+	if params.Location != "" {
+		category.Image = params.Location
+		category.ImageHash = params.Hash
+	}
+	r.categories[params.Uuid] = &category
+	return replaced, nil
+}
+
+func (r *fakeRepository) RenameProducts(context.Context, string, string, time.Time) (int, error) {
+	return r.renamed, r.renameErr
+}
+
+func (r *fakeRepository) GetTrashCategories(context.Context) (models.Categories, error) {
+	categories := models.Categories{}
+	for _, category := range r.categories {
+		if category.DeletedAt != "" {
+			categories = append(categories, category)
+		}
+	}
+	return categories, nil
+}
+
+func (r *fakeRepository) DelCategory(_ context.Context, uuid string) (*models.Category, error) {
+	category, ok := r.categories[uuid]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	delete(r.categories, uuid)
+	return category, nil
+}
+
+func (r *fakeRepository) AcquireImage(_ context.Context, hash string, updatedAt time.Time) (*models.ImageReference, error) {
+	reference, ok := r.references[hash]
+	if !ok {
+		reference = &models.ImageReference{Hash: hash}
+		r.references[hash] = reference
+	}
+	reference.References++
+	return reference, nil
+}
+
+func (r *fakeRepository) PutImageRenditions(_ context.Context, hash string, renditions domain.Renditions) error {
+	for _, rendition := range renditions {
+		r.references[hash].Renditions = append(r.references[hash].Renditions, &models.Rendition{
+			Width:  rendition.Width,
+			Format: rendition.Format,
+			Url:    rendition.Location,
+		})
+	}
+	return nil
+}
+
+func (r *fakeRepository) ReleaseImage(_ context.Context, hash string) (bool, error) {
+	reference, ok := r.references[hash]
+	if !ok {
+		return false, nil
+	}
+	reference.References--
+	if reference.References > 0 {
+		return false, nil
+	}
+	delete(r.references, hash)
+	return true, nil
+}
+
+// fakeStorage keeps the uploaded renditions in memory.
+type fakeStorage struct {
+	Storage
+
+	objects map[string]bool
+
+	// deleteErr fails the image deletions
+	deleteErr error
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{objects: map[string]bool{}}
+}
+
+func (s *fakeStorage) UploadImage(_ context.Context, hash string, _ []byte) (domain.Renditions, error) {
+	var renditions domain.Renditions
+	for _, width := range []int{320, 1280} {
+		location := fmt.Sprintf("https://cdn.example.com/images/%s/%d.webp", hash, width)
+		s.objects[location] = true
+		renditions = append(renditions, domain.Rendition{Width: width, Format: "webp", Location: location})
+	}
+	return renditions, nil
+}
+
+func (s *fakeStorage) DeleteImage(_ context.Context, image string) error {
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	delete(s.objects, image)
+	return nil
+}
+
+func (s *fakeStorage) DeleteUpload(context.Context, string) error {
+	return nil
+}
+
+func testService(repository Repository, storage Storage) *Category {
+	return &Category{
+		logger:     slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		repository: repository,
+		storage:    storage,
+		limits:     imagex.Limits{MaxBytes: imagex.DefaultMaxBytes, MaxDimension: imagex.DefaultMaxDimension},
+	}
+}
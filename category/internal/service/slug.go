@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"shopy/pkg/slug"
+	"strings"
+)
+
+// slugAttempts bounds the numbered candidates tried for a generated slug.
+const slugAttempts = 100
+
+// slugWrites bounds the writes of a generated slug, another category may take
+// it between the check and the write.
+const slugWrites = 3
+
+// GetCategoryBySlug returns the category holding a slug, the current one or
+// one it had before a rename.
+func (c *Category) GetCategoryBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	uuid, err := c.repository.GetSlugOwner(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.repository.GetCategory(ctx, uuid)
+}
+
+// newSlug returns the slug of a category: the one sent by the client unless
+// another category holds it, or one made from the name and numbered until a
+// free one is found.
+func (c *Category) newSlug(ctx context.Context, uuid, name, custom string) (string, error) {
+	if custom != "" {
+		free, err := c.freeSlug(ctx, uuid, custom)
+		if err != nil {
+			return "", err
+		}
+		if !free {
+			return "", domain.ErrSlug
+		}
+		return custom, nil
+	}
+
+	base := slug.Make(name)
+	if base == "" {
+		// the name has no letters to transliterate
+		base, _, _ = strings.Cut(uuid, "-")
+	}
+	for n := 1; n <= slugAttempts; n++ {
+		candidate := slug.Number(base, n)
+		free, err := c.freeSlug(ctx, uuid, candidate)
+		if err != nil {
+			return "", err
+		}
+		if free {
+			return candidate, nil
+		}
+	}
+	return "", domain.ErrSlug
+}
+
+// renamedSlug returns the new slug of a category being updated, or an empty
+// one when it keeps its slug: the client slug when it differs, or a slug made
+// from the name when the name changes or the category has none yet.
+func (c *Category) renamedSlug(ctx context.Context, uuid, name, custom string) (string, error) {
+	current, err := c.repository.GetCategory(ctx, uuid)
+	if err != nil {
+		return "", err
+	}
+
+	if custom == "" && name == current.Name && current.Slug != "" {
+		return "", nil
+	}
+
+	slug, err := c.newSlug(ctx, uuid, name, custom)
+	if err != nil || slug == current.Slug {
+		return "", err
+	}
+	return slug, nil
+}
+
+// retrySlug runs write with slug. When the slug was generated and another
+// category took it since it was checked, the write is retried with the slug
+// returned by next, which is then the next free candidate. Client slugs keep
+// failing with ErrSlug.
+func retrySlug(slug string, generated bool, next func() (string, error), write func(slug string) error) error {
+	for attempt := 1; ; attempt++ {
+		err := write(slug)
+		if !errors.Is(err, domain.ErrSlug) || !generated || attempt == slugWrites {
+			return err
+		}
+
+		if slug, err = next(); err != nil {
+			return err
+		}
+	}
+}
+
+// freeSlug reports whether a category can take a slug, either nobody holds it
+// or the category held it before.
+func (c *Category) freeSlug(ctx context.Context, uuid, slug string) (bool, error) {
+	owner, err := c.repository.GetSlugOwner(ctx, slug)
+	if errors.Is(err, domain.ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return owner == uuid, nil
+}
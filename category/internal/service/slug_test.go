@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"testing"
+)
+
+func TestAddCategorySlugRace(t *testing.T) {
+	const other = "5d7e0a3c-1b2f-4e8a-9c6d-3f4a5b6c7d8e"
+
+	tests := []struct {
+		name    string
+		custom  string
+		taken   func(slug string) bool
+		want    string
+		wantErr error
+	}{
+		{name: "generated slug taken before the write", taken: func(slug string) bool { return slug == "boissons" }, want: "boissons-2"},
+		{name: "client slug taken before the write", custom: "boissons", taken: func(string) bool { return true }, wantErr: domain.ErrSlug},
+		{name: "generated slugs always taken", taken: func(string) bool { return true }, wantErr: domain.ErrSlug},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeRepository()
+			repository.race = func(slug string) {
+				if tt.taken(slug) {
+					repository.slugs[slug] = other
+				}
+			}
+
+			category, err := testService(repository, newFakeStorage()).AddCategory(context.Background(), domain.CategoryParams{
+				Uuid: testCategoryUuid,
+				Name: "Boissons",
+				Slug: tt.custom,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && category.Slug != tt.want {
+				t.Errorf("slug = %q, want %q", category.Slug, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"sort"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// GetCategoryTree returns the categories nested under their parents, sorted
+// like the listing. A
+// category whose parent is in the trash is listed at the top level until the
+// parent is restored.
+func (c *Category) GetCategoryTree(ctx context.Context) (models.CategoryTree, error) {
+	categories, err := c.repository.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		nodes    = make(map[string]*models.CategoryNode, len(categories))
+		children = make(map[string][]*models.CategoryNode)
+		tree     = models.CategoryTree{}
+	)
+	for _, category := range categories {
+		nodes[category.Uuid] = &models.CategoryNode{Category: category, Children: []*models.CategoryNode{}}
+	}
+	for _, category := range categories {
+		if _, ok := nodes[category.ParentUuid]; ok {
+			children[category.ParentUuid] = append(children[category.ParentUuid], nodes[category.Uuid])
+		} else {
+			tree = append(tree, nodes[category.Uuid])
+		}
+	}
+
+	attached := make(map[string]bool, len(categories))
+	var attach func(nodes []*models.CategoryNode)
+	attach = func(nodes []*models.CategoryNode) {
+		sortNodes(nodes)
+		for _, node := range nodes {
+			attached[node.Uuid] = true
+			node.Children = append(node.Children, children[node.Uuid]...)
+			attach(node.Children)
+		}
+	}
+	attach(tree)
+
+	// categories moved below each other by concurrent updates form a cycle
+	// unreachable from the top level, they are listed there to stay visible
+	for _, category := range categories {
+		if !attached[category.Uuid] {
+			delete(children, category.ParentUuid)
+			tree = append(tree, nodes[category.Uuid])
+			attach(tree[len(tree)-1:])
+		}
+	}
+
+	return tree, nil
+}
+
+// checkParent makes sure the parent of a category is one of categories and is
+// not the category itself nor one of its descendants.
+func checkParent(categories models.Categories, uuid, parentUuid string) error {
+	if parentUuid == "" {
+		return nil
+	}
+
+	parents := make(map[string]string, len(categories))
+	for _, category := range categories {
+		parents[category.Uuid] = category.ParentUuid
+	}
+	if _, ok := parents[parentUuid]; !ok {
+		return domain.ErrCategory.Wrap(validation.Errors{"parent_uuid": errors.New("must reference an existing category")})
+	}
+
+	visited := make(map[string]bool)
+	for ancestor := parentUuid; ancestor != "" && !visited[ancestor]; ancestor = parents[ancestor] {
+		if ancestor == uuid {
+			return domain.ErrCycle.Wrap(validation.Errors{"parent_uuid": errors.New("must not be the category or one of its subcategories")})
+		}
+		visited[ancestor] = true
+	}
+	return nil
+}
+
+func sortNodes(nodes []*models.CategoryNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return before(nodes[i].Category, nodes[j].Category)
+	})
+}
+
+// before orders categories by position, then by name.
+func before(a, b *models.Category) bool {
+	if a.Position != b.Position {
+		return a.Position < b.Position
+	}
+	return a.Name < b.Name
+}
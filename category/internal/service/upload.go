@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/pkg/imagex"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// uploadedImage returns image unless upload references a presigned upload, in
+// which case the uploaded object is read and put through the same checks as
+// an image sent in the body before it can be attached.
+func (c *Category) uploadedImage(ctx context.Context, image []byte, upload string) ([]byte, error) {
+	if upload == "" {
+		return image, nil
+	}
+
+	image, err := c.storage.GetUpload(ctx, upload, c.limits.MaxBytes)
+	if errors.Is(err, domain.ErrUpload) {
+		return nil, domain.ErrUpload.Wrap(validation.Errors{"upload_uuid": errors.New("must reference an uploaded image")})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.limits.Validate(image); err != nil {
+		errs := validation.Errors{"upload_uuid": err}
+		if imagex.TooLarge(err) {
+			return nil, domain.ErrImageTooLarge.Wrap(errs)
+		}
+		return nil, domain.ErrImage.Wrap(errs)
+	}
+
+	return image, nil
+}
+
+// discardUpload deletes an upload once its image is attached, a failure only
+// leaves the object for the bucket lifecycle rule to expire.
+func (c *Category) discardUpload(ctx context.Context, upload string) {
+	if upload == "" {
+		return
+	}
+	if err := c.storage.DeleteUpload(ctx, upload); err != nil {
+		c.logger.Error("error deleting upload", "upload", upload, "error", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Package imagex decodes uploaded images and renders them into resized
+// copies without any metadata. It doesn't depend on the storage so it can be
+// exercised with in-memory images.
+package imagex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"sort"
+	"strconv"
+	"strings"
+
+	// decoders for the accepted upload formats
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/disintegration/imaging"
+)
+
+var ErrWidths = errors.New("widths must be a comma separated list of positive integers")
+
+// DefaultWidths are rendered when no widths are configured.
+var DefaultWidths = []int{150, 600, 1200}
+
+// jpegQuality trades size for fidelity on the JPEG renditions.
+const jpegQuality = 85
+
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatWebP Format = "webp"
+)
+
+// Formats lists the formats every rendition is encoded in.
+var Formats = []Format{FormatWebP, FormatJPEG}
+
+func (f Format) ContentType() string {
+	return "image/" + string(f)
+}
+
+func (f Format) Extension() string {
+	if f == FormatJPEG {
+		return "jpg"
+	}
+	return string(f)
+}
+
+type Rendition struct {
+	Width  int
+	Height int
+	Format Format
+	Data   []byte
+}
+
+type Processor struct {
+	widths []int
+}
+
+// NewProcessor returns a processor rendering the given widths, or the
+// default ones when none are given.
+func NewProcessor(widths []int) *Processor {
+	if len(widths) == 0 {
+		widths = DefaultWidths
+	}
+	return &Processor{widths: widths}
+}
+
+// ParseWidths parses a comma separated list of widths such as "150,600,1200".
+func ParseWidths(s string) ([]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var widths []int
+	for _, field := range strings.Split(s, ",") {
+		width, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || width <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrWidths, s)
+		}
+		widths = append(widths, width)
+	}
+	sort.Ints(widths)
+	return widths, nil
+}
+
+// Process decodes data, applies its EXIF orientation and renders every
+// configured width in every format. Images are never upscaled, widths larger
+// than the image collapse into a single rendition at its own width. Encoding
+// from the decoded pixels drops EXIF and any other metadata.
+func (p *Processor) Process(data []byte) ([]Rendition, error) {
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding image: %w", err)
+	}
+
+	var (
+		renditions []Rendition
+		rendered   = map[int]bool{}
+		bounds     = img.Bounds()
+	)
+	for _, width := range p.widths {
+		if width > bounds.Dx() {
+			width = bounds.Dx()
+		}
+		if rendered[width] {
+			continue
+		}
+		rendered[width] = true
+
+		resized := img
+		if width != bounds.Dx() {
+			resized = imaging.Resize(img, width, 0, imaging.Lanczos)
+		}
+
+		for _, format := range Formats {
+			encoded, err := Encode(resized, format)
+			if err != nil {
+				return nil, err
+			}
+			renditions = append(renditions, Rendition{
+				Width:  width,
+				Height: resized.Bounds().Dy(),
+				Format: format,
+				Data:   encoded,
+			})
+		}
+	}
+
+	return renditions, nil
+}
+
+// Encode encodes img in the given format, JPEG has no transparency so the
+// image is flattened over a white background first.
+func Encode(img image.Image, format Format) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case FormatJPEG:
+		flat := imaging.New(img.Bounds().Dx(), img.Bounds().Dy(), color.White)
+		flat = imaging.Overlay(flat, img, image.Pt(0, 0), 1)
+		if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("error encoding jpeg: %w", err)
+		}
+	case FormatWebP:
+		if err := nativewebp.Encode(&buf, img, nil); err != nil {
+			return nil, fmt.Errorf("error encoding webp: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported image format %q", format)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,205 @@
+package imagex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+// picture returns a width x height image, opaque red unless transparent.
+func picture(width, height int, transparent bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	fill := color.NRGBA{R: 255, A: 255}
+	if transparent {
+		fill = color.NRGBA{}
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, fill)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeJPEG encodes img with an EXIF segment holding orientation, 0 for
+// none. Orientation 6 asks viewers to rotate the image 90 degrees clockwise.
+func encodeJPEG(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if orientation == 0 {
+		return data
+	}
+
+	// a big endian TIFF header and a single IFD entry, tag 0x0112 SHORT
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00\x2a")
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	segment := []byte{0xff, 0xe1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	// the segment goes right after the start of image marker
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestParseWidths(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []int
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "1200, 150,600", want: []int{150, 600, 1200}},
+		{value: "150,abc", wantErr: true},
+		{value: "150,0", wantErr: true},
+		{value: "-5", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseWidths(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseWidths(%q) error = %v, want error %v", test.value, err, test.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseWidths(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestProcess(t *testing.T) {
+	type size struct {
+		width, height int
+		format        Format
+	}
+
+	tests := []struct {
+		name   string
+		widths []int
+		data   func(t *testing.T) []byte
+		want   []size
+	}{
+		{
+			name:   "png resized to every width in both formats",
+			widths: []int{100, 200},
+			data:   func(t *testing.T) []byte { return encodePNG(t, picture(400, 200, false)) },
+			want: []size{
+				{100, 50, FormatWebP}, {100, 50, FormatJPEG},
+				{200, 100, FormatWebP}, {200, 100, FormatJPEG},
+			},
+		},
+		{
+			name:   "widths larger than the image collapse into its own width",
+			widths: []int{150, 600, 1200},
+			data:   func(t *testing.T) []byte { return encodePNG(t, picture(400, 200, false)) },
+			want: []size{
+				{150, 75, FormatWebP}, {150, 75, FormatJPEG},
+				{400, 200, FormatWebP}, {400, 200, FormatJPEG},
+			},
+		},
+		{
+			name:   "jpeg rotated by its exif orientation",
+			widths: []int{1200},
+			data:   func(t *testing.T) []byte { return encodeJPEG(t, picture(40, 20, false), 6) },
+			want:   []size{{20, 40, FormatWebP}, {20, 40, FormatJPEG}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			renditions, err := NewProcessor(test.widths).Process(test.data(t))
+			if err != nil {
+				t.Fatalf("Process: %v", err)
+			}
+
+			got := make([]size, len(renditions))
+			for i, rendition := range renditions {
+				got[i] = size{rendition.Width, rendition.Height, rendition.Format}
+
+				config, format, err := image.DecodeConfig(bytes.NewReader(rendition.Data))
+				if err != nil {
+					t.Fatalf("rendition %d doesn't decode: %v", i, err)
+				}
+				if format != string(rendition.Format) {
+					t.Errorf("rendition %d is encoded as %s, want %s", i, format, rendition.Format)
+				}
+				if config.Width != rendition.Width || config.Height != rendition.Height {
+					t.Errorf("rendition %d is %dx%d, want %dx%d", i, config.Width, config.Height, rendition.Width, rendition.Height)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("renditions = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestProcessStripsMetadata(t *testing.T) {
+	data := encodeJPEG(t, picture(40, 20, false), 6)
+	if !bytes.Contains(data, []byte("Exif")) {
+		t.Fatal("test image has no exif segment")
+	}
+
+	renditions, err := NewProcessor(nil).Process(data)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	for _, rendition := range renditions {
+		if bytes.Contains(rendition.Data, []byte("Exif")) {
+			t.Errorf("%s rendition keeps the exif segment", rendition.Format)
+		}
+	}
+}
+
+func TestProcessRejectsGarbage(t *testing.T) {
+	if _, err := NewProcessor(nil).Process([]byte("not an image")); err == nil {
+		t.Error("Process accepted data that isn't an image")
+	}
+}
+
+func TestEncodeFlattensJPEG(t *testing.T) {
+	data, err := Encode(picture(8, 8, true), FormatJPEG)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	r, g, b, _ := img.At(4, 4).RGBA()
+	if r>>8 < 250 || g>>8 < 250 || b>>8 < 250 {
+		t.Errorf("transparent pixel encoded as %d,%d,%d, want white", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestEncodeUnsupportedFormat(t *testing.T) {
+	if _, err := Encode(picture(8, 8, false), Format("bmp")); err == nil {
+		t.Error("Encode accepted an unsupported format")
+	}
+}
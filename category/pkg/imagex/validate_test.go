@@ -0,0 +1,70 @@
+package imagex
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		data   []byte
+		format string
+		ok     bool
+	}{
+		{[]byte("\x89PNG\r\n\x1a\n...."), "png", true},
+		{[]byte("\xff\xd8\xff\xe0"), "jpeg", true},
+		{[]byte("GIF89a...."), "gif", true},
+		{[]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), "webp", true},
+		{[]byte("RIFF\x00\x00\x00\x00WAVE"), "", false},
+		{[]byte("<svg></svg>"), "", false},
+	}
+
+	for _, test := range tests {
+		format, ok := Sniff(test.data)
+		if format != test.format || ok != test.ok {
+			t.Errorf("Sniff(%q) = %q, %v, want %q, %v", test.data, format, ok, test.format, test.ok)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var (
+		limits = Limits{MaxBytes: DefaultMaxBytes, MaxDimension: 100}
+		valid  = encodePNG(t, picture(100, 50, false))
+	)
+
+	tests := []struct {
+		name   string
+		limits Limits
+		data   []byte
+		want   error
+	}{
+		{name: "valid", limits: limits, data: valid},
+		{name: "too many bytes", limits: Limits{MaxBytes: len(valid) - 1, MaxDimension: 100}, data: valid, want: ErrSize},
+		{name: "too wide", limits: limits, data: encodePNG(t, picture(101, 10, false)), want: ErrDimensions},
+		{name: "unknown type", limits: limits, data: []byte("<svg></svg>"), want: ErrType},
+		{name: "truncated", limits: limits, data: valid[:20], want: ErrInvalid},
+		{name: "jpeg sent with a png signature", limits: limits, data: append([]byte("\x89PNG\r\n\x1a\n"), encodeJPEG(t, picture(10, 10, false), 0)...), want: ErrInvalid},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.limits.Validate(test.data)
+			if !errors.Is(err, test.want) || (test.want == nil) != (err == nil) {
+				t.Errorf("Validate() = %v, want %v", err, test.want)
+			}
+			if TooLarge(err) != (test.want == ErrSize || test.want == ErrDimensions) {
+				t.Errorf("TooLarge(%v) = %v", err, TooLarge(err))
+			}
+		})
+	}
+}
+
+func TestNewLimits(t *testing.T) {
+	if got := NewLimits("", "abc"); got != (Limits{DefaultMaxBytes, DefaultMaxDimension}) {
+		t.Errorf("NewLimits with invalid values = %+v, want the defaults", got)
+	}
+	if got := NewLimits("1024", "512"); got != (Limits{1024, 512}) {
+		t.Errorf("NewLimits(1024, 512) = %+v", got)
+	}
+}
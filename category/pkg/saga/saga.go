@@ -0,0 +1,69 @@
+// Package saga runs writes spanning several stores as a sequence of steps,
+// undoing the completed steps in reverse order when a later one fails. It
+// only depends on the actions it is given so it can be exercised with fake
+// stores.
+package saga
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Action is a step of a saga or the compensation undoing it.
+type Action func(ctx context.Context) error
+
+// Saga records the compensations of the completed steps of a write.
+type Saga struct {
+	logger        *slog.Logger
+	compensations []compensation
+}
+
+type compensation struct {
+	step string
+	undo Action
+}
+
+func New(logger *slog.Logger) *Saga {
+	return &Saga{logger: logger}
+}
+
+// Do runs a step and records the compensation undoing it, which may be nil
+// when the step leaves nothing to undo. When the step fails the completed
+// steps are compensated and its error is returned unchanged, so callers keep
+// reporting the domain error of the step that failed.
+func (s *Saga) Do(ctx context.Context, step string, action, compensate Action) error {
+	if err := action(ctx); err != nil {
+		s.Compensate(ctx, step, err)
+		return err
+	}
+
+	if compensate != nil {
+		s.compensations = append(s.compensations, compensation{step: step, undo: compensate})
+	}
+	return nil
+}
+
+// Compensate undoes the completed steps in reverse order after step failed
+// with cause. The compensations still run when ctx is canceled, and their
+// failures are only logged since the caller reports cause and whatever is
+// left behind is reclaimed by the image collector.
+func (s *Saga) Compensate(ctx context.Context, step string, cause error) {
+	ctx = context.WithoutCancel(ctx)
+	for i := len(s.compensations) - 1; i >= 0; i-- {
+		c := s.compensations[i]
+		if err := c.undo(ctx); err != nil {
+			s.logger.Error("error compensating step", "step", c.step, "failed_step", step, "cause", cause, "error", err)
+			continue
+		}
+		s.logger.Info("step compensated", "step", c.step, "failed_step", step)
+	}
+	s.compensations = nil
+}
+
+// Finally runs a step once the write is committed. Its failure can neither
+// undo the write nor fail it, so it is only logged.
+func (s *Saga) Finally(ctx context.Context, step string, action Action) {
+	if err := action(ctx); err != nil {
+		s.logger.Error("error completing step", "step", step, "error", err)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"shopy/internal/dynamodb"
+)
+
+// Sets the product count of every category from the products outside the
+// trash, for categories stored before counts were kept.
+func main() {
+	dynamoClient, err := dynamodb.Connection()
+	if err != nil {
+		log.Fatalf("error connecting to dynamodb: %v", err)
+	}
+
+	var (
+		logger     = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+		repository = dynamodb.NewCategory(logger, dynamoClient)
+	)
+
+	counted, err := repository.CountProducts(context.Background())
+	if err != nil {
+		log.Fatalf("error counting products after %d categories: %v", counted, err)
+	}
+
+	logger.Info("product count finished", "categories", counted)
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigateway"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsdynamodb"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsevents"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awseventstargets"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
 
@@ -13,8 +15,11 @@ import (
 
 type ProductStackProps struct {
 	awscdk.StackProps
-	s3bucket awss3.Bucket
-	version  awsapigateway.Resource
+	s3bucket      awss3.Bucket
+	imageTable    awsdynamodb.Table
+	categoryTable awsdynamodb.Table
+	slugTable     awsdynamodb.Table
+	version       awsapigateway.Resource
 }
 
 func NewProductStack(stack constructs.Construct, props *ProductStackProps) {
@@ -45,26 +50,203 @@ func NewProductStack(stack constructs.Construct, props *ProductStackProps) {
 		ProjectionType: awsdynamodb.ProjectionType_ALL,
 	})
 
+	variantTable := awsdynamodb.NewTable(stack, jsii.String("ProductVariantDynamodb"), &awsdynamodb.TableProps{
+		TableName:     jsii.String("product_variant"),
+		RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("product_uuid"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		SortKey: &awsdynamodb.Attribute{
+			Name: jsii.String("uuid"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+	})
+
+	variantTable.AddGlobalSecondaryIndex(&awsdynamodb.GlobalSecondaryIndexProps{
+		IndexName: jsii.String("GSI_QRCODE"),
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("qrcode"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		ProjectionType: awsdynamodb.ProjectionType_ALL,
+	})
+
+	ledgerTable := awsdynamodb.NewTable(stack, jsii.String("InventoryLedgerDynamodb"), &awsdynamodb.TableProps{
+		TableName:     jsii.String("inventory_ledger"),
+		RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("product_uuid"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		SortKey: &awsdynamodb.Attribute{
+			Name: jsii.String("uuid"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+	})
+
+	locationTable := awsdynamodb.NewTable(stack, jsii.String("LocationDynamodb"), &awsdynamodb.TableProps{
+		TableName:     jsii.String("location"),
+		RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("uuid"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+	})
+
+	stockTable := awsdynamodb.NewTable(stack, jsii.String("LocationStockDynamodb"), &awsdynamodb.TableProps{
+		TableName:     jsii.String("location_stock"),
+		RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("product_uuid"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		SortKey: &awsdynamodb.Attribute{
+			Name: jsii.String("stock_key"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+	})
+
+	// locks each QR code to the single product holding it
+	qrcodeTable := awsdynamodb.NewTable(stack, jsii.String("ProductQRCodeDynamodb"), &awsdynamodb.TableProps{
+		TableName:     jsii.String("product_qrcode"),
+		RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("qrcode"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+	})
+
 	lambdaFunc := awslambda.NewFunction(stack, jsii.String("ProductLambda"), &awslambda.FunctionProps{
 		FunctionName: jsii.String("manage-product"),
 		Code:         awslambda.AssetCode_FromAsset(jsii.String("./product/assets/lambda.zip"), nil),
 		Runtime:      awslambda.Runtime_PROVIDED_AL2023(),
 		Architecture: awslambda.Architecture_ARM_64(),
-		MemorySize:   jsii.Number(128),
+		MemorySize:   jsii.Number(512),
 		Handler:      jsii.String("bootstrap"),
 		Timeout:      awscdk.Duration_Seconds(jsii.Number(30)),
 		Environment: &map[string]*string{
-			"BUCKET_NAME": props.s3bucket.BucketName(),
+			"BUCKET_NAME":      props.s3bucket.BucketName(),
+			"DEFAULT_CURRENCY": jsii.String("MXN"),
+			"IMAGE_WIDTHS":     jsii.String("150,600,1200"),
+			"TOKEN_KEY":        jsii.String("secret"),
 		},
 	})
 
 	table.GrantReadWriteData(lambdaFunc)
+	variantTable.GrantReadWriteData(lambdaFunc)
+	ledgerTable.GrantReadWriteData(lambdaFunc)
+	locationTable.GrantReadWriteData(lambdaFunc)
+	stockTable.GrantReadWriteData(lambdaFunc)
+	qrcodeTable.GrantReadWriteData(lambdaFunc)
+	props.categoryTable.GrantReadWriteData(lambdaFunc)
+	props.imageTable.GrantReadWriteData(lambdaFunc)
+	props.slugTable.GrantReadWriteData(lambdaFunc)
 	props.s3bucket.GrantReadWrite(lambdaFunc, nil)
 
+	purgeFunc := awslambda.NewFunction(stack, jsii.String("ProductPurgeLambda"), &awslambda.FunctionProps{
+		FunctionName: jsii.String("purge-product"),
+		Code:         awslambda.AssetCode_FromAsset(jsii.String("./product/assets/purge.zip"), nil),
+		Runtime:      awslambda.Runtime_PROVIDED_AL2023(),
+		Architecture: awslambda.Architecture_ARM_64(),
+		MemorySize:   jsii.Number(128),
+		Handler:      jsii.String("bootstrap"),
+		Timeout:      awscdk.Duration_Minutes(jsii.Number(5)),
+		Environment: &map[string]*string{
+			"BUCKET_NAME":    props.s3bucket.BucketName(),
+			"RETENTION_DAYS": jsii.String("30"),
+		},
+	})
+
+	table.GrantReadWriteData(purgeFunc)
+	variantTable.GrantReadWriteData(purgeFunc)
+	qrcodeTable.GrantReadWriteData(purgeFunc)
+	props.imageTable.GrantReadWriteData(purgeFunc)
+	props.slugTable.GrantReadWriteData(purgeFunc)
+	props.s3bucket.GrantReadWrite(purgeFunc, nil)
+
+	awsevents.NewRule(stack, jsii.String("ProductPurgeSchedule"), &awsevents.RuleProps{
+		Schedule: awsevents.Schedule_Cron(&awsevents.CronOptions{
+			Minute: jsii.String("0"),
+			Hour:   jsii.String("3"),
+		}),
+		Targets: &[]awsevents.IRuleTarget{
+			awseventstargets.NewLambdaFunction(purgeFunc, nil),
+		},
+	})
+
+	gcFunc := awslambda.NewFunction(stack, jsii.String("ProductGcLambda"), &awslambda.FunctionProps{
+		FunctionName: jsii.String("collect-images"),
+		Code:         awslambda.AssetCode_FromAsset(jsii.String("./product/assets/gc.zip"), nil),
+		Runtime:      awslambda.Runtime_PROVIDED_AL2023(),
+		Architecture: awslambda.Architecture_ARM_64(),
+		MemorySize:   jsii.Number(256),
+		Handler:      jsii.String("bootstrap"),
+		Timeout:      awscdk.Duration_Minutes(jsii.Number(15)),
+		Environment: &map[string]*string{
+			"BUCKET_NAME": props.s3bucket.BucketName(),
+			"GRACE_HOURS": jsii.String("24"),
+			"DRY_RUN":     jsii.String("false"),
+		},
+	})
+
+	table.GrantReadData(gcFunc)
+	variantTable.GrantReadData(gcFunc)
+	props.categoryTable.GrantReadData(gcFunc)
+	props.imageTable.GrantReadWriteData(gcFunc)
+	props.s3bucket.GrantReadWrite(gcFunc, nil)
+
+	awsevents.NewRule(stack, jsii.String("ProductGcSchedule"), &awsevents.RuleProps{
+		Schedule: awsevents.Schedule_Cron(&awsevents.CronOptions{
+			Minute: jsii.String("0"),
+			Hour:   jsii.String("4"),
+		}),
+		Targets: &[]awsevents.IRuleTarget{
+			awseventstargets.NewLambdaFunction(gcFunc, nil),
+		},
+	})
+
+	publishFunc := awslambda.NewFunction(stack, jsii.String("ProductPublishLambda"), &awslambda.FunctionProps{
+		FunctionName: jsii.String("publish-product"),
+		Code:         awslambda.AssetCode_FromAsset(jsii.String("./product/assets/publish.zip"), nil),
+		Runtime:      awslambda.Runtime_PROVIDED_AL2023(),
+		Architecture: awslambda.Architecture_ARM_64(),
+		MemorySize:   jsii.Number(128),
+		Handler:      jsii.String("bootstrap"),
+		Timeout:      awscdk.Duration_Minutes(jsii.Number(1)),
+	})
+
+	table.GrantReadWriteData(publishFunc)
+
+	awsevents.NewRule(stack, jsii.String("ProductPublishSchedule"), &awsevents.RuleProps{
+		Schedule: awsevents.Schedule_Rate(awscdk.Duration_Minutes(jsii.Number(5))),
+		Targets: &[]awsevents.IRuleTarget{
+			awseventstargets.NewLambdaFunction(publishFunc, nil),
+		},
+	})
+
 	var (
-		products     = props.version.AddResource(jsii.String("products"), nil)
-		productsUuid = products.ResourceForPath(jsii.String("{uuid}"))
-		options      = &awsapigateway.LambdaIntegrationOptions{
+		products        = props.version.AddResource(jsii.String("products"), nil)
+		productsUuid    = products.ResourceForPath(jsii.String("{uuid}"))
+		productsTrash   = products.AddResource(jsii.String("trash"), nil)
+		productsSlug    = products.AddResource(jsii.String("by-slug"), nil).AddResource(jsii.String("{slug}"), nil)
+		productsQRCode  = products.AddResource(jsii.String("qrcode"), nil).AddResource(jsii.String("{code}"), nil)
+		productsLabels  = products.AddResource(jsii.String("labels"), nil)
+		productsBatch   = products.AddResource(jsii.String("batch-get"), nil)
+		productsQRImage = productsUuid.AddResource(jsii.String("qrcode.png"), nil)
+		productsRestore = productsUuid.AddResource(jsii.String("restore"), nil)
+		productsStatus  = productsUuid.AddResource(jsii.String("status"), nil)
+		variants        = productsUuid.AddResource(jsii.String("variants"), nil)
+		variantsUuid    = variants.ResourceForPath(jsii.String("{variant_uuid}"))
+		images          = productsUuid.AddResource(jsii.String("images"), nil)
+		imagesUuid      = images.ResourceForPath(jsii.String("{image_uuid}"))
+		stock           = productsUuid.AddResource(jsii.String("stock"), nil)
+		transfers       = productsUuid.AddResource(jsii.String("transfers"), nil)
+		availability    = productsUuid.AddResource(jsii.String("availability"), nil)
+		locations       = props.version.AddResource(jsii.String("locations"), nil)
+		locationsUuid   = locations.ResourceForPath(jsii.String("{uuid}"))
+		uploads         = props.version.AddResource(jsii.String("uploads"), nil)
+		options         = &awsapigateway.LambdaIntegrationOptions{
 			AllowTestInvoke: jsii.Bool(false),
 		}
 	)
@@ -72,5 +254,30 @@ func NewProductStack(stack constructs.Construct, props *ProductStackProps) {
 	products.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
 	products.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
 	productsUuid.AddMethod(jsii.String("PUT"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	productsUuid.AddMethod(jsii.String("PATCH"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
 	productsUuid.AddMethod(jsii.String("DELETE"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	productsSlug.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	productsQRCode.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	productsLabels.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	productsBatch.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	productsQRImage.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	productsTrash.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	productsRestore.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	productsStatus.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	variants.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	variants.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	variantsUuid.AddMethod(jsii.String("PUT"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	variantsUuid.AddMethod(jsii.String("DELETE"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	images.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	images.AddMethod(jsii.String("PUT"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	imagesUuid.AddMethod(jsii.String("PATCH"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	imagesUuid.AddMethod(jsii.String("DELETE"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	stock.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	stock.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	transfers.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	availability.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	locations.AddMethod(jsii.String("GET"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	locations.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	locationsUuid.AddMethod(jsii.String("PUT"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
+	uploads.AddMethod(jsii.String("POST"), awsapigateway.NewLambdaIntegration(lambdaFunc, options), nil)
 }
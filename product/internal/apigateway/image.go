@@ -0,0 +1,198 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"shopy/internal/domain"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// @Summary 	Add product image.
+// @Description Add an image at the end of the product gallery. The image is sent base64 encoded or referenced by upload_uuid. The first image, or one sent with primary set, becomes the primary image.
+// @Tags 		Images
+// @Router 		/products/{uuid}/images [post]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param	    params body  ImageRequest true "Image"
+// @Success     201	{object} ProductAdded "Success"
+// @Header      201	{string} ETag "Product version"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     413	{object} ErrorResponse "Request Entity Too Large"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleAddImage(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var (
+		err     error
+		image   []byte
+		request ImageRequest
+	)
+
+	if err = json.Unmarshal([]byte(event.Body), &request); err != nil {
+		p.logger.Error("invalid image body", "error", err)
+		return Error(domain.ErrRequest)
+	}
+
+	if err = request.Validate(); err != nil {
+		p.logger.Error("invalid image params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	if request.Image != "" {
+		image, err = p.decodeImage(request.Image)
+		if err != nil {
+			return Error(err)
+		}
+	}
+
+	product, err := p.service.AddImage(ctx, domain.ImageParams{
+		ProductUuid: event.PathParameters["uuid"],
+		Uuid:        uuid.New().String(),
+		Image:       image,
+		Upload:      request.UploadUuid,
+		Alt:         request.Alt,
+		Primary:     request.Primary,
+		UpdatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		p.logger.Error("error adding image", "error", err)
+		return Error(err)
+	}
+
+	var response = ProductAdded{
+		BaseResponse: NewBaseResponse(http.StatusCreated),
+		Product:      product,
+	}
+
+	return JSONWithETag(response, http.StatusCreated, product.Version)
+}
+
+// @Summary 	Reorder product images.
+// @Description Reorder the product gallery, the request lists the UUID of every image once in the new order.
+// @Tags 		Images
+// @Router 		/products/{uuid}/images [put]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param	    params body  ImageOrderRequest true "Image order"
+// @Success     200	{object} ProductAdded "Success"
+// @Header      200	{string} ETag "Product version"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleReorderImages(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var request ImageOrderRequest
+
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		p.logger.Error("invalid image order body", "error", err)
+		return Error(domain.ErrRequest)
+	}
+
+	if err := request.Validate(); err != nil {
+		p.logger.Error("invalid image order params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	product, err := p.service.ReorderImages(ctx, domain.ImageOrder{
+		ProductUuid: event.PathParameters["uuid"],
+		Uuids:       request.Images,
+		UpdatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		p.logger.Error("error reordering images", "error", err)
+		return Error(err)
+	}
+
+	var response = ProductAdded{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Product:      product,
+	}
+
+	return JSONWithETag(response, http.StatusOK, product.Version)
+}
+
+// @Summary 	Update product image.
+// @Description Change the alt text of an image or make it the primary image.
+// @Tags 		Images
+// @Router 		/products/{uuid}/images/{image_uuid} [patch]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param       image_uuid path string true "Image UUID"
+// @Param	    params body  ImagePatchRequest true "Image"
+// @Success     200	{object} ProductAdded "Success"
+// @Header      200	{string} ETag "Product version"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandlePatchImage(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var request ImagePatchRequest
+
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		p.logger.Error("invalid image body", "error", err)
+		return Error(domain.ErrRequest)
+	}
+
+	if err := request.Validate(); err != nil {
+		p.logger.Error("invalid image params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	product, err := p.service.PatchImage(ctx, domain.ImagePatch{
+		ProductUuid: event.PathParameters["uuid"],
+		Uuid:        event.PathParameters["image_uuid"],
+		Alt:         request.Alt,
+		Primary:     request.Primary,
+		UpdatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		p.logger.Error("error updating image", "error", err)
+		return Error(err)
+	}
+
+	var response = ProductAdded{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Product:      product,
+	}
+
+	return JSONWithETag(response, http.StatusOK, product.Version)
+}
+
+// @Summary 	Delete product image.
+// @Description Remove an image from the gallery and delete it, the first remaining image is promoted when the primary image is deleted.
+// @Tags 		Images
+// @Router 		/products/{uuid}/images/{image_uuid} [delete]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param       image_uuid path string true "Image UUID"
+// @Success     200	{object} ProductAdded "Success"
+// @Header      200	{string} ETag "Product version"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleDelImage(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	product, err := p.service.DelImage(ctx, event.PathParameters["uuid"], event.PathParameters["image_uuid"])
+	if err != nil {
+		p.logger.Error("error deleting image", "error", err)
+		return Error(err)
+	}
+
+	var response = ProductAdded{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Product:      product,
+	}
+
+	return JSONWithETag(response, http.StatusOK, product.Version)
+}
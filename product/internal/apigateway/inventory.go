@@ -0,0 +1,206 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"shopy/internal/domain"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultLedgerLimit = 50
+	maxLedgerLimit     = 100
+)
+
+// @Summary 	Get product stock.
+// @Description Get the stock of a product and its variants together with the latest inventory ledger entries, newest first.
+// @Tags 		Inventory
+// @Router 		/products/{uuid}/stock [get]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param       limit query int false "Ledger entries, 50 by default and 100 at most"
+// @Success     200	{object} SelectedStock "Success"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleGetStock(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	limit := int64(defaultLedgerLimit)
+	if value := event.QueryStringParameters["limit"]; value != "" {
+		var err error
+		limit, err = strconv.ParseInt(value, 10, 32)
+		if err != nil || limit < 1 || limit > maxLedgerLimit {
+			p.logger.Error("invalid ledger limit", "limit", value)
+			return Error(domain.ErrParams)
+		}
+	}
+
+	stock, err := p.service.GetStock(ctx, event.PathParameters["uuid"], int32(limit))
+	if err != nil {
+		p.logger.Error("error getting stock", "error", err)
+		return Error(err)
+	}
+
+	var response = SelectedStock{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Stock:        stock,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
+// @Summary 	Adjust product stock.
+// @Description Receive, sell or correct the stock of a product, or of one of its variants when variant_uuid is sent, and of the stock held at location_uuid when it is sent. Receive and sell take a positive quantity, corrections a signed one. Every adjustment is recorded in the inventory ledger.
+// @Tags 		Inventory
+// @Router 		/products/{uuid}/stock [post]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param	    params body  StockRequest true "Adjustment"
+// @Success     201	{object} StockAdjusted "Success"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     409	{object} ErrorResponse "Conflict"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleAdjustStock(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var request StockRequest
+
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		p.logger.Error("invalid stock body", "error", err)
+		return Error(domain.ErrRequest)
+	}
+
+	if err := request.Validate(); err != nil {
+		p.logger.Error("invalid stock params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	// version 7 UUIDs sort by time, keeping the ledger in order
+	id, err := uuid.NewV7()
+	if err != nil {
+		p.logger.Error("error generating ledger uuid", "error", err)
+		return Error(err)
+	}
+
+	entry, err := p.service.AdjustStock(ctx, domain.StockParams{
+		Uuid:        id.String(),
+		ProductUuid: event.PathParameters["uuid"],
+		VariantUuid: request.VariantUuid,
+		Location:    request.LocationUuid,
+		Adjustment:  domain.Adjustment(request.Adjustment),
+		Quantity:    request.Quantity,
+		Reason:      request.Reason,
+		Note:        request.Note,
+		CreatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		p.logger.Error("error adjusting stock", "error", err)
+		return Error(err)
+	}
+
+	var response = StockAdjusted{
+		BaseResponse: NewBaseResponse(http.StatusCreated),
+		Entry:        entry,
+	}
+
+	return JSON(response, http.StatusCreated)
+}
+
+// @Summary 	Transfer product stock.
+// @Description Move stock of a product, or of one of its variants, between two locations. The transfer is recorded as a pair of ledger entries sharing the transfer UUID.
+// @Tags 		Inventory
+// @Router 		/products/{uuid}/transfers [post]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param	    params body  TransferRequest true "Transfer"
+// @Success     201	{object} TransferAdded "Success"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     409	{object} ErrorResponse "Conflict"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleTransferStock(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var request TransferRequest
+
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		p.logger.Error("invalid transfer body", "error", err)
+		return Error(domain.ErrRequest)
+	}
+
+	if err := request.Validate(); err != nil {
+		p.logger.Error("invalid transfer params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	var ids [2]string
+	for i := range ids {
+		id, err := uuid.NewV7()
+		if err != nil {
+			p.logger.Error("error generating ledger uuid", "error", err)
+			return Error(err)
+		}
+		ids[i] = id.String()
+	}
+
+	transfer, err := p.service.TransferStock(ctx, domain.TransferParams{
+		Uuid:        uuid.New().String(),
+		OutUuid:     ids[0],
+		InUuid:      ids[1],
+		ProductUuid: event.PathParameters["uuid"],
+		VariantUuid: request.VariantUuid,
+		From:        request.FromLocationUuid,
+		To:          request.ToLocationUuid,
+		Quantity:    request.Quantity,
+		Note:        request.Note,
+		CreatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		p.logger.Error("error transferring stock", "error", err)
+		return Error(err)
+	}
+
+	var response = TransferAdded{
+		BaseResponse: NewBaseResponse(http.StatusCreated),
+		Transfer:     transfer,
+	}
+
+	return JSON(response, http.StatusCreated)
+}
+
+// @Summary 	Get product availability.
+// @Description Get the stock of a product and its variants by location. Stock adjusted without a location is reported as unallocated.
+// @Tags 		Inventory
+// @Router 		/products/{uuid}/availability [get]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Success     200	{object} SelectedAvailability "Success"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleGetAvailability(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	availability, err := p.service.GetAvailability(ctx, event.PathParameters["uuid"])
+	if err != nil {
+		p.logger.Error("error getting availability", "error", err)
+		return Error(err)
+	}
+
+	var response = SelectedAvailability{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Availability: availability,
+	}
+
+	return JSON(response, http.StatusOK)
+}
@@ -0,0 +1,97 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"shopy/internal/domain"
+	"shopy/pkg/label"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// @Summary 	Get product QR code image.
+// @Description Render the QR code of a product as a square PNG. The image is cached in the bucket and the response redirects to it, a changed code renders a new image. Only published products are rendered unless the request carries a staff token.
+// @Tags 		Products
+// @Router 		/products/{uuid}/qrcode.png [get]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param       size query int false "Width and height in pixels, 256 by default, from 64 to 2048"
+// @Param       level query string false "Error correction level, M by default" Enums(L, M, Q, H)
+// @Success     302	{object} Redirected "Found"
+// @Header      302	{string} Location "PNG image URL"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleGetQRCodeImage(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var (
+		params = domain.QRCodeParams{
+			ProductUuid: event.PathParameters["uuid"],
+			Size:        label.DefaultSize,
+			Staff:       p.staff(event),
+		}
+		errs = validation.Errors{}
+		err  error
+	)
+
+	if value := event.QueryStringParameters["size"]; value != "" {
+		params.Size, err = strconv.Atoi(value)
+		if err != nil || params.Size < label.MinSize || params.Size > label.MaxSize {
+			errs["size"] = label.ErrSize
+		}
+	}
+	if params.Level, err = label.ParseLevel(event.QueryStringParameters["level"]); err != nil {
+		errs["level"] = err
+	}
+	if len(errs) > 0 {
+		p.logger.Error("invalid qrcode image params", "error", errs)
+		return Error(domain.ErrParams.Wrap(errs))
+	}
+
+	location, err := p.service.GetQRCodeImage(ctx, params)
+	if err != nil {
+		p.logger.Error("error getting qrcode image", "uuid", params.ProductUuid, "error", err)
+		return Error(err)
+	}
+
+	return Found(location)
+}
+
+// @Summary 	Print product labels.
+// @Description Print the shelf label of each product, with its name, price and QR code, on an A4 PDF sheet of 3 by 8 labels in the order of uuids. The sheet is cached in the bucket and the response redirects to it, changed products print a new sheet. Only published products are printed unless the request carries a staff token, the others are rejected like missing ones.
+// @Tags 		Products
+// @Router 		/products/labels [post]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param	    params body  LabelsRequest true "Products"
+// @Success     303	{object} Redirected "See Other"
+// @Header      303	{string} Location "PDF sheet URL"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleGetLabels(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var request LabelsRequest
+
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		p.logger.Error("invalid labels body", "error", err)
+		return Error(domain.ErrRequest)
+	}
+
+	if err := request.Validate(); err != nil {
+		p.logger.Error("invalid labels params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	location, err := p.service.GetLabels(ctx, request.Uuids, p.staff(event))
+	if err != nil {
+		p.logger.Error("error getting labels", "error", err)
+		return Error(err)
+	}
+
+	return SeeOther(location)
+}
@@ -0,0 +1,133 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"shopy/internal/domain"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// @Summary 	Get locations.
+// @Description Get the stores and warehouses that hold stock.
+// @Tags 		Locations
+// @Router 		/locations [get]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Success     200	{object} SelectedLocations "Success"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleGetLocations(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	locations, err := p.service.GetLocations(ctx)
+	if err != nil {
+		p.logger.Error("error getting locations", "error", err)
+		return Error(err)
+	}
+
+	var response = SelectedLocations{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Locations:    locations,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
+// @Summary 	Add location.
+// @Description Add a store or warehouse, pickup marks the locations with a click-and-collect counter.
+// @Tags 		Locations
+// @Router 		/locations [post]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param	    params body  LocationRequest true "Location"
+// @Success     201	{object} LocationAdded "Success"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleAddLocation(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	params, err := p.locationParams(event)
+	if err != nil {
+		return Error(err)
+	}
+
+	now := time.Now().UTC()
+	params.Uuid = uuid.New().String()
+	params.CreatedAt = now
+	params.UpdatedAt = now
+
+	location, err := p.service.AddLocation(ctx, params)
+	if err != nil {
+		p.logger.Error("error adding location", "error", err)
+		return Error(err)
+	}
+
+	var response = LocationAdded{
+		BaseResponse: NewBaseResponse(http.StatusCreated),
+		Location:     location,
+	}
+
+	return JSON(response, http.StatusCreated)
+}
+
+// @Summary 	Update location.
+// @Description Update a store or warehouse.
+// @Tags 		Locations
+// @Router 		/locations/{uuid} [put]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Location UUID"
+// @Param	    params body  LocationRequest true "Location"
+// @Success     200	{object} LocationAdded "Success"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandlePutLocation(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	params, err := p.locationParams(event)
+	if err != nil {
+		return Error(err)
+	}
+
+	params.Uuid = event.PathParameters["uuid"]
+	params.UpdatedAt = time.Now().UTC()
+
+	location, err := p.service.PutLocation(ctx, params)
+	if err != nil {
+		p.logger.Error("error updating location", "error", err)
+		return Error(err)
+	}
+
+	var response = LocationAdded{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Location:     location,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
+// locationParams decodes and validates the location sent in the request body.
+func (p *Product) locationParams(event events.APIGatewayProxyRequest) (domain.LocationParams, error) {
+	var request LocationRequest
+
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		p.logger.Error("invalid location body", "error", err)
+		return domain.LocationParams{}, domain.ErrRequest
+	}
+
+	if err := request.Validate(); err != nil {
+		p.logger.Error("invalid location params", "error", err)
+		return domain.LocationParams{}, domain.ErrParams.Wrap(err)
+	}
+
+	return domain.LocationParams{
+		Name:    request.Name,
+		Kind:    domain.LocationKind(request.Kind),
+		Address: request.Address,
+		Pickup:  request.Pickup,
+	}, nil
+}
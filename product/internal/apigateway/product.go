@@ -6,73 +6,137 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"os"
 	"shopy/internal/domain"
 	"shopy/internal/models"
+	"shopy/pkg/barcode"
+	"shopy/pkg/imagex"
+	"shopy/pkg/token"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/google/uuid"
 )
 
 type Service interface {
 	SearchProducts(ctx context.Context, params domain.ProductParams) (models.Products, error)
+	GetProductBySlug(ctx context.Context, slug string, staff bool) (*models.Product, error)
+	GetProductByQRCode(ctx context.Context, qrcode string, staff bool) (*models.Product, error)
+	GetQRCodeImage(ctx context.Context, params domain.QRCodeParams) (string, error)
+	GetLabels(ctx context.Context, uuids []string, staff bool) (string, error)
+	BatchGetProducts(ctx context.Context, params domain.BatchParams) (models.BatchItems, error)
 	AddProduct(ctx context.Context, params domain.ProductParams) (*models.Product, error)
 	PutProduct(ctx context.Context, params domain.ProductParams) (*models.Product, error)
+	PatchProduct(ctx context.Context, patch domain.ProductPatch) (*models.Product, error)
 	DelProduct(ctx context.Context, uuid string) error
+	GetTrashProducts(ctx context.Context) (models.Products, error)
+	RestoreProduct(ctx context.Context, uuid string) (*models.Product, error)
+	TransitionProduct(ctx context.Context, params domain.StatusParams) (*models.Product, error)
+	GetVariants(ctx context.Context, productUuid string) (models.Variants, error)
+	AddVariant(ctx context.Context, params domain.VariantParams) (*models.Variant, error)
+	PutVariant(ctx context.Context, params domain.VariantParams) (*models.Variant, error)
+	DelVariant(ctx context.Context, productUuid, uuid string) error
+	AddImage(ctx context.Context, params domain.ImageParams) (*models.Product, error)
+	PatchImage(ctx context.Context, patch domain.ImagePatch) (*models.Product, error)
+	ReorderImages(ctx context.Context, order domain.ImageOrder) (*models.Product, error)
+	DelImage(ctx context.Context, productUuid, uuid string) (*models.Product, error)
+	AdjustStock(ctx context.Context, params domain.StockParams) (*models.LedgerEntry, error)
+	GetStock(ctx context.Context, productUuid string, limit int32) (*models.Stock, error)
+	TransferStock(ctx context.Context, params domain.TransferParams) (*models.Transfer, error)
+	GetAvailability(ctx context.Context, productUuid string) (*models.Availability, error)
+	GetLocations(ctx context.Context) (models.Locations, error)
+	AddLocation(ctx context.Context, params domain.LocationParams) (*models.Location, error)
+	PutLocation(ctx context.Context, params domain.LocationParams) (*models.Location, error)
+	CreateUpload(ctx context.Context, params domain.UploadParams) (*models.Upload, error)
 }
 
 type Product struct {
 	logger  *slog.Logger
 	service Service
+	jwt     *token.JWT
+	limits  imagex.Limits
 }
 
 func NewProduct(logger *slog.Logger, service Service) *Product {
 	return &Product{
 		logger:  logger,
 		service: service,
+		jwt:     token.NewJWT(os.Getenv("TOKEN_KEY")),
+		limits:  imagex.NewLimits(os.Getenv("IMAGE_MAX_BYTES"), os.Getenv("IMAGE_MAX_DIMENSION")),
 	}
 }
 
 func (p *Product) Router() APIGatewayFunc {
-	return func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-		switch event.HTTPMethod {
-		case http.MethodGet:
-			return p.HandleSearchProducts(ctx, event)
-		case http.MethodPost:
-			return p.HandleAddProduct(ctx, event)
-		case http.MethodPut:
-			return p.HandlePutProduct(ctx, event)
-		case http.MethodDelete:
-			return p.HandleDelProduct(ctx, event)
-		}
-		return events.APIGatewayProxyResponse{
-			Body:       "method is not valid",
-			StatusCode: http.StatusMethodNotAllowed,
-		}, nil
+	routes := Routes{
+		"GET /products":                                   p.HandleSearchProducts,
+		"POST /products":                                  p.HandleAddProduct,
+		"GET /products/by-slug/{slug}":                    p.HandleGetProductBySlug,
+		"GET /products/qrcode/{code}":                     p.HandleGetProductByQRCode,
+		"POST /products/labels":                           p.HandleGetLabels,
+		"POST /products/batch-get":                        p.HandleBatchGetProducts,
+		"GET /products/{uuid}/qrcode.png":                 p.HandleGetQRCodeImage,
+		"PUT /products/{uuid}":                            p.HandlePutProduct,
+		"PATCH /products/{uuid}":                          p.HandlePatchProduct,
+		"DELETE /products/{uuid}":                         p.HandleDelProduct,
+		"GET /products/trash":                             p.HandleGetTrashProducts,
+		"POST /products/{uuid}/restore":                   p.HandleRestoreProduct,
+		"POST /products/{uuid}/status":                    p.HandleTransitionProduct,
+		"GET /products/{uuid}/variants":                   p.HandleGetVariants,
+		"POST /products/{uuid}/variants":                  p.HandleAddVariant,
+		"PUT /products/{uuid}/variants/{variant_uuid}":    p.HandlePutVariant,
+		"DELETE /products/{uuid}/variants/{variant_uuid}": p.HandleDelVariant,
+		"POST /products/{uuid}/images":                    p.HandleAddImage,
+		"PUT /products/{uuid}/images":                     p.HandleReorderImages,
+		"PATCH /products/{uuid}/images/{image_uuid}":      p.HandlePatchImage,
+		"DELETE /products/{uuid}/images/{image_uuid}":     p.HandleDelImage,
+		"GET /products/{uuid}/stock":                      p.HandleGetStock,
+		"POST /products/{uuid}/stock":                     p.HandleAdjustStock,
+		"POST /products/{uuid}/transfers":                 p.HandleTransferStock,
+		"GET /products/{uuid}/availability":               p.HandleGetAvailability,
+		"GET /locations":                                  p.HandleGetLocations,
+		"POST /locations":                                 p.HandleAddLocation,
+		"PUT /locations/{uuid}":                           p.HandlePutLocation,
+		"POST /uploads":                                   p.HandleCreateUpload,
 	}
+	return routes.Router()
 }
 
 // @Summary 	Get products.
-// @Description Retrieves products using query parameters, only one parameter can be used at a time. By default, the top products are returned.
+// @Description Retrieves products using query parameters, only one parameter can be used at a time. By default, the top products are returned. Only published products are listed unless the request carries a staff token.
 // @Tags 		Products
 // @Router 		/products [get]
 // @Accept 		json
 // @Produce 	json
 // @Security    JWT
 // @Param       name query string true "Product name"
-// @Param       qrcode query string true "Product QR code"
+// @Param       qrcode query string true "Product or variant QR code, variant matches include the variant"
 // @Param       category_uuid query string true "Product category UUID"
+// @Param       include_descendants query bool false "Also return the products of the subcategories of category_uuid"
 // @Success     200	{object} SelectedProducts "Success"
 // @Failure     400	{object} ErrorResponse "Bad Request"
 // @Failure     401	{object} ErrorResponse "Unauthorized"
 // @Failure     500	{object} ErrorResponse "Error Internal Server"
 func (p *Product) HandleSearchProducts(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var descendants bool
+	if value := event.QueryStringParameters["include_descendants"]; value != "" {
+		var err error
+		descendants, err = strconv.ParseBool(value)
+		if err != nil {
+			p.logger.Error("invalid include descendants", "include_descendants", value)
+			return Error(domain.ErrParams)
+		}
+	}
+
 	products, err := p.service.SearchProducts(ctx, domain.ProductParams{
 		Name:   event.QueryStringParameters["name"],
 		QRCode: event.QueryStringParameters["qrcode"],
 		Category: domain.Category{
 			Uuid: event.QueryStringParameters["category_uuid"],
 		},
+		Descendants: descendants,
+		Staff:       p.staff(event),
 	})
 	if err != nil {
 		p.logger.Error("error getting products", "error", err)
@@ -87,51 +151,133 @@ func (p *Product) HandleSearchProducts(ctx context.Context, event events.APIGate
 	return JSON(response, http.StatusOK)
 }
 
+// @Summary 	Get product by slug.
+// @Description Get a product by its slug. A slug the product had before a rename redirects to its current slug. Only published products are found unless the request carries a staff token.
+// @Tags 		Products
+// @Router 		/products/by-slug/{slug} [get]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       slug path string true "Product slug"
+// @Success     200	{object} ProductAdded "Success"
+// @Header      200	{string} ETag "Product version"
+// @Success     301	{object} Redirected "Moved Permanently"
+// @Header      301	{string} Location "Current slug"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleGetProductBySlug(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	slug := event.PathParameters["slug"]
+	product, err := p.service.GetProductBySlug(ctx, slug, p.staff(event))
+	if err != nil {
+		p.logger.Error("error getting product by slug", "slug", slug, "error", err)
+		return Error(err)
+	}
+
+	if product.Slug != slug {
+		// the location is relative to the old slug, whatever the stage prefix
+		return Redirect(product.Slug)
+	}
+
+	var response = ProductAdded{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Product:      product,
+	}
+
+	return JSONWithETag(response, http.StatusOK, product.Version)
+}
+
+// @Summary 	Get product by QR code.
+// @Description Get the single product holding a QR code, GS1 codes match as EAN-13, UPC-A or GTIN-14 alike. Trashed products keep their code until they are purged. A variant code returns its parent product with the variant in variant. Only published products are found unless the request carries a staff token.
+// @Tags 		Products
+// @Router 		/products/qrcode/{code} [get]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       code path string true "Product QR code"
+// @Success     200	{object} ProductAdded "Success"
+// @Header      200	{string} ETag "Product version"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleGetProductByQRCode(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	code := event.PathParameters["code"]
+	product, err := p.service.GetProductByQRCode(ctx, code, p.staff(event))
+	if err != nil {
+		p.logger.Error("error getting product by qrcode", "qrcode", code, "error", err)
+		return Error(err)
+	}
+
+	var response = ProductAdded{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Product:      product,
+	}
+
+	return JSONWithETag(response, http.StatusOK, product.Version)
+}
+
 // @Summary 	Add product.
-// @Description Add new product.
+// @Description Add new product, the slug is made from the name unless one is sent. The qrcode is checked against its barcode_type, qrcode by default, and GS1 codes (ean13, upca, gtin14) are stored as GTIN-14. A qrcode held by another product is rejected with 409. The image is sent base64 encoded in image or uploaded beforehand and referenced by upload_uuid. The body can also be multipart/form-data with the same fields, nested ones named with dots such as price.amount, and the image as a binary image part.
 // @Tags 		Products
 // @Router 		/products [post]
-// @Accept 		json
+// @Accept 		json,mpfd
 // @Produce 	json
 // @Security    JWT
 // @Param	    params body  ProductAddRequest true "Product"
 // @Success     201	{object} ProductAdded "Success"
+// @Header      201	{string} ETag "Product version"
 // @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     413	{object} ErrorResponse "Request Entity Too Large"
 // @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     409	{object} ErrorResponse "Conflict"
 // @Failure     500	{object} ErrorResponse "Error Internal Server"
 func (p *Product) HandleAddProduct(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	var request ProductAddRequest
+	var (
+		err     error
+		request ProductAddRequest
+	)
 
-	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+	if err = p.bindAdd(event, &request); err != nil {
 		p.logger.Error("invalid product body", "error", err)
 		return Error(domain.ErrRequest)
 	}
 
-	if err := request.Validate(); err != nil {
+	if err = request.Validate(); err != nil {
 		p.logger.Error("invalid product params", "error", err)
 		return Error(domain.ErrParams.Wrap(err))
 	}
 
-	image, err := base64.StdEncoding.DecodeString(request.Image)
+	image, err := p.requestImage(request.Image, request.ImageFile)
 	if err != nil {
-		p.logger.Error("error decoding image", "error", err)
-		return Error(domain.ErrRequest)
+		return Error(err)
+	}
+
+	status := domain.Status(request.Status)
+	if status == "" {
+		// new products stay hidden until they are published
+		status = domain.StatusDraft
 	}
 
 	now := time.Now().UTC()
 	product, err := p.service.AddProduct(ctx, domain.ProductParams{
-		Uuid:   uuid.New().String(),
-		Name:   request.Name,
-		Price:  request.Price,
-		QRCode: request.QRCode,
-		IsTop:  request.IsTop,
+		Uuid:        uuid.New().String(),
+		Name:        request.Name,
+		Slug:        request.Slug,
+		Price:       request.Price.Money(),
+		QRCode:      request.QRCode,
+		BarcodeType: barcode.Type(request.BarcodeType),
+		IsTop:       request.IsTop,
 		Category: domain.Category{
 			Uuid: request.Category.Uuid,
-			Name: request.Category.Name,
 		},
-		Image:     image,
-		CreatedAt: now,
-		UpdatedAt: now,
+		Image:       image,
+		ImageUuid:   uuid.New().String(),
+		Upload:      request.UploadUuid,
+		Status:      status,
+		PublishAt:   parseTime(request.PublishAt),
+		UnpublishAt: parseTime(request.UnpublishAt),
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	})
 	if err != nil {
 		p.logger.Error("error adding product", "error", err)
@@ -143,21 +289,28 @@ func (p *Product) HandleAddProduct(ctx context.Context, event events.APIGatewayP
 		Product:      product,
 	}
 
-	return JSON(response, http.StatusCreated)
+	return JSONWithETag(response, http.StatusCreated, product.Version)
 }
 
 // @Summary 	Update product.
-// @Description Update product and image fields.
+// @Description Update product fields, the qrcode is checked against its barcode_type and a qrcode held by another product is rejected with 409. A new name moves the product to a new slug unless one is sent and the old slug redirects to it. An image or upload_uuid replaces the primary image of the gallery. The body can also be multipart/form-data with the same fields and the image as a binary image part.
 // @Tags 		Products
 // @Router 		/products/{uuid} [put]
-// @Accept 		json
+// @Accept 		json,mpfd
 // @Produce 	json
 // @Security    JWT
 // @Param       uuid path string true "Product UUID"
+// @Param       If-Match header string true "Product ETag"
 // @Param	    params body  ProductPutRequest true "Product"
 // @Success     201	{object} ProductAdded "Success"
+// @Header      201	{string} ETag "Product version"
 // @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     413	{object} ErrorResponse "Request Entity Too Large"
 // @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     409	{object} ErrorResponse "Conflict"
+// @Failure     412	{object} ErrorResponse "Precondition Failed"
+// @Failure     428	{object} ErrorResponse "Precondition Required"
 // @Failure     500	{object} ErrorResponse "Error Internal Server"
 func (p *Product) HandlePutProduct(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	var (
@@ -165,7 +318,19 @@ func (p *Product) HandlePutProduct(ctx context.Context, event events.APIGatewayP
 		request ProductPutRequest
 	)
 
-	if err = json.Unmarshal([]byte(event.Body), &request); err != nil {
+	ifMatch := Header(event, "If-Match")
+	if ifMatch == "" {
+		p.logger.Error("missing product version")
+		return Error(domain.ErrVersionRequired)
+	}
+
+	version, err := ParseETag(ifMatch)
+	if err != nil {
+		p.logger.Error("invalid product version", "error", err)
+		return Error(domain.ErrVersionMismatch)
+	}
+
+	if err = p.bindPut(event, &request); err != nil {
 		p.logger.Error("invalid product body", "error", err)
 		return Error(domain.ErrRequest)
 	}
@@ -175,27 +340,31 @@ func (p *Product) HandlePutProduct(ctx context.Context, event events.APIGatewayP
 		return Error(domain.ErrParams.Wrap(err))
 	}
 
-	var image []byte
-	if request.Image != "" {
-		image, err = base64.StdEncoding.DecodeString(request.Image)
-		if err != nil {
-			p.logger.Error("error decoding image", "error", err)
-			return Error(domain.ErrRequest)
-		}
+	image, err := p.requestImage(request.Image, request.ImageFile)
+	if err != nil {
+		return Error(err)
+	}
+
+	var imageUuid string
+	if image != nil || request.UploadUuid != "" {
+		imageUuid = uuid.New().String()
 	}
 
-	uuid := event.PathParameters["uuid"]
 	product, err := p.service.PutProduct(ctx, domain.ProductParams{
-		Uuid:   uuid,
-		Name:   request.Name,
-		Price:  request.Price,
-		QRCode: request.QRCode,
-		IsTop:  request.IsTop,
+		Uuid:        event.PathParameters["uuid"],
+		Name:        request.Name,
+		Slug:        request.Slug,
+		Price:       request.Price.Money(),
+		QRCode:      request.QRCode,
+		BarcodeType: barcode.Type(request.BarcodeType),
+		IsTop:       request.IsTop,
 		Category: domain.Category{
 			Uuid: request.Category.Uuid,
-			Name: request.Category.Name,
 		},
 		Image:     image,
+		ImageUuid: imageUuid,
+		Upload:    request.UploadUuid,
+		Version:   version,
 		UpdatedAt: time.Now().UTC(),
 	})
 	if err != nil {
@@ -208,11 +377,122 @@ func (p *Product) HandlePutProduct(ctx context.Context, event events.APIGatewayP
 		Product:      product,
 	}
 
-	return JSON(response, http.StatusOK)
+	return JSONWithETag(response, http.StatusOK, product.Version)
+}
+
+// @Summary 	Patch product.
+// @Description Partially update a product with a JSON Merge Patch (RFC 7396), only the fields present are validated and written. A patched qrcode or barcode_type is checked against the other, patched or stored. A qrcode held by another product is rejected with 409. A new name moves the product to a new slug unless one is sent and the old slug redirects to it. An image or upload_uuid replaces the primary image of the gallery and "image": null removes it.
+// @Tags 		Products
+// @Router 		/products/{uuid} [patch]
+// @Accept 		application/merge-patch+json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param       If-Match header string true "Product ETag"
+// @Param	    params body  ProductPatchRequest true "Product"
+// @Success     200	{object} ProductAdded "Success"
+// @Header      200	{string} ETag "Product version"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     413	{object} ErrorResponse "Request Entity Too Large"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     409	{object} ErrorResponse "Conflict"
+// @Failure     412	{object} ErrorResponse "Precondition Failed"
+// @Failure     428	{object} ErrorResponse "Precondition Required"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandlePatchProduct(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var request ProductPatchRequest
+
+	ifMatch := Header(event, "If-Match")
+	if ifMatch == "" {
+		p.logger.Error("missing product version")
+		return Error(domain.ErrVersionRequired)
+	}
+
+	version, err := ParseETag(ifMatch)
+	if err != nil {
+		p.logger.Error("invalid product version", "error", err)
+		return Error(domain.ErrVersionMismatch)
+	}
+
+	if err = json.Unmarshal([]byte(event.Body), &request); err != nil {
+		p.logger.Error("invalid product body", "error", err)
+		return Error(domain.ErrRequest)
+	}
+
+	if err = request.Validate(); err != nil {
+		p.logger.Error("invalid product params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	patch := domain.ProductPatch{
+		Uuid:         event.PathParameters["uuid"],
+		Name:         request.Name.Value,
+		Slug:         request.Slug.Value,
+		QRCode:       request.QRCode.Value,
+		RemoveQRCode: request.QRCode.Present && request.QRCode.Value == nil,
+		Upload:       request.UploadUuid,
+		RemoveImage:  request.Image.Present && request.Image.Value == nil,
+		Version:      version,
+		UpdatedAt:    time.Now().UTC(),
+	}
+
+	if request.Price.Value != nil {
+		patch.PriceAmount = request.Price.Value.Amount.Value
+		patch.PriceCurrency = request.Price.Value.Currency.Value
+	}
+
+	if request.Category.Value != nil {
+		patch.CategoryUuid = request.Category.Value.Uuid.Value
+	}
+
+	if request.BarcodeType.Value != nil {
+		kind := barcode.Type(*request.BarcodeType.Value)
+		patch.BarcodeType = &kind
+	}
+
+	if request.IsTop.Present {
+		// null resets the flag to its default
+		isTop := request.IsTop.Value != nil && *request.IsTop.Value
+		patch.IsTop = &isTop
+	}
+
+	if request.PublishAt.Value != nil {
+		patch.PublishAt = parseTime(*request.PublishAt.Value)
+	}
+	patch.RemovePublishAt = request.PublishAt.Present && request.PublishAt.Value == nil
+
+	if request.UnpublishAt.Value != nil {
+		patch.UnpublishAt = parseTime(*request.UnpublishAt.Value)
+	}
+	patch.RemoveUnpublishAt = request.UnpublishAt.Present && request.UnpublishAt.Value == nil
+
+	if request.Image.Value != nil {
+		patch.Image, err = p.decodeImage(*request.Image.Value)
+		if err != nil {
+			return Error(err)
+		}
+	}
+	if patch.Image != nil || patch.Upload != "" {
+		patch.ImageUuid = uuid.New().String()
+	}
+
+	product, err := p.service.PatchProduct(ctx, patch)
+	if err != nil {
+		p.logger.Error("error patching product", "error", err)
+		return Error(err)
+	}
+
+	var response = ProductAdded{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Product:      product,
+	}
+
+	return JSONWithETag(response, http.StatusOK, product.Version)
 }
 
 // @Summary 	Delete product.
-// @Description Delete product and image.
+// @Description Move product to the trash, the images are kept until the product is purged.
 // @Tags 		Products
 // @Router 		/products/{uuid} [delete]
 // @Accept 		json
@@ -237,3 +517,269 @@ func (p *Product) HandleDelProduct(ctx context.Context, event events.APIGatewayP
 
 	return JSON(response, http.StatusOK)
 }
+
+// @Summary 	Get deleted products.
+// @Description Get the products in the trash.
+// @Tags 		Products
+// @Router 		/products/trash [get]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Success     200	{object} SelectedProducts "Success"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleGetTrashProducts(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	products, err := p.service.GetTrashProducts(ctx)
+	if err != nil {
+		p.logger.Error("error getting deleted products", "error", err)
+		return Error(err)
+	}
+
+	var response = SelectedProducts{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Products:     products,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
+// @Summary 	Restore product.
+// @Description Restore a product from the trash.
+// @Tags 		Products
+// @Router 		/products/{uuid}/restore [post]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Success     200	{object} ProductAdded "Success"
+// @Header      200	{string} ETag "Product version"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleRestoreProduct(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	uuid := event.PathParameters["uuid"]
+	product, err := p.service.RestoreProduct(ctx, uuid)
+	if err != nil {
+		p.logger.Error("error restoring product", "error", err)
+		return Error(err)
+	}
+
+	var response = ProductAdded{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Product:      product,
+	}
+
+	return JSONWithETag(response, http.StatusOK, product.Version)
+}
+
+// @Summary 	Change product status.
+// @Description Move a product between the draft, published and archived states. Publishing clears publish_at and archiving clears the whole schedule.
+// @Tags 		Products
+// @Router 		/products/{uuid}/status [post]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param	    params body  StatusRequest true "Status"
+// @Success     200	{object} ProductAdded "Success"
+// @Header      200	{string} ETag "Product version"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     409	{object} ErrorResponse "Conflict"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleTransitionProduct(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var request StatusRequest
+
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		p.logger.Error("invalid status body", "error", err)
+		return Error(domain.ErrRequest)
+	}
+
+	if err := request.Validate(); err != nil {
+		p.logger.Error("invalid status params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	product, err := p.service.TransitionProduct(ctx, domain.StatusParams{
+		Uuid:      event.PathParameters["uuid"],
+		Status:    domain.Status(request.Status),
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		p.logger.Error("error changing product status", "error", err)
+		return Error(err)
+	}
+
+	var response = ProductAdded{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Product:      product,
+	}
+
+	return JSONWithETag(response, http.StatusOK, product.Version)
+}
+
+// staff reports whether the request carries a valid access token.
+func (p *Product) staff(event events.APIGatewayProxyRequest) bool {
+	return p.jwt.Validate(Header(event, "Authorization")) == nil
+}
+
+// bindAdd decodes a JSON or multipart/form-data body into request. Form
+// fields are named after the JSON fields with dots for nested ones such as
+// "price.amount", the image may also be sent as a binary file part.
+func (p *Product) bindAdd(event events.APIGatewayProxyRequest, request *ProductAddRequest) error {
+	if !IsMultipart(event) {
+		body, err := Body(event)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, request)
+	}
+
+	form, err := ParseForm(event)
+	if err != nil {
+		return err
+	}
+
+	request.Name = form.FormValue("name")
+	request.Slug = form.FormValue("slug")
+	request.Price.Currency = form.FormValue("price.currency")
+	request.Image = form.FormValue("image")
+	request.UploadUuid = form.FormValue("upload_uuid")
+	request.QRCode = form.FormValue("qrcode")
+	request.BarcodeType = form.FormValue("barcode_type")
+	request.Category.Uuid = form.FormValue("category.uuid")
+	request.Status = form.FormValue("status")
+	request.PublishAt = form.FormValue("publish_at")
+	request.UnpublishAt = form.FormValue("unpublish_at")
+
+	if request.Price.Amount, err = FormInt(form, "price.amount"); err != nil {
+		return err
+	}
+	if request.IsTop, err = FormBool(form, "is_top"); err != nil {
+		return err
+	}
+
+	request.ImageFile, err = FormFile(form, "image", p.limits.MaxBytes)
+	return err
+}
+
+// bindPut decodes a JSON or multipart/form-data body into request, the form
+// fields are those of bindAdd.
+func (p *Product) bindPut(event events.APIGatewayProxyRequest, request *ProductPutRequest) error {
+	if !IsMultipart(event) {
+		body, err := Body(event)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, request)
+	}
+
+	form, err := ParseForm(event)
+	if err != nil {
+		return err
+	}
+
+	request.Name = form.FormValue("name")
+	request.Slug = form.FormValue("slug")
+	request.Price.Currency = form.FormValue("price.currency")
+	request.Image = form.FormValue("image")
+	request.UploadUuid = form.FormValue("upload_uuid")
+	request.QRCode = form.FormValue("qrcode")
+	request.BarcodeType = form.FormValue("barcode_type")
+	request.Category.Uuid = form.FormValue("category.uuid")
+
+	if request.Price.Amount, err = FormInt(form, "price.amount"); err != nil {
+		return err
+	}
+	if request.IsTop, err = FormBool(form, "is_top"); err != nil {
+		return err
+	}
+
+	request.ImageFile, err = FormFile(form, "image", p.limits.MaxBytes)
+	return err
+}
+
+// requestImage returns the image sent as a multipart file or base64 encoded,
+// if any.
+func (p *Product) requestImage(encoded string, file []byte) ([]byte, error) {
+	switch {
+	case file != nil:
+		return p.checkImage(file)
+	case encoded != "":
+		return p.decodeImage(encoded)
+	}
+	return nil, nil
+}
+
+// decodeImage decodes a base64 image and checks it against the upload limits.
+func (p *Product) decodeImage(encoded string) ([]byte, error) {
+	image, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		p.logger.Error("error decoding image", "error", err)
+		return nil, domain.ErrRequest
+	}
+	return p.checkImage(image)
+}
+
+// checkImage checks an image against the upload limits, so invalid or
+// oversized images never reach the storage.
+func (p *Product) checkImage(image []byte) ([]byte, error) {
+	if err := p.limits.Validate(image); err != nil {
+		p.logger.Error("invalid image", "error", err)
+		errs := validation.Errors{"image": err}
+		if imagex.TooLarge(err) {
+			return nil, domain.ErrImageTooLarge.Wrap(errs)
+		}
+		return nil, domain.ErrImage.Wrap(errs)
+	}
+
+	return image, nil
+}
+
+// @Summary 	Get products in batch.
+// @Description Look up to 100 products at once, each item holds either a uuid or a qrcode. A qrcode matches the product holding it or else a variant code, which returns the parent product with the variant. The items are returned in the order of the request and those matching no product have found false. Only published products are found unless the request carries a staff token.
+// @Tags 		Products
+// @Router 		/products/batch-get [post]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param	    params body  BatchGetRequest true "Items"
+// @Success     200	{object} SelectedBatch "Success"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleBatchGetProducts(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var request BatchGetRequest
+
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		p.logger.Error("invalid batch body", "error", err)
+		return Error(domain.ErrRequest)
+	}
+
+	if err := request.Validate(); err != nil {
+		p.logger.Error("invalid batch params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	keys := make([]domain.BatchKey, len(request.Items))
+	for i, item := range request.Items {
+		keys[i] = domain.BatchKey{Uuid: item.Uuid, QRCode: item.QRCode}
+	}
+
+	items, err := p.service.BatchGetProducts(ctx, domain.BatchParams{
+		Keys:  keys,
+		Staff: p.staff(event),
+	})
+	if err != nil {
+		p.logger.Error("error getting products in batch", "error", err)
+		return Error(err)
+	}
+
+	var response = SelectedBatch{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Items:        items,
+	}
+
+	return JSON(response, http.StatusOK)
+}
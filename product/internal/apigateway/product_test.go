@@ -0,0 +1,196 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"shopy/pkg/imagex"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type formPart struct {
+	name, file, value string
+}
+
+// formEvent builds an API Gateway event with a multipart/form-data body
+// holding parts in order.
+func formEvent(t *testing.T, parts ...formPart) events.APIGatewayProxyRequest {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range parts {
+		var err error
+		if part.file != "" {
+			w, ferr := writer.CreateFormFile(part.name, part.file)
+			if ferr != nil {
+				t.Fatal(ferr)
+			}
+			_, err = w.Write([]byte(part.value))
+		} else {
+			err = writer.WriteField(part.name, part.value)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return events.APIGatewayProxyRequest{
+		Headers: map[string]string{"content-type": writer.FormDataContentType()},
+		Body:    body.String(),
+	}
+}
+
+func testProduct(maxBytes int) *Product {
+	return &Product{
+		logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		limits: imagex.Limits{MaxBytes: maxBytes, MaxDimension: imagex.DefaultMaxDimension},
+	}
+}
+
+func TestBindAddForm(t *testing.T) {
+	event := formEvent(t,
+		formPart{name: "name", value: "Café crème"},
+		formPart{name: "slug", value: "cafe-creme"},
+		formPart{name: "price.amount", value: "250"},
+		formPart{name: "price.currency", value: "EUR"},
+		formPart{name: "is_top", value: "true"},
+		formPart{name: "category.uuid", value: "8c1a8a5e-8bd6-4b0e-9d5c-0a4c43f7d0a1"},
+		formPart{name: "barcode_type", value: "ean13"},
+		formPart{name: "qrcode", value: "4006381333931"},
+		formPart{name: "status", value: "published"},
+		formPart{name: "image", file: "cafe.png", value: "png"},
+		formPart{name: "unknown", value: "ignored"},
+	)
+
+	var request ProductAddRequest
+	if err := testProduct(1024).bindAdd(event, &request); err != nil {
+		t.Fatal(err)
+	}
+
+	want := ProductAddRequest{
+		Name:        "Café crème",
+		Slug:        "cafe-creme",
+		Price:       PriceRequest{Amount: 250, Currency: "EUR"},
+		QRCode:      "4006381333931",
+		BarcodeType: "ean13",
+		IsTop:       true,
+		Category:    CategoryRequest{Uuid: "8c1a8a5e-8bd6-4b0e-9d5c-0a4c43f7d0a1"},
+		Status:      "published",
+	}
+	if string(request.ImageFile) != "png" {
+		t.Errorf("image file = %q, want %q", request.ImageFile, "png")
+	}
+	request.ImageFile = nil
+	if !reflect.DeepEqual(request, want) {
+		t.Errorf("request = %+v, want %+v", request, want)
+	}
+}
+
+func TestBindAddFormInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		part formPart
+	}{
+		{"amount", formPart{name: "price.amount", value: "2.50"}},
+		{"is_top", formPart{name: "is_top", value: "yes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request ProductAddRequest
+			if err := testProduct(1024).bindAdd(formEvent(t, tt.part), &request); err == nil {
+				t.Errorf("bindAdd(%s=%q) succeeded, want an error", tt.part.name, tt.part.value)
+			}
+		})
+	}
+}
+
+func TestBindAddFormMissingFile(t *testing.T) {
+	event := formEvent(t,
+		formPart{name: "name", value: "Café"},
+		formPart{name: "upload_uuid", value: "0b8d5c4e-2a43-4f4b-9f0c-6a9b8f2e5d11"},
+	)
+
+	var request ProductAddRequest
+	if err := testProduct(1024).bindAdd(event, &request); err != nil {
+		t.Fatal(err)
+	}
+	if request.ImageFile != nil {
+		t.Errorf("image file = %q, want nil", request.ImageFile)
+	}
+	if request.Price.Amount != 0 || request.IsTop {
+		t.Errorf("missing fields = %+v, want zero values", request)
+	}
+}
+
+func TestBindAddFormRepeatedFields(t *testing.T) {
+	event := formEvent(t,
+		formPart{name: "name", value: "first"},
+		formPart{name: "name", value: "second"},
+		formPart{name: "image", file: "a.png", value: "a"},
+		formPart{name: "image", file: "b.png", value: "b"},
+	)
+
+	var request ProductAddRequest
+	if err := testProduct(1024).bindAdd(event, &request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Name != "first" {
+		t.Errorf("name = %q, want the first value", request.Name)
+	}
+	if string(request.ImageFile) != "a" {
+		t.Errorf("image file = %q, want the first file", request.ImageFile)
+	}
+}
+
+func TestBindAddJSON(t *testing.T) {
+	event := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    `{"name":"Café","price":{"amount":250,"currency":"EUR"},"is_top":true}`,
+	}
+
+	var request ProductAddRequest
+	if err := testProduct(1024).bindAdd(event, &request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Name != "Café" || request.Price.Amount != 250 || !request.IsTop || request.ImageFile != nil {
+		t.Errorf("request = %+v", request)
+	}
+}
+
+func TestAddProductOversizedFile(t *testing.T) {
+	const maxBytes = 16
+
+	event := formEvent(t,
+		formPart{name: "name", value: "Café"},
+		formPart{name: "price.amount", value: "250"},
+		formPart{name: "price.currency", value: "EUR"},
+		formPart{name: "category.uuid", value: "8c1a8a5e-8bd6-4b0e-9d5c-0a4c43f7d0a1"},
+		formPart{name: "image", file: "big.png", value: string(make([]byte, 4*maxBytes))},
+	)
+
+	p := testProduct(maxBytes)
+	var request ProductAddRequest
+	if err := p.bindAdd(event, &request); err != nil {
+		t.Fatal(err)
+	}
+	if len(request.ImageFile) != maxBytes+1 {
+		t.Errorf("read %d bytes of the image, want %d", len(request.ImageFile), maxBytes+1)
+	}
+
+	response, err := p.HandleAddProduct(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d: %s", response.StatusCode, http.StatusRequestEntityTooLarge, response.Body)
+	}
+}
@@ -1,17 +1,34 @@
 package apigateway
 
 import (
+	"encoding/json"
+	"regexp"
+	"shopy/internal/domain"
+	"shopy/pkg/barcode"
+	"shopy/pkg/money"
+	"shopy/pkg/slug"
+	"time"
+
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
 )
 
+var skuRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
 type ProductAddRequest struct {
-	Name     string          `json:"name"`
-	Price    float64         `json:"price"`
-	Image    string          `json:"image"`
-	QRCode   string          `json:"qrcode"`
-	IsTop    bool            `json:"is_top"`
-	Category CategoryRequest `json:"category"`
+	Name        string          `json:"name"`
+	Slug        string          `json:"slug" example:"cafe-creme"`
+	Price       PriceRequest    `json:"price"`
+	Image       string          `json:"image"`
+	ImageFile   []byte          `json:"-" swaggerignore:"true"`
+	UploadUuid  string          `json:"upload_uuid"`
+	QRCode      string          `json:"qrcode"`
+	BarcodeType string          `json:"barcode_type" enums:"qrcode,ean13,upca,gtin14"`
+	IsTop       bool            `json:"is_top"`
+	Category    CategoryRequest `json:"category"`
+	Status      string          `json:"status" enums:"draft,published"`
+	PublishAt   string          `json:"publish_at" example:"2026-12-01T00:00:00Z"`
+	UnpublishAt string          `json:"unpublish_at" example:"2027-01-07T00:00:00Z"`
 }
 
 func (p ProductAddRequest) Validate() error {
@@ -20,29 +37,57 @@ func (p ProductAddRequest) Validate() error {
 			validation.Required,
 			validation.Length(1, 100),
 		),
-		validation.Field(&p.Price,
-			validation.Required,
-			validation.Min(0.0),
+		validation.Field(&p.Slug,
+			validation.Length(1, slug.MaxLength),
+			validation.By(slug.Validate),
 		),
+		validation.Field(&p.Price),
 		validation.Field(&p.Image,
-			validation.Required,
+			validation.When(p.UploadUuid == "" && p.ImageFile == nil,
+				validation.Required,
+			).Else(
+				validation.Empty,
+			),
 			is.Base64,
 		),
+		validation.Field(&p.UploadUuid,
+			validation.When(p.ImageFile != nil,
+				validation.Empty,
+			),
+			is.UUID,
+		),
 		validation.Field(&p.QRCode,
 			validation.When(p.QRCode != "",
-				is.Alphanumeric,
+				validation.By(isBarcode(p.BarcodeType)),
 			)),
+		validation.Field(&p.BarcodeType,
+			validation.By(isBarcodeType),
+		),
 		validation.Field(&p.Category),
+		validation.Field(&p.Status,
+			validation.In(string(domain.StatusDraft), string(domain.StatusPublished)),
+		),
+		validation.Field(&p.PublishAt,
+			validation.Date(time.RFC3339),
+		),
+		validation.Field(&p.UnpublishAt,
+			validation.Date(time.RFC3339),
+			validation.By(after(p.PublishAt)),
+		),
 	)
 }
 
 type ProductPutRequest struct {
-	Name     string          `json:"name"`
-	Price    float64         `json:"price"`
-	Image    string          `json:"image"`
-	QRCode   string          `json:"qrcode"`
-	IsTop    bool            `json:"is_top"`
-	Category CategoryRequest `json:"category"`
+	Name        string          `json:"name"`
+	Slug        string          `json:"slug" example:"cafe-creme"`
+	Price       PriceRequest    `json:"price"`
+	Image       string          `json:"image"`
+	ImageFile   []byte          `json:"-" swaggerignore:"true"`
+	UploadUuid  string          `json:"upload_uuid"`
+	QRCode      string          `json:"qrcode"`
+	BarcodeType string          `json:"barcode_type" enums:"qrcode,ean13,upca,gtin14"`
+	IsTop       bool            `json:"is_top"`
+	Category    CategoryRequest `json:"category"`
 }
 
 func (p ProductPutRequest) Validate() error {
@@ -51,25 +96,38 @@ func (p ProductPutRequest) Validate() error {
 			validation.Required,
 			validation.Length(1, 100),
 		),
-		validation.Field(&p.Price,
-			validation.Required,
-			validation.Min(0.0),
+		validation.Field(&p.Slug,
+			validation.Length(1, slug.MaxLength),
+			validation.By(slug.Validate),
 		),
+		validation.Field(&p.Price),
 		validation.Field(&p.Image,
-			validation.When(p.Image != "",
-				is.Base64,
-			)),
+			validation.When(p.UploadUuid != "" || p.ImageFile != nil,
+				validation.Empty,
+			),
+			is.Base64,
+		),
+		validation.Field(&p.UploadUuid,
+			validation.When(p.ImageFile != nil,
+				validation.Empty,
+			),
+			is.UUID,
+		),
 		validation.Field(&p.QRCode,
 			validation.When(p.QRCode != "",
-				is.Alphanumeric,
+				validation.By(isBarcode(p.BarcodeType)),
 			)),
+		validation.Field(&p.BarcodeType,
+			validation.By(isBarcodeType),
+		),
 		validation.Field(&p.Category),
 	)
 }
 
+// CategoryRequest references the category of a product, its name is taken
+// from the category itself.
 type CategoryRequest struct {
 	Uuid string `json:"uuid"`
-	Name string `json:"name"`
 }
 
 func (c CategoryRequest) Validate() error {
@@ -78,9 +136,494 @@ func (c CategoryRequest) Validate() error {
 			validation.Required,
 			is.UUID,
 		),
-		validation.Field(&c.Name,
+	)
+}
+
+type PriceRequest struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func (p PriceRequest) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Amount,
+			validation.Min(int64(0)),
+		),
+		validation.Field(&p.Currency,
+			validation.Required,
+			validation.By(isCurrency),
+		),
+	)
+}
+
+func (p PriceRequest) Money() money.Money {
+	return money.Money{
+		Amount:   p.Amount,
+		Currency: p.Currency,
+	}
+}
+
+func isCurrency(value any) error {
+	code, _ := value.(string)
+	if !money.IsCurrency(code) {
+		return money.ErrCurrency
+	}
+	return nil
+}
+
+// Member is a JSON Merge Patch (RFC 7396) member, Present reports whether it
+// was sent and a nil Value means it was sent as null.
+type Member[T any] struct {
+	Present bool
+	Value   *T
+}
+
+func (m *Member[T]) UnmarshalJSON(data []byte) error {
+	m.Present = true
+	if string(data) == "null" {
+		m.Value = nil
+		return nil
+	}
+	m.Value = new(T)
+	return json.Unmarshal(data, m.Value)
+}
+
+type ProductPatchRequest struct {
+	Name        Member[string]               `json:"name" swaggertype:"string"`
+	Slug        Member[string]               `json:"slug" swaggertype:"string"`
+	Price       Member[PricePatchRequest]    `json:"price" swaggertype:"object"`
+	Image       Member[string]               `json:"image" swaggertype:"string"`
+	UploadUuid  string                       `json:"upload_uuid"`
+	QRCode      Member[string]               `json:"qrcode" swaggertype:"string"`
+	BarcodeType Member[string]               `json:"barcode_type" swaggertype:"string" enums:"qrcode,ean13,upca,gtin14"`
+	IsTop       Member[bool]                 `json:"is_top" swaggertype:"boolean"`
+	Category    Member[CategoryPatchRequest] `json:"category" swaggertype:"object"`
+	PublishAt   Member[string]               `json:"publish_at" swaggertype:"string"`
+	UnpublishAt Member[string]               `json:"unpublish_at" swaggertype:"string"`
+}
+
+func (p ProductPatchRequest) Validate() error {
+	errs := validation.Errors{}
+	if p.Name.Present {
+		errs["name"] = validation.Validate(p.Name.Value,
+			validation.NotNil,
+			validation.Length(1, 100),
+		)
+	}
+	if p.Slug.Present {
+		errs["slug"] = validation.Validate(p.Slug.Value,
+			validation.NotNil,
+			validation.Length(1, slug.MaxLength),
+			validation.By(slug.Validate),
+		)
+	}
+	if p.Price.Present {
+		errs["price"] = validation.Validate(p.Price.Value, validation.NotNil)
+	}
+	if p.Image.Present {
+		errs["image"] = validation.Validate(p.Image.Value,
+			validation.When(p.UploadUuid != "",
+				validation.Nil,
+			),
+			is.Base64,
+		)
+	}
+	errs["upload_uuid"] = validation.Validate(p.UploadUuid, is.UUID)
+	if p.QRCode.Present {
+		// checked against the barcode type of the product by the service
+		errs["qrcode"] = validation.Validate(p.QRCode.Value, is.Alphanumeric)
+	}
+	if p.BarcodeType.Present {
+		errs["barcode_type"] = validation.Validate(p.BarcodeType.Value,
+			validation.NotNil,
+			validation.By(isBarcodeType),
+		)
+	}
+	if p.Category.Present {
+		errs["category"] = validation.Validate(p.Category.Value, validation.NotNil)
+	}
+	if p.PublishAt.Present {
+		errs["publish_at"] = validation.Validate(p.PublishAt.Value, validation.Date(time.RFC3339))
+	}
+	if p.UnpublishAt.Present {
+		errs["unpublish_at"] = validation.Validate(p.UnpublishAt.Value, validation.Date(time.RFC3339))
+	}
+	return errs.Filter()
+}
+
+type PricePatchRequest struct {
+	Amount   Member[int64]  `json:"amount" swaggertype:"integer"`
+	Currency Member[string] `json:"currency" swaggertype:"string"`
+}
+
+func (p PricePatchRequest) Validate() error {
+	errs := validation.Errors{}
+	if p.Amount.Present {
+		errs["amount"] = validation.Validate(p.Amount.Value,
+			validation.NotNil,
+			validation.Min(int64(0)),
+		)
+	}
+	if p.Currency.Present {
+		errs["currency"] = validation.Validate(p.Currency.Value,
+			validation.NotNil,
+			validation.By(isCurrency),
+		)
+	}
+	return errs.Filter()
+}
+
+// CategoryPatchRequest moves a product to another category, its name is taken
+// from the category itself.
+type CategoryPatchRequest struct {
+	Uuid Member[string] `json:"uuid" swaggertype:"string"`
+}
+
+func (c CategoryPatchRequest) Validate() error {
+	return validation.Errors{
+		"uuid": validation.Validate(c.Uuid.Value,
+			validation.NotNil,
+			is.UUID,
+		),
+	}.Filter()
+}
+
+type StatusRequest struct {
+	Status string `json:"status" enums:"draft,published,archived"`
+}
+
+func (s StatusRequest) Validate() error {
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.Status,
+			validation.Required,
+			validation.In(string(domain.StatusDraft), string(domain.StatusPublished), string(domain.StatusArchived)),
+		),
+	)
+}
+
+// isBarcode checks a code against the barcode type sent with it, GS1 codes
+// must carry a valid check digit.
+func isBarcode(kind string) validation.RuleFunc {
+	return func(value any) error {
+		code, _ := value.(string)
+		if !barcode.Type(kind).Valid() {
+			// reported on barcode_type
+			return nil
+		}
+		return barcode.Validate(barcode.Type(kind), code)
+	}
+}
+
+func isBarcodeType(value any) error {
+	var kind string
+	switch value := value.(type) {
+	case string:
+		kind = value
+	case *string:
+		if value != nil {
+			kind = *value
+		}
+	}
+	if !barcode.Type(kind).Valid() {
+		return barcode.ErrType
+	}
+	return nil
+}
+
+// after checks that an RFC 3339 time is later than start, when both are set.
+func after(start string) validation.RuleFunc {
+	return func(value any) error {
+		end, _ := value.(string)
+		if start == "" || end == "" {
+			return nil
+		}
+		from, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return nil
+		}
+		to, err := time.Parse(time.RFC3339, end)
+		if err != nil || to.After(from) {
+			return nil
+		}
+		return validation.NewError("validation_time_after", "must be later than publish_at")
+	}
+}
+
+// parseTime parses an optional RFC 3339 time that has already been validated.
+func parseTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+type VariantRequest struct {
+	Sku         string            `json:"sku"`
+	Options     map[string]string `json:"options"`
+	Price       *PriceRequest     `json:"price"`
+	QRCode      string            `json:"qrcode"`
+	BarcodeType string            `json:"barcode_type" enums:"qrcode,ean13,upca,gtin14"`
+	Image       string            `json:"image"`
+	UploadUuid  string            `json:"upload_uuid"`
+}
+
+func (v VariantRequest) Validate() error {
+	return validation.ValidateStruct(&v,
+		validation.Field(&v.Sku,
+			validation.Required,
+			validation.Length(1, 64),
+			validation.Match(skuRegexp),
+		),
+		validation.Field(&v.Options,
+			validation.Required,
+			validation.Each(
+				validation.Required,
+				validation.Length(1, 50),
+			),
+		),
+		validation.Field(&v.Price),
+		validation.Field(&v.QRCode,
+			validation.When(v.QRCode != "",
+				validation.By(isBarcode(v.BarcodeType)),
+			)),
+		validation.Field(&v.BarcodeType,
+			validation.By(isBarcodeType),
+		),
+		validation.Field(&v.Image,
+			validation.When(v.UploadUuid != "",
+				validation.Empty,
+			),
+			is.Base64,
+		),
+		validation.Field(&v.UploadUuid,
+			is.UUID,
+		),
+	)
+}
+
+type StockRequest struct {
+	Adjustment   string `json:"adjustment" enums:"receive,sell,correct"`
+	Quantity     int64  `json:"quantity"`
+	Reason       string `json:"reason" enums:"purchase,return,production,sale,sample,count,damaged,lost,found"`
+	VariantUuid  string `json:"variant_uuid"`
+	LocationUuid string `json:"location_uuid"`
+	Note         string `json:"note"`
+}
+
+func (s StockRequest) Validate() error {
+	adjustment := domain.Adjustment(s.Adjustment)
+
+	reasons := make([]any, 0, len(adjustment.Reasons()))
+	for _, reason := range adjustment.Reasons() {
+		reasons = append(reasons, reason)
+	}
+
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.Adjustment,
+			validation.Required,
+			validation.In(string(domain.AdjustmentReceive), string(domain.AdjustmentSell), string(domain.AdjustmentCorrect)),
+		),
+		validation.Field(&s.Quantity,
+			validation.Required,
+			validation.When(adjustment != domain.AdjustmentCorrect,
+				validation.Min(int64(1)),
+			),
+		),
+		validation.Field(&s.Reason,
+			validation.Required,
+			validation.In(reasons...),
+		),
+		validation.Field(&s.VariantUuid,
+			validation.When(s.VariantUuid != "",
+				is.UUID,
+			)),
+		validation.Field(&s.LocationUuid,
+			validation.When(s.LocationUuid != "",
+				is.UUID,
+			)),
+		validation.Field(&s.Note,
+			validation.Length(0, 255),
+		),
+	)
+}
+
+type TransferRequest struct {
+	FromLocationUuid string `json:"from_location_uuid"`
+	ToLocationUuid   string `json:"to_location_uuid"`
+	Quantity         int64  `json:"quantity"`
+	VariantUuid      string `json:"variant_uuid"`
+	Note             string `json:"note"`
+}
+
+func (t TransferRequest) Validate() error {
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.FromLocationUuid,
+			validation.Required,
+			is.UUID,
+		),
+		validation.Field(&t.ToLocationUuid,
+			validation.Required,
+			is.UUID,
+			validation.NotIn(t.FromLocationUuid).Error("must be different from from_location_uuid"),
+		),
+		validation.Field(&t.Quantity,
+			validation.Required,
+			validation.Min(int64(1)),
+		),
+		validation.Field(&t.VariantUuid,
+			validation.When(t.VariantUuid != "",
+				is.UUID,
+			)),
+		validation.Field(&t.Note,
+			validation.Length(0, 255),
+		),
+	)
+}
+
+type LocationRequest struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind" enums:"store,warehouse"`
+	Address string `json:"address"`
+	Pickup  bool   `json:"pickup"`
+}
+
+func (l LocationRequest) Validate() error {
+	return validation.ValidateStruct(&l,
+		validation.Field(&l.Name,
+			validation.Required,
+			validation.Length(1, 100),
+		),
+		validation.Field(&l.Kind,
+			validation.Required,
+			validation.In(string(domain.LocationStore), string(domain.LocationWarehouse)),
+		),
+		validation.Field(&l.Address,
+			validation.Length(0, 255),
+		),
+	)
+}
+
+type ImageRequest struct {
+	Image      string `json:"image"`
+	UploadUuid string `json:"upload_uuid"`
+	Alt        string `json:"alt"`
+	Primary    bool   `json:"primary"`
+}
+
+func (i ImageRequest) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Image,
+			validation.When(i.UploadUuid == "",
+				validation.Required,
+			).Else(
+				validation.Empty,
+			),
+			is.Base64,
+		),
+		validation.Field(&i.UploadUuid,
+			is.UUID,
+		),
+		validation.Field(&i.Alt,
+			validation.Length(0, 255),
+		),
+	)
+}
+
+type ImagePatchRequest struct {
+	Alt     *string `json:"alt"`
+	Primary bool    `json:"primary"`
+}
+
+func (i ImagePatchRequest) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Alt,
+			validation.Length(0, 255),
+		),
+	)
+}
+
+type ImageOrderRequest struct {
+	Images []string `json:"images"`
+}
+
+func (i ImageOrderRequest) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Images,
+			validation.Required,
+			validation.Each(is.UUID),
+		),
+	)
+}
+
+type UploadRequest struct {
+	ContentType string `json:"content_type" enums:"image/png,image/jpeg,image/webp,image/gif"`
+	Size        int64  `json:"size"`
+}
+
+func (u UploadRequest) Validate() error {
+	contentTypes := make([]any, len(domain.UploadContentTypes))
+	for i, contentType := range domain.UploadContentTypes {
+		contentTypes[i] = contentType
+	}
+
+	return validation.ValidateStruct(&u,
+		validation.Field(&u.ContentType,
+			validation.Required,
+			validation.In(contentTypes...),
+		),
+		validation.Field(&u.Size,
+			validation.Required,
+			validation.Min(int64(1)),
+		),
+	)
+}
+
+type LabelsRequest struct {
+	Uuids []string `json:"uuids"`
+}
+
+func (l LabelsRequest) Validate() error {
+	return validation.ValidateStruct(&l,
+		validation.Field(&l.Uuids,
+			validation.Required,
+			validation.Length(1, domain.MaxLabels),
+			validation.Each(is.UUID),
+		),
+	)
+}
+
+type BatchGetRequest struct {
+	Items []BatchGetItem `json:"items"`
+}
+
+// BatchGetItem holds either the UUID or a QR code of a product.
+type BatchGetItem struct {
+	Uuid   string `json:"uuid,omitempty"`
+	QRCode string `json:"qrcode,omitempty"`
+}
+
+func (b BatchGetRequest) Validate() error {
+	return validation.ValidateStruct(&b,
+		validation.Field(&b.Items,
 			validation.Required,
-			validation.Length(1, 50),
+			validation.Length(1, domain.MaxBatch),
+		),
+	)
+}
+
+func (b BatchGetItem) Validate() error {
+	return validation.ValidateStruct(&b,
+		validation.Field(&b.Uuid,
+			validation.When(b.QRCode == "", validation.Required).Else(validation.Empty),
+			is.UUID,
+		),
+		validation.Field(&b.QRCode,
+			validation.Length(1, 100),
+			is.Alphanumeric,
 		),
 	)
 }
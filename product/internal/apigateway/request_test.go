@@ -0,0 +1,23 @@
+package apigateway
+
+import "testing"
+
+func TestPriceRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		price   PriceRequest
+		wantErr bool
+	}{
+		{name: "price", price: PriceRequest{Amount: 1999, Currency: "USD"}},
+		{name: "free", price: PriceRequest{Amount: 0, Currency: "USD"}},
+		{name: "negative", price: PriceRequest{Amount: -1, Currency: "USD"}, wantErr: true},
+		{name: "no currency", price: PriceRequest{Amount: 1999}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.price.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -29,7 +29,69 @@ type ProductAdded struct {
 	Product *models.Product `json:"product"`
 }
 
+// Redirected points the client to another location, such as the current slug
+// of an item or a cached render.
+type Redirected struct {
+	BaseResponse
+	Location string `json:"location"`
+}
+
 type ProductDeleted struct {
 	BaseResponse
 	Product string `json:"product"`
 }
+
+type SelectedVariants struct {
+	BaseResponse
+	Variants models.Variants `json:"variants"`
+}
+
+type VariantAdded struct {
+	BaseResponse
+	Variant *models.Variant `json:"variant"`
+}
+
+type VariantDeleted struct {
+	BaseResponse
+	Variant string `json:"variant"`
+}
+
+type SelectedStock struct {
+	BaseResponse
+	Stock *models.Stock `json:"stock"`
+}
+
+type StockAdjusted struct {
+	BaseResponse
+	Entry *models.LedgerEntry `json:"entry"`
+}
+
+type TransferAdded struct {
+	BaseResponse
+	Transfer *models.Transfer `json:"transfer"`
+}
+
+type SelectedAvailability struct {
+	BaseResponse
+	Availability *models.Availability `json:"availability"`
+}
+
+type SelectedLocations struct {
+	BaseResponse
+	Locations models.Locations `json:"locations"`
+}
+
+type LocationAdded struct {
+	BaseResponse
+	Location *models.Location `json:"location"`
+}
+
+type UploadCreated struct {
+	BaseResponse
+	Upload *models.Upload `json:"upload"`
+}
+
+type SelectedBatch struct {
+	BaseResponse
+	Items models.BatchItems `json:"items"`
+}
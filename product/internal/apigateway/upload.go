@@ -0,0 +1,67 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"shopy/internal/domain"
+	"shopy/pkg/imagex"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+// @Summary 	Create upload.
+// @Description Create a presigned URL to upload an image straight to the bucket. The client sends the image with the returned method and headers before the URL expires, then references the upload UUID as upload_uuid when creating or updating a product, variant, gallery image or category. The image is checked when it is attached.
+// @Tags 		Uploads
+// @Router 		/uploads [post]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param	    params body  UploadRequest true "Upload"
+// @Success     201	{object} UploadCreated "Success"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     413	{object} ErrorResponse "Request Entity Too Large"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleCreateUpload(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var request UploadRequest
+
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		p.logger.Error("invalid upload body", "error", err)
+		return Error(domain.ErrRequest)
+	}
+
+	if err := request.Validate(); err != nil {
+		p.logger.Error("invalid upload params", "error", err)
+		return Error(domain.ErrParams.Wrap(err))
+	}
+
+	if request.Size > int64(p.limits.MaxBytes) {
+		p.logger.Error("upload is too large", "size", request.Size)
+		return Error(domain.ErrImageTooLarge.Wrap(validation.Errors{
+			"size": fmt.Errorf("%w of %d bytes", imagex.ErrSize, p.limits.MaxBytes),
+		}))
+	}
+
+	upload, err := p.service.CreateUpload(ctx, domain.UploadParams{
+		Uuid:        uuid.New().String(),
+		ContentType: request.ContentType,
+		Size:        request.Size,
+		CreatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		p.logger.Error("error creating upload", "error", err)
+		return Error(err)
+	}
+
+	var response = UploadCreated{
+		BaseResponse: NewBaseResponse(http.StatusCreated),
+		Upload:       upload,
+	}
+
+	return JSON(response, http.StatusCreated)
+}
@@ -1,12 +1,18 @@
 package apigateway
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"io"
+	"mime"
 	"net/http"
 	"shopy/internal/domain"
 	"shopy/pkg/errorx"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -15,6 +21,29 @@ import (
 
 type APIGatewayFunc func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 
+// Routes maps "METHOD /resource" keys, without the API version prefix, to handlers.
+type Routes map[string]APIGatewayFunc
+
+func (r Routes) Router() APIGatewayFunc {
+	return func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		if handler, ok := r[event.HTTPMethod+" "+resource(event.Resource)]; ok {
+			return handler(ctx, event)
+		}
+		return events.APIGatewayProxyResponse{
+			Body:       "method is not valid",
+			StatusCode: http.StatusMethodNotAllowed,
+		}, nil
+	}
+}
+
+// resource removes the version segment from a resource such as "/v1/products/{uuid}".
+func resource(resource string) string {
+	if i := strings.Index(strings.TrimPrefix(resource, "/"), "/"); i >= 0 {
+		return resource[i+1:]
+	}
+	return resource
+}
+
 type ErrorResponse struct {
 	BaseResponse
 	Message string            `json:"message"`
@@ -44,6 +73,17 @@ func Error(err error) (events.APIGatewayProxyResponse, error) {
 			}
 		case domain.CodeNotFound:
 			response.Code = http.StatusNotFound
+		case domain.CodePreconditionFailed:
+			response.Code = http.StatusPreconditionFailed
+		case domain.CodePreconditionRequired:
+			response.Code = http.StatusPreconditionRequired
+		case domain.CodeConflict:
+			response.Code = http.StatusConflict
+		case domain.CodePayloadTooLarge:
+			response.Code = http.StatusRequestEntityTooLarge
+			if errors.As(errx, &errs) {
+				response.Errors = errs
+			}
 		}
 	}
 	return JSON(response, response.Code)
@@ -64,3 +104,141 @@ func JSON(response any, statusCode int) (events.APIGatewayProxyResponse, error)
 		},
 	}, nil
 }
+
+// Body returns the request body, decoding the base64 bodies API Gateway sends
+// for binary media types.
+func Body(event events.APIGatewayProxyRequest) ([]byte, error) {
+	if event.IsBase64Encoded {
+		return base64.StdEncoding.DecodeString(event.Body)
+	}
+	return []byte(event.Body), nil
+}
+
+// IsMultipart reports whether the request body is multipart/form-data.
+func IsMultipart(event events.APIGatewayProxyRequest) bool {
+	mediaType, _, err := mime.ParseMediaType(Header(event, "Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// ParseForm parses a multipart/form-data body into a request whose text
+// fields are read with FormValue and files with FormFile.
+func ParseForm(event events.APIGatewayProxyRequest) (*http.Request, error) {
+	body, err := Body(event)
+	if err != nil {
+		return nil, err
+	}
+
+	form, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	form.Header.Set("Content-Type", Header(event, "Content-Type"))
+
+	// the body is already in memory, its files are kept there too
+	if err = form.ParseMultipartForm(int64(len(body))); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+// FormFile reads the file part name of a parsed form, nil when it's missing.
+// At most maxBytes+1 bytes are read so oversized files are still detected.
+func FormFile(form *http.Request, name string, maxBytes int) ([]byte, error) {
+	file, _, err := form.FormFile(name)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(io.LimitReader(file, int64(maxBytes)+1))
+}
+
+// FormInt parses the integer field name of a parsed form, 0 when it's missing.
+func FormInt(form *http.Request, name string) (int64, error) {
+	value := form.FormValue(name)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// FormBool parses the boolean field name of a parsed form, false when it's
+// missing.
+func FormBool(form *http.Request, name string) (bool, error) {
+	value := form.FormValue(name)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// Header returns the value of a request header ignoring the case of its name.
+func Header(event events.APIGatewayProxyRequest, name string) string {
+	for key, value := range event.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// ETag formats an item version as a strong entity tag.
+func ETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ParseETag returns the version held by an If-Match value, the wildcard "*" is reported as -1.
+func ParseETag(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "*" {
+		return -1, nil
+	}
+
+	value = strings.TrimPrefix(value, "W/")
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(unquoted, 10, 64)
+}
+
+// JSONWithETag works like JSON and exposes the item version in the ETag header.
+func JSONWithETag(response any, statusCode int, version int64) (events.APIGatewayProxyResponse, error) {
+	result, err := JSON(response, statusCode)
+	if err != nil {
+		return result, err
+	}
+	result.Headers["ETag"] = ETag(version)
+	return result, nil
+}
+
+// Redirect answers with a permanent redirect to location.
+func Redirect(location string) (events.APIGatewayProxyResponse, error) {
+	return redirect(location, http.StatusMovedPermanently)
+}
+
+// Found answers with a temporary redirect to location.
+func Found(location string) (events.APIGatewayProxyResponse, error) {
+	return redirect(location, http.StatusFound)
+}
+
+// SeeOther points the client to location, where the result of a POST request
+// is read with GET.
+func SeeOther(location string) (events.APIGatewayProxyResponse, error) {
+	return redirect(location, http.StatusSeeOther)
+}
+
+func redirect(location string, statusCode int) (events.APIGatewayProxyResponse, error) {
+	result, err := JSON(Redirected{
+		BaseResponse: NewBaseResponse(statusCode),
+		Location:     location,
+	}, statusCode)
+	if err != nil {
+		return result, err
+	}
+	result.Headers["Location"] = location
+	return result, nil
+}
@@ -0,0 +1,32 @@
+package apigateway
+
+import "testing"
+
+func TestETag(t *testing.T) {
+	for _, version := range []int64{0, 1, 42} {
+		got, err := ParseETag(ETag(version))
+		if err != nil || got != version {
+			t.Errorf("ParseETag(ETag(%d)) = %d, %v", version, got, err)
+		}
+	}
+
+	tests := []struct {
+		ifMatch string
+		want    int64
+	}{
+		{ifMatch: `"3"`, want: 3},
+		{ifMatch: `W/"3"`, want: 3},
+		{ifMatch: " \"3\" ", want: 3},
+		{ifMatch: "*", want: -1},
+	}
+	for _, tt := range tests {
+		got, err := ParseETag(tt.ifMatch)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseETag(%q) = %d, %v, want %d", tt.ifMatch, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseETag("3"); err == nil {
+		t.Error("ParseETag of an unquoted value succeeded")
+	}
+}
@@ -0,0 +1,188 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"shopy/internal/domain"
+	"shopy/pkg/barcode"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// @Summary 	Get product variants.
+// @Description Get the variants (SKUs) of a product.
+// @Tags 		Variants
+// @Router 		/products/{uuid}/variants [get]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Success     200	{object} SelectedVariants "Success"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleGetVariants(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	variants, err := p.service.GetVariants(ctx, event.PathParameters["uuid"])
+	if err != nil {
+		p.logger.Error("error getting variants", "error", err)
+		return Error(err)
+	}
+
+	var response = SelectedVariants{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Variants:     variants,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
+// @Summary 	Add product variant.
+// @Description Add a variant with its own SKU, option values, QR code, image and an optional price overriding the product price. The qrcode is checked against its barcode_type, qrcode by default, and GS1 codes (ean13, upca, gtin14) are stored as GTIN-14. A qrcode held by another product or variant is rejected with 409.
+// @Tags 		Variants
+// @Router 		/products/{uuid}/variants [post]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param	    params body  VariantRequest true "Variant"
+// @Success     201	{object} VariantAdded "Success"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     413	{object} ErrorResponse "Request Entity Too Large"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     409	{object} ErrorResponse "Conflict"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleAddVariant(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	params, err := p.variantParams(event)
+	if err != nil {
+		return Error(err)
+	}
+
+	now := time.Now().UTC()
+	params.Uuid = uuid.New().String()
+	params.CreatedAt = now
+	params.UpdatedAt = now
+
+	variant, err := p.service.AddVariant(ctx, params)
+	if err != nil {
+		p.logger.Error("error adding variant", "error", err)
+		return Error(err)
+	}
+
+	var response = VariantAdded{
+		BaseResponse: NewBaseResponse(http.StatusCreated),
+		Variant:      variant,
+	}
+
+	return JSON(response, http.StatusCreated)
+}
+
+// @Summary 	Update product variant.
+// @Description Update variant fields, the image is kept when none is sent. The qrcode is checked against its barcode_type like on creation. A qrcode held by another product or variant is rejected with 409.
+// @Tags 		Variants
+// @Router 		/products/{uuid}/variants/{variant_uuid} [put]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param       variant_uuid path string true "Variant UUID"
+// @Param	    params body  VariantRequest true "Variant"
+// @Success     200	{object} VariantAdded "Success"
+// @Failure     400	{object} ErrorResponse "Bad Request"
+// @Failure     413	{object} ErrorResponse "Request Entity Too Large"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     409	{object} ErrorResponse "Conflict"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandlePutVariant(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	params, err := p.variantParams(event)
+	if err != nil {
+		return Error(err)
+	}
+
+	params.Uuid = event.PathParameters["variant_uuid"]
+	params.UpdatedAt = time.Now().UTC()
+
+	variant, err := p.service.PutVariant(ctx, params)
+	if err != nil {
+		p.logger.Error("error updating variant", "error", err)
+		return Error(err)
+	}
+
+	var response = VariantAdded{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Variant:      variant,
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
+// @Summary 	Delete product variant.
+// @Description Delete variant and image.
+// @Tags 		Variants
+// @Router 		/products/{uuid}/variants/{variant_uuid} [delete]
+// @Accept 		json
+// @Produce 	json
+// @Security    JWT
+// @Param       uuid path string true "Product UUID"
+// @Param       variant_uuid path string true "Variant UUID"
+// @Success     200	{object} VariantDeleted "Success"
+// @Failure     401	{object} ErrorResponse "Unauthorized"
+// @Failure     404	{object} ErrorResponse "Not Found"
+// @Failure     500	{object} ErrorResponse "Error Internal Server"
+func (p *Product) HandleDelVariant(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	err := p.service.DelVariant(ctx, event.PathParameters["uuid"], event.PathParameters["variant_uuid"])
+	if err != nil {
+		p.logger.Error("error deleting variant", "error", err)
+		return Error(err)
+	}
+
+	var response = VariantDeleted{
+		BaseResponse: NewBaseResponse(http.StatusOK),
+		Variant:      "deleted",
+	}
+
+	return JSON(response, http.StatusOK)
+}
+
+// variantParams decodes and validates the variant sent in the request body.
+func (p *Product) variantParams(event events.APIGatewayProxyRequest) (domain.VariantParams, error) {
+	var request VariantRequest
+
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		p.logger.Error("invalid variant body", "error", err)
+		return domain.VariantParams{}, domain.ErrRequest
+	}
+
+	if err := request.Validate(); err != nil {
+		p.logger.Error("invalid variant params", "error", err)
+		return domain.VariantParams{}, domain.ErrParams.Wrap(err)
+	}
+
+	params := domain.VariantParams{
+		ProductUuid: event.PathParameters["uuid"],
+		Sku:         request.Sku,
+		Options:     request.Options,
+		QRCode:      request.QRCode,
+		BarcodeType: barcode.Type(request.BarcodeType),
+		Upload:      request.UploadUuid,
+	}
+
+	if request.Price != nil {
+		price := request.Price.Money()
+		params.Price = &price
+	}
+
+	if request.Image != "" {
+		image, err := p.decodeImage(request.Image)
+		if err != nil {
+			return domain.VariantParams{}, err
+		}
+		params.Image = image
+	}
+
+	return params, nil
+}
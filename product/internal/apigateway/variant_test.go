@@ -0,0 +1,42 @@
+package apigateway
+
+import (
+	"errors"
+	"shopy/pkg/barcode"
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func TestVariantRequestBarcode(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		kind    string
+		wantErr error
+	}{
+		{name: "UPC-A code", code: "036000291452", kind: "upca"},
+		{name: "UPC-A code with a wrong check digit", code: "036000291453", kind: "upca", wantErr: barcode.ErrCheckDigit},
+		{name: "EAN-13 code of 12 digits", code: "036000291452", kind: "ean13", wantErr: barcode.ErrEAN13},
+		{name: "untyped code", code: "LATTE1L"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := VariantRequest{
+				Sku:         "LATTE-1L",
+				Options:     map[string]string{"size": "1l"},
+				QRCode:      tt.code,
+				BarcodeType: tt.kind,
+			}
+
+			var got error
+			var errs validation.Errors
+			if errors.As(request.Validate(), &errs) {
+				got = errs["qrcode"]
+			}
+			if !errors.Is(got, tt.wantErr) {
+				t.Errorf("Validate() qrcode error = %v, want %v", got, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package domain
+
+// MaxBatch is the most products looked up by a single batch request.
+const MaxBatch = 100
+
+// BatchKey identifies a product looked up in a batch by either its UUID or a
+// QR code of the product or of one of its variants.
+type BatchKey struct {
+	Uuid   string
+	QRCode string
+}
+
+type BatchParams struct {
+	Keys  []BatchKey
+	Staff bool
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// ImageFolder holds the renditions stored under the content hash of their
+// upload.
+const ImageFolder = "images"
+
+// ContentHash returns the hash of an object stored in the image folder.
+func ContentHash(key string) (string, bool) {
+	hash, _, ok := strings.Cut(strings.TrimPrefix(key, ImageFolder+"/"), "/")
+	return hash, ok && strings.HasPrefix(key, ImageFolder+"/")
+}
+
+// StoredImage is an object found under one of the image folders.
+type StoredImage struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// CollectParams selects the unreferenced images to delete, only those stored
+// before Before are collected so images being written are left alone.
+type CollectParams struct {
+	Before time.Time
+	DryRun bool
+}
@@ -5,10 +5,29 @@ import "shopy/pkg/errorx"
 const (
 	CodeBadRequest errorx.Code = iota
 	CodeNotFound
+	CodePreconditionFailed
+	CodePreconditionRequired
+	CodeConflict
+	CodePayloadTooLarge
 )
 
 var (
 	ErrRequest  = errorx.NewErrorf(CodeBadRequest, "invalid body request")
 	ErrParams   = errorx.NewErrorf(CodeBadRequest, "invalid params")
 	ErrNotFound = errorx.NewErrorf(CodeNotFound, "item not found")
+	ErrCategory = errorx.NewErrorf(CodeBadRequest, "category not found")
+	ErrSlug     = errorx.NewErrorf(CodeConflict, "slug is already in use")
+	ErrQRCode   = errorx.NewErrorf(CodeConflict, "qrcode is already in use")
+	ErrNoQRCode = errorx.NewErrorf(CodeNotFound, "product has no qrcode")
+
+	ErrImageOrder    = errorx.NewErrorf(CodeBadRequest, "images must list every image of the product once")
+	ErrImage         = errorx.NewErrorf(CodeBadRequest, "invalid image")
+	ErrImageTooLarge = errorx.NewErrorf(CodePayloadTooLarge, "image is too large")
+	ErrUpload        = errorx.NewErrorf(CodeBadRequest, "upload not found")
+
+	ErrVersionMismatch = errorx.NewErrorf(CodePreconditionFailed, "item has been modified")
+	ErrVersionRequired = errorx.NewErrorf(CodePreconditionRequired, "If-Match header is required")
+
+	ErrTransition        = errorx.NewErrorf(CodeConflict, "status transition is not allowed")
+	ErrInsufficientStock = errorx.NewErrorf(CodeConflict, "insufficient stock")
 )
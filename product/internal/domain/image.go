@@ -0,0 +1,201 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"shopy/pkg/imagex"
+	"time"
+)
+
+type Image struct {
+	Uuid       string
+	Hash       string
+	Location   string
+	Alt        string
+	Primary    bool
+	Renditions Renditions
+}
+
+// Rendition is a resized copy of an uploaded image in one format.
+type Rendition struct {
+	Width    int
+	Format   string
+	Location string
+}
+
+type Renditions []Rendition
+
+// Location returns the URL of the widest JPEG rendition, used wherever a
+// single image URL is expected.
+func (r Renditions) Location() string {
+	var widest Rendition
+	for _, rendition := range r {
+		if rendition.Format == string(imagex.FormatJPEG) && rendition.Width >= widest.Width {
+			widest = rendition
+		}
+	}
+	return widest.Location
+}
+
+// Locations returns the URL of every stored copy of the image.
+func (i Image) Locations() []string {
+	if len(i.Renditions) == 0 {
+		// images uploaded before renditions were introduced
+		return []string{i.Location}
+	}
+	locations := make([]string, len(i.Renditions))
+	for n, rendition := range i.Renditions {
+		locations[n] = rendition.Location
+	}
+	return locations
+}
+
+// Images is the ordered gallery of a product, at most one image is primary.
+type Images []Image
+
+// ImageHash returns the hex encoded SHA-256 of an uploaded image, its
+// renditions are stored under this hash so identical uploads share them.
+func ImageHash(image []byte) string {
+	sum := sha256.Sum256(image)
+	return hex.EncodeToString(sum[:])
+}
+
+// Primary returns the primary image of the gallery.
+func (i Images) Primary() (Image, bool) {
+	for _, image := range i {
+		if image.Primary {
+			return image, true
+		}
+	}
+	return Image{}, false
+}
+
+// Add appends an image to the gallery, the first image is always primary
+// and a new primary image demotes the current one.
+func (i Images) Add(image Image) Images {
+	image.Primary = image.Primary || len(i) == 0
+	images := make(Images, 0, len(i)+1)
+	for _, current := range i {
+		current.Primary = current.Primary && !image.Primary
+		images = append(images, current)
+	}
+	return append(images, image)
+}
+
+// ReplacePrimary puts image in place of the primary image, or at the front
+// of the gallery when there is none, returning the image it replaced.
+func (i Images) ReplacePrimary(image Image) (Images, *Image) {
+	image.Primary = true
+	images := make(Images, len(i))
+	copy(images, i)
+	for n, current := range images {
+		if current.Primary {
+			images[n] = image
+			return images, &current
+		}
+	}
+	return append(Images{image}, images...), nil
+}
+
+// Remove deletes an image from the gallery, returning it. When the primary
+// image is removed the first remaining image is promoted.
+func (i Images) Remove(uuid string) (Images, *Image) {
+	var (
+		removed *Image
+		images  = make(Images, 0, len(i))
+	)
+	for _, current := range i {
+		if current.Uuid == uuid {
+			image := current
+			removed = &image
+			continue
+		}
+		images = append(images, current)
+	}
+	if removed == nil {
+		return i, nil
+	}
+	if _, ok := images.Primary(); !ok && len(images) > 0 {
+		images[0].Primary = true
+	}
+	return images, removed
+}
+
+// Update changes the alt text of an image and promotes it to primary when
+// requested, it reports whether the image belongs to the gallery.
+func (i Images) Update(uuid string, alt *string, primary bool) (Images, bool) {
+	var (
+		found  bool
+		images = make(Images, len(i))
+	)
+	for n, current := range i {
+		if current.Uuid == uuid {
+			found = true
+			if alt != nil {
+				current.Alt = *alt
+			}
+			current.Primary = current.Primary || primary
+		} else if primary {
+			current.Primary = false
+		}
+		images[n] = current
+	}
+	return images, found
+}
+
+// Reorder sorts the gallery following uuids, which must list every image once.
+func (i Images) Reorder(uuids []string) (Images, error) {
+	if len(uuids) != len(i) {
+		return nil, ErrImageOrder
+	}
+
+	byUuid := make(map[string]Image, len(i))
+	for _, image := range i {
+		byUuid[image.Uuid] = image
+	}
+
+	images := make(Images, 0, len(i))
+	for _, uuid := range uuids {
+		image, ok := byUuid[uuid]
+		if !ok {
+			return nil, ErrImageOrder
+		}
+		delete(byUuid, uuid)
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+type ImageParams struct {
+	ProductUuid string
+	Uuid        string
+	Image       []byte
+	Upload      string
+	Alt         string
+	Primary     bool
+	UpdatedAt   time.Time
+}
+
+// ImagePatch changes the alt text of an image when set and makes it the
+// primary image when Primary is true.
+type ImagePatch struct {
+	ProductUuid string
+	Uuid        string
+	Alt         *string
+	Primary     bool
+	UpdatedAt   time.Time
+}
+
+type ImageOrder struct {
+	ProductUuid string
+	Uuids       []string
+	UpdatedAt   time.Time
+}
+
+// GalleryParams replaces the gallery of a product at the given version.
+type GalleryParams struct {
+	Uuid      string
+	Images    Images
+	Version   int64
+	UpdatedAt time.Time
+}
@@ -0,0 +1,63 @@
+package domain
+
+import "time"
+
+type Adjustment string
+
+const (
+	AdjustmentReceive Adjustment = "receive"
+	AdjustmentSell    Adjustment = "sell"
+	AdjustmentCorrect Adjustment = "correct"
+
+	// AdjustmentTransfer is only recorded by transfers between locations.
+	AdjustmentTransfer Adjustment = "transfer"
+)
+
+// reasons lists the reason codes accepted by each kind of adjustment.
+var reasons = map[Adjustment][]string{
+	AdjustmentReceive:  {"purchase", "return", "production"},
+	AdjustmentSell:     {"sale", "sample"},
+	AdjustmentCorrect:  {"count", "damaged", "lost", "found"},
+	AdjustmentTransfer: {"transfer_out", "transfer_in"},
+}
+
+// Reasons returns the reason codes accepted by the adjustment.
+func (a Adjustment) Reasons() []string {
+	return reasons[a]
+}
+
+// Delta returns the signed change in stock for a quantity: receiving adds
+// stock, selling removes it and corrections carry their own sign.
+func (a Adjustment) Delta(quantity int64) int64 {
+	if a == AdjustmentSell {
+		return -quantity
+	}
+	return quantity
+}
+
+type StockParams struct {
+	Uuid        string
+	ProductUuid string
+	VariantUuid string
+	Location    string
+	Adjustment  Adjustment
+	Quantity    int64
+	Reason      string
+	Note        string
+	CreatedAt   time.Time
+}
+
+// TransferParams moves stock between two locations, OutUuid and InUuid
+// identify the paired ledger entries recorded for the transfer.
+type TransferParams struct {
+	Uuid        string
+	OutUuid     string
+	InUuid      string
+	ProductUuid string
+	VariantUuid string
+	From        string
+	To          string
+	Quantity    int64
+	Note        string
+	CreatedAt   time.Time
+}
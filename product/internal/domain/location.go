@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+type LocationKind string
+
+const (
+	LocationStore     LocationKind = "store"
+	LocationWarehouse LocationKind = "warehouse"
+)
+
+type LocationParams struct {
+	Uuid      string
+	Name      string
+	Kind      LocationKind
+	Address   string
+	Pickup    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -1,28 +1,95 @@
 package domain
 
 import (
+	"shopy/pkg/barcode"
+	"shopy/pkg/money"
+	"sort"
 	"strconv"
 	"time"
 )
 
 type ProductParams struct {
-	Uuid      string
-	Name      string
-	Price     float64
-	QRCode    string
-	IsTop     bool
-	Location  string
-	Category  Category
-	Image     []byte
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	Uuid        string
+	Name        string
+	Slug        string
+	Price       money.Money
+	QRCode      string
+	BarcodeType barcode.Type
+	IsTop       bool
+	Category    Category
+	Descendants bool
+	Image       []byte
+	ImageUuid   string
+	Upload      string
+	Images      Images
+	Version     int64
+	Status      Status
+	PublishAt   *time.Time
+	UnpublishAt *time.Time
+	Staff       bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
 
+// ProductPatch holds the fields of a partial update, nil fields are left untouched.
+type ProductPatch struct {
+	Uuid              string
+	Name              *string
+	Slug              *string
+	PriceAmount       *int64
+	PriceCurrency     *string
+	QRCode            *string
+	RemoveQRCode      bool
+	BarcodeType       *barcode.Type
+	IsTop             *bool
+	CategoryUuid      *string
+	CategoryName      *string
+	Image             []byte
+	ImageUuid         string
+	Upload            string
+	RemoveImage       bool
+	Images            Images
+	PublishAt         *time.Time
+	RemovePublishAt   bool
+	UnpublishAt       *time.Time
+	RemoveUnpublishAt bool
+	Version           int64
+	UpdatedAt         time.Time
+}
+
+// AnyVersion matches whatever version is stored, it comes from an "If-Match: *" header.
+const AnyVersion int64 = -1
+
 type Category struct {
 	Uuid string
 	Name string
 }
 
+// Descendants returns uuid followed by every category below it, given the
+// parent of each category.
+func Descendants(parents map[string]string, uuid string) []string {
+	children := make(map[string][]string)
+	for child, parent := range parents {
+		children[parent] = append(children[parent], child)
+	}
+
+	var (
+		descendants = []string{uuid}
+		seen        = map[string]bool{uuid: true}
+	)
+	for i := 0; i < len(descendants); i++ {
+		next := children[descendants[i]]
+		sort.Strings(next)
+		for _, child := range next {
+			if !seen[child] {
+				seen[child] = true
+				descendants = append(descendants, child)
+			}
+		}
+	}
+	return descendants
+}
+
 func Top(s string) (int, error) {
 	if s == "" {
 		// default to 0
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"shopy/pkg/label"
+	"strings"
+)
+
+// RenderFolder holds the QR code images and label sheets rendered on request.
+// They are stored under a hash of what they show, so a product change renders
+// a new object, and the bucket expires them after 30 days.
+const RenderFolder = "renders"
+
+// MaxLabels is the most products printed on a single label request.
+const MaxLabels = 100
+
+type QRCodeParams struct {
+	ProductUuid string
+	Size        int
+	Level       label.Level
+	Staff       bool
+}
+
+// RenderKey returns the storage key of a render made from parts.
+func RenderKey(extension string, parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return RenderFolder + "/" + hex.EncodeToString(sum[:]) + "." + extension
+}
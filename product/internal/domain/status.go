@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// Status is the lifecycle state of a product.
+type Status string
+
+const (
+	StatusDraft     Status = "draft"
+	StatusPublished Status = "published"
+	StatusArchived  Status = "archived"
+)
+
+// transitions lists the states a product can move to from each state.
+var transitions = map[Status][]Status{
+	StatusDraft:     {StatusPublished, StatusArchived},
+	StatusPublished: {StatusDraft, StatusArchived},
+	StatusArchived:  {StatusDraft},
+}
+
+// Sources returns the states from which a product can move to s.
+func (s Status) Sources() []Status {
+	var sources []Status
+	for from, targets := range transitions {
+		for _, to := range targets {
+			if to == s {
+				sources = append(sources, from)
+			}
+		}
+	}
+	return sources
+}
+
+// CanTransition reports whether a product in state s can move to the given state.
+func (s Status) CanTransition(to Status) bool {
+	for _, target := range transitions[s] {
+		if target == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Visible reports whether a product in state s is shown in public listings,
+// products stored before statuses were introduced have none and are live.
+func (s Status) Visible() bool {
+	return s == StatusPublished || s == ""
+}
+
+type StatusParams struct {
+	Uuid      string
+	Status    Status
+	UpdatedAt time.Time
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// UploadFolder holds the images uploaded to a presigned URL until a product,
+// variant or category references them.
+const UploadFolder = "uploads"
+
+// UploadContentTypes are the content types accepted for presigned uploads.
+var UploadContentTypes = []string{"image/png", "image/jpeg", "image/webp", "image/gif"}
+
+type UploadParams struct {
+	Uuid        string
+	ContentType string
+	Size        int64
+	CreatedAt   time.Time
+}
+
+// UploadKey returns the storage key of an upload.
+func UploadKey(uuid string) string {
+	return UploadFolder + "/" + uuid
+}
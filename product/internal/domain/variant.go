@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"shopy/pkg/barcode"
+	"shopy/pkg/money"
+	"time"
+)
+
+type VariantParams struct {
+	Uuid        string
+	ProductUuid string
+	Sku         string
+	Options     map[string]string
+	Price       *money.Money
+	QRCode      string
+	BarcodeType barcode.Type
+	Image       []byte
+	Upload      string
+	Hash        string
+	Location    string
+	Renditions  Renditions
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
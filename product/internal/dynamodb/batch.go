@@ -0,0 +1,162 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"shopy/internal/models"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// batchSize is the most keys a BatchGetItem request accepts.
+	batchSize = 100
+	// batchRetries bounds the retries of the keys DynamoDB leaves unprocessed
+	// when a table is throttled or a response grows past 16 MB.
+	batchRetries = 5
+	// batchBackoff is the wait before the first retry, doubled on each one.
+	batchBackoff = 50 * time.Millisecond
+)
+
+// GetProducts returns the products found by UUID, products in the trash are
+// left out like missing ones.
+func (p *Product) GetProducts(ctx context.Context, uuids []string) (map[string]*models.Product, error) {
+	keys := make([]map[string]types.AttributeValue, 0, len(uuids))
+	for _, uuid := range unique(uuids) {
+		keys = append(keys, map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: uuid},
+		})
+	}
+
+	items, err := p.batchGet(ctx, p.tableName, keys, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var products []ProductTable
+	if err = attributevalue.UnmarshalListOfMaps(items, &products); err != nil {
+		return nil, fmt.Errorf("error unmarshaling items: %w", err)
+	}
+
+	found := make(map[string]*models.Product, len(products))
+	for _, product := range products {
+		if product.DeletedAt == "" {
+			found[product.Uuid] = p.assembleProduct(product)
+		}
+	}
+	return found, nil
+}
+
+// GetQRCodeOwners returns the product or variant holding each locked QR code,
+// codes nobody holds are left out.
+func (p *Product) GetQRCodeOwners(ctx context.Context, qrcodes []string) (map[string]*models.QRCodeOwner, error) {
+	keys := make([]map[string]types.AttributeValue, 0, len(qrcodes))
+	for _, qrcode := range unique(qrcodes) {
+		keys = append(keys, map[string]types.AttributeValue{
+			"qrcode": &types.AttributeValueMemberS{Value: qrcode},
+		})
+	}
+
+	items, err := p.batchGet(ctx, p.qrcodeTable, keys, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var locks []QRCodeTable
+	if err = attributevalue.UnmarshalListOfMaps(items, &locks); err != nil {
+		return nil, fmt.Errorf("error unmarshaling items: %w", err)
+	}
+
+	owners := make(map[string]*models.QRCodeOwner, len(locks))
+	for _, lock := range locks {
+		owners[lock.QRCode] = assembleQRCodeOwner(lock)
+	}
+	return owners, nil
+}
+
+// batchGet reads the items of keys from a table with concurrent BatchGetItem
+// requests of up to batchSize keys. The keys left unprocessed are requested
+// again with exponential backoff.
+func (p *Product) batchGet(ctx context.Context, table string, keys []map[string]types.AttributeValue, consistent bool) ([]map[string]types.AttributeValue, error) {
+	var (
+		chunks = (len(keys) + batchSize - 1) / batchSize
+		items  = make([][]map[string]types.AttributeValue, chunks)
+		errs   = make([]error, chunks)
+		wg     sync.WaitGroup
+	)
+	for i := 0; i < chunks; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			chunk := keys[i*batchSize : min((i+1)*batchSize, len(keys))]
+			items[i], errs[i] = p.batchGetChunk(ctx, table, chunk, consistent)
+		}(i)
+	}
+	wg.Wait()
+
+	var all []map[string]types.AttributeValue
+	for i := range items {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		all = append(all, items[i]...)
+	}
+	return all, nil
+}
+
+func (p *Product) batchGetChunk(ctx context.Context, table string, keys []map[string]types.AttributeValue, consistent bool) ([]map[string]types.AttributeValue, error) {
+	var (
+		items   []map[string]types.AttributeValue
+		request = map[string]types.KeysAndAttributes{
+			table: {
+				Keys:           keys,
+				ConsistentRead: aws.Bool(consistent),
+			},
+		}
+	)
+	for retry := 0; ; retry++ {
+		result, err := p.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: request,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error getting items: %w", err)
+		}
+
+		items = append(items, result.Responses[table]...)
+		if len(result.UnprocessedKeys) == 0 {
+			return items, nil
+		}
+		if retry == batchRetries {
+			return nil, fmt.Errorf("error getting items: %d keys left unprocessed", len(result.UnprocessedKeys[table].Keys))
+		}
+
+		p.logger.Warn("retrying unprocessed keys", "table", table, "keys", len(result.UnprocessedKeys[table].Keys), "retry", retry+1)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(batchBackoff << retry):
+		}
+		request = result.UnprocessedKeys
+	}
+}
+
+// unique returns values without duplicates, BatchGetItem rejects a request
+// holding the same key twice.
+func unique(values []string) []string {
+	var (
+		seen   = make(map[string]bool, len(values))
+		result = make([]string, 0, len(values))
+	)
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// categoryItem holds the attributes of a category item a product refers to.
+type categoryItem struct {
+	Uuid       string `dynamodbav:"uuid"`
+	Name       string `dynamodbav:"name"`
+	ParentUuid string `dynamodbav:"parent_uuid,omitempty"`
+	DeletedAt  string `dynamodbav:"deleted_at,omitempty"`
+}
+
+// GetCategory returns a category of the category table, categories in the
+// trash are not found.
+func (p *Product) GetCategory(ctx context.Context, uuid string) (*models.Category, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(p.categoryTable),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+		ProjectionExpression: aws.String("#uuid, #name, deleted_at"),
+		ExpressionAttributeNames: map[string]string{
+			"#uuid": "uuid",
+			"#name": "name",
+		},
+	}
+
+	result, err := p.client.GetItem(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error getting item: %w", err)
+	}
+
+	if len(result.Item) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	var category categoryItem
+	if err = attributevalue.UnmarshalMap(result.Item, &category); err != nil {
+		return nil, fmt.Errorf("error unmarshaling item: %w", err)
+	}
+
+	if category.DeletedAt != "" {
+		return nil, domain.ErrNotFound
+	}
+
+	return &models.Category{
+		Uuid: category.Uuid,
+		Name: category.Name,
+	}, nil
+}
+
+// GetCategoryParents returns the parent of every category outside the trash,
+// top level categories have an empty parent.
+func (p *Product) GetCategoryParents(ctx context.Context) (map[string]string, error) {
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String(p.categoryTable),
+		ProjectionExpression: aws.String("#uuid, parent_uuid, deleted_at"),
+		ExpressionAttributeNames: map[string]string{
+			"#uuid": "uuid",
+		},
+	}
+
+	parents := make(map[string]string)
+	paginator := dynamodb.NewScanPaginator(p.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error executing scan: %w", err)
+		}
+
+		var categories []categoryItem
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &categories); err != nil {
+			return nil, fmt.Errorf("error unmarshaling items: %w", err)
+		}
+		for _, category := range categories {
+			if category.DeletedAt == "" {
+				parents[category.Uuid] = category.ParentUuid
+			}
+		}
+	}
+
+	return parents, nil
+}
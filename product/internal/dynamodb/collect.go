@@ -0,0 +1,108 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// imageItem holds the image attributes shared by products, variants and
+// categories, categories store the rendition URL as url instead of location.
+type imageItem struct {
+	Image      string       `dynamodbav:"image"`
+	Images     []ImageTable `dynamodbav:"images"`
+	Renditions []struct {
+		Location string `dynamodbav:"location"`
+		Url      string `dynamodbav:"url"`
+	} `dynamodbav:"renditions"`
+}
+
+// GetImageLocations returns the URL of every image stored by the products,
+// trashed ones included, their variants and the categories.
+func (p *Product) GetImageLocations(ctx context.Context) ([]string, error) {
+	var locations []string
+	for _, table := range []string{p.tableName, p.variantTable, p.categoryTable} {
+		paginator := dynamodb.NewScanPaginator(p.client, &dynamodb.ScanInput{
+			TableName:            aws.String(table),
+			ProjectionExpression: aws.String("#image, #images, #renditions"),
+			ExpressionAttributeNames: map[string]string{
+				"#image":      "image",
+				"#images":     "images",
+				"#renditions": "renditions",
+			},
+		})
+
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("error scanning %s: %w", table, err)
+			}
+
+			for _, item := range page.Items {
+				var images imageItem
+				if err = attributevalue.UnmarshalMap(item, &images); err != nil {
+					return nil, fmt.Errorf("error unmarshaling item: %w", err)
+				}
+				locations = append(locations, images.locations()...)
+			}
+		}
+	}
+
+	return locations, nil
+}
+
+func (i imageItem) locations() []string {
+	var locations []string
+	if i.Image != "" {
+		locations = append(locations, i.Image)
+	}
+	for _, image := range i.Images {
+		locations = append(locations, image.Location)
+		for _, rendition := range image.Renditions {
+			locations = append(locations, rendition.Location)
+		}
+	}
+	for _, rendition := range i.Renditions {
+		if rendition.Location != "" {
+			locations = append(locations, rendition.Location)
+		}
+		if rendition.Url != "" {
+			locations = append(locations, rendition.Url)
+		}
+	}
+	return locations
+}
+
+// DelImageReference deletes the reference record of a hash no image points
+// at, unless it was updated after before by an upload in progress. It
+// reports whether the renditions can be deleted.
+func (p *Product) DelImageReference(ctx context.Context, hash string, before time.Time) (bool, error) {
+	_, err := p.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(p.imageTable),
+		Key: map[string]types.AttributeValue{
+			"hash": &types.AttributeValueMemberS{Value: hash},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#hash) OR updated_at < :before"),
+		ExpressionAttributeNames: map[string]string{
+			"#hash": "hash",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":before": &types.AttributeValueMemberS{Value: before.Format(time.DateTime)},
+		},
+	})
+	if err != nil {
+		var errf *types.ConditionalCheckFailedException
+		if errors.As(err, &errf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error deleting item: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,183 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// updateCounted runs an update that may move a product in or out of the
+// product count of a category: counted returns the categories it leaves and
+// joins given the product as it is stored, and related, which may be nil, the
+// other writes that must go with the update such as slug guards and QR code
+// locks. When the categories differ or there are other writes, the update
+// runs in a transaction with them and the product is read back, since
+// transactions don't return the written item. Either way the update only
+// applies while the product still has the category and QR code it was read
+// with.
+func (p *Product) updateCounted(ctx context.Context, input *dynamodb.UpdateItemInput, counted func(current ProductTable) (from, to string), related func(current ProductTable) ([]transactWrite, error)) (*models.Product, error) {
+	result, err := p.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(p.tableName),
+		Key:                  input.Key,
+		ProjectionExpression: aws.String("category_uuid, qrcode, deleted_at"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting item: %w", err)
+	}
+	if len(result.Item) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	var current ProductTable
+	if err = attributevalue.UnmarshalMap(result.Item, &current); err != nil {
+		return nil, fmt.Errorf("error unmarshaling item: %w", err)
+	}
+
+	input.ConditionExpression = aws.String(aws.ToString(input.ConditionExpression) + " AND category_uuid = :current_category AND " + qrcodeCondition(current.QRCode, input.ExpressionAttributeValues))
+	input.ExpressionAttributeValues[":current_category"] = &types.AttributeValueMemberS{Value: current.CategoryUuid}
+
+	var writes []transactWrite
+	if related != nil {
+		writes, err = related(current)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	from, to := counted(current)
+	if from == to && len(writes) == 0 {
+		return p.updateProduct(ctx, input)
+	}
+
+	var counts []transactWrite
+	if from != to {
+		counts, err = p.countWrites(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	update := transactWrite{
+		item: types.TransactWriteItem{
+			Update: &types.Update{
+				TableName:                           input.TableName,
+				Key:                                 input.Key,
+				UpdateExpression:                    input.UpdateExpression,
+				ConditionExpression:                 input.ConditionExpression,
+				ExpressionAttributeNames:            input.ExpressionAttributeNames,
+				ExpressionAttributeValues:           input.ExpressionAttributeValues,
+				ReturnValuesOnConditionCheckFailure: input.ReturnValuesOnConditionCheckFailure,
+			},
+		},
+		failed: func(reason types.CancellationReason) error {
+			if _, deleted := reason.Item["deleted_at"]; len(reason.Item) == 0 || deleted {
+				return domain.ErrNotFound
+			}
+			return domain.ErrVersionMismatch
+		},
+	}
+	if err = p.writeTransaction(ctx, append(append([]transactWrite{update}, counts...), writes...), "updating item"); err != nil {
+		return nil, err
+	}
+
+	result, err = p.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(p.tableName),
+		Key:            input.Key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting item: %w", err)
+	}
+
+	var product ProductTable
+	if err = attributevalue.UnmarshalMap(result.Item, &product); err != nil {
+		return nil, fmt.Errorf("error unmarshaling item: %w", err)
+	}
+
+	return p.assembleProduct(product), nil
+}
+
+// countWrites returns the writes moving a product from the count of a
+// category to another, either may be empty. The category left is skipped when
+// it doesn't exist, as for products stored before categories were checked.
+func (p *Product) countWrites(ctx context.Context, from, to string) ([]transactWrite, error) {
+	var writes []transactWrite
+	if from != "" {
+		result, err := p.client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(p.categoryTable),
+			Key: map[string]types.AttributeValue{
+				"uuid": &types.AttributeValueMemberS{Value: from},
+			},
+			ProjectionExpression: aws.String("#uuid"),
+			ExpressionAttributeNames: map[string]string{
+				"#uuid": "uuid",
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error getting item: %w", err)
+		}
+		if len(result.Item) > 0 {
+			writes = append(writes, p.categoryCount(from, -1, "attribute_exists(#uuid)"))
+		}
+	}
+	if to != "" {
+		writes = append(writes, p.categoryCount(to, 1, "attribute_exists(#uuid) AND "+notDeleted))
+	}
+	return writes, nil
+}
+
+// categoryCheck checks in a transaction that a category exists outside the
+// trash, for product writes keeping their category. The category trash only
+// applies without products, so a product written with the category holds it.
+func (p *Product) categoryCheck(uuid string) transactWrite {
+	return transactWrite{
+		item: types.TransactWriteItem{
+			ConditionCheck: &types.ConditionCheck{
+				TableName: aws.String(p.categoryTable),
+				Key: map[string]types.AttributeValue{
+					"uuid": &types.AttributeValueMemberS{Value: uuid},
+				},
+				ConditionExpression: aws.String("attribute_exists(#uuid) AND " + notDeleted),
+				ExpressionAttributeNames: map[string]string{
+					"#uuid": "uuid",
+				},
+			},
+		},
+		failed: func(types.CancellationReason) error {
+			return domain.ErrCategory
+		},
+	}
+}
+
+// categoryCount adds delta to the product count of a category under
+// condition, so a count is never written to a category that is gone.
+func (p *Product) categoryCount(uuid string, delta int, condition string) transactWrite {
+	return transactWrite{
+		item: types.TransactWriteItem{
+			Update: &types.Update{
+				TableName: aws.String(p.categoryTable),
+				Key: map[string]types.AttributeValue{
+					"uuid": &types.AttributeValueMemberS{Value: uuid},
+				},
+				UpdateExpression:    aws.String("ADD product_count :delta"),
+				ConditionExpression: aws.String(condition),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":delta": &types.AttributeValueMemberN{Value: strconv.Itoa(delta)},
+				},
+				ExpressionAttributeNames: map[string]string{
+					"#uuid": "uuid",
+				},
+			},
+		},
+		failed: func(types.CancellationReason) error {
+			return domain.ErrCategory
+		},
+	}
+}
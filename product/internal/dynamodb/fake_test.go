@@ -0,0 +1,330 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// item is an item in the DynamoDB JSON wire format, {"uuid": {"S": "..."}}.
+type item map[string]map[string]any
+
+// fakeDynamo serves the DynamoDB calls the repository makes from memory. It
+// keys items by their "uuid" or "qrcode" attribute and evaluates only the
+// version, existence, trash and QR code lock parts of condition expressions,
+// enough to follow an item through its versions, categories through the
+// trash and codes through their locks.
+type fakeDynamo struct {
+	mu    sync.Mutex
+	items map[string]map[string]item
+	// pages splits the scans into pages of this many items, 0 for one page.
+	pages int
+	calls []string
+}
+
+func newFakeDynamo() *fakeDynamo {
+	return &fakeDynamo{items: make(map[string]map[string]item)}
+}
+
+// repository returns a product repository talking to the fake.
+func (f *fakeDynamo) repository(t *testing.T) *Product {
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	client := dynamodb.New(dynamodb.Options{
+		BaseEndpoint: aws.String(server.URL),
+		Region:       "us-east-1",
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	return NewProduct(slog.New(slog.NewTextHandler(io.Discard, nil)), client)
+}
+
+func (f *fakeDynamo) put(table string, it item) {
+	if f.items[table] == nil {
+		f.items[table] = make(map[string]item)
+	}
+	f.items[table][key(it)] = it
+}
+
+func key(it item) string {
+	for _, name := range []string{"uuid", "qrcode", "slug"} {
+		if value, ok := it[name]; ok {
+			return value["S"].(string)
+		}
+	}
+	return ""
+}
+
+func (f *fakeDynamo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.")
+	f.calls = append(f.calls, operation)
+
+	var request map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var response any
+	switch operation {
+	case "GetItem":
+		response = f.getItem(request)
+	case "UpdateItem":
+		var ok bool
+		if response, ok = f.updateItem(request); !ok {
+			conditionFailed(w, f.getItem(request))
+			return
+		}
+	case "TransactWriteItems":
+		f.transactWriteItems(w, request)
+		return
+	case "Scan", "Query":
+		response = f.scan(request)
+	default:
+		response = struct{}{}
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(response)
+}
+
+// conditionFailed answers with the stored item, as for requests returning
+// ALL_OLD values on condition check failure.
+func conditionFailed(w http.ResponseWriter, stored any) {
+	response := map[string]any{
+		"__type":  "com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException",
+		"message": "The conditional request failed",
+	}
+	if found, ok := stored.(map[string]item); ok {
+		response["Item"] = found["Item"]
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeDynamo) getItem(request map[string]json.RawMessage) any {
+	var (
+		table string
+		k     item
+	)
+	json.Unmarshal(request["TableName"], &table)
+	json.Unmarshal(request["Key"], &k)
+
+	if it, ok := f.items[table][key(k)]; ok {
+		return map[string]item{"Item": it}
+	}
+	return struct{}{}
+}
+
+// update is an Update of UpdateItem or of a transaction.
+type update struct {
+	TableName                           string
+	Key                                 item
+	UpdateExpression                    string
+	ConditionExpression                 string
+	ExpressionAttributeValues           item
+	ReturnValuesOnConditionCheckFailure string
+}
+
+func (f *fakeDynamo) updateItem(request map[string]json.RawMessage) (any, bool) {
+	var u update
+	json.Unmarshal(request["TableName"], &u.TableName)
+	json.Unmarshal(request["Key"], &u.Key)
+	json.Unmarshal(request["UpdateExpression"], &u.UpdateExpression)
+	json.Unmarshal(request["ConditionExpression"], &u.ConditionExpression)
+	json.Unmarshal(request["ExpressionAttributeValues"], &u.ExpressionAttributeValues)
+
+	updated, ok := f.updated(u)
+	if !ok {
+		return nil, false
+	}
+	f.put(u.TableName, updated)
+	return map[string]item{"Attributes": updated}, true
+}
+
+// updated checks the condition of an update of a stored item and returns the
+// item with its version bumped and the plain "name = :value" and REMOVE
+// clauses applied, the other clauses of the update expression are not.
+func (f *fakeDynamo) updated(u update) (item, bool) {
+	it, ok := f.items[u.TableName][key(u.Key)]
+	if !ok || !holds(u.ConditionExpression, u.ExpressionAttributeValues, it) {
+		return nil, false
+	}
+
+	updated := make(item, len(it))
+	for name, value := range it {
+		updated[name] = value
+	}
+	set, remove, _ := strings.Cut(strings.TrimPrefix(u.UpdateExpression, "SET "), " REMOVE ")
+	for _, clause := range strings.Split(set, ", ") {
+		name, value, _ := strings.Cut(clause, " = ")
+		if stored, ok := u.ExpressionAttributeValues[value]; ok && !strings.HasPrefix(name, "#") {
+			updated[name] = stored
+		}
+	}
+	for _, name := range strings.Split(remove, ", ") {
+		delete(updated, name)
+	}
+	if strings.Contains(u.UpdateExpression, "#version") {
+		var version int
+		if stored, ok := it["version"]; ok {
+			version, _ = strconv.Atoi(stored["N"].(string))
+		}
+		updated["version"] = map[string]any{"N": strconv.Itoa(version + 1)}
+	}
+	return updated, true
+}
+
+func holds(condition string, values, it item) bool {
+	if _, deleted := it["deleted_at"]; deleted && strings.Contains(condition, notDeleted) {
+		return false
+	}
+	return versionHolds(condition, values, it) && lockHolds(condition, values, it)
+}
+
+// lockHolds checks the holder part of a QR code lock condition.
+func lockHolds(condition string, values, it item) bool {
+	variant, isVariant := it["variant_uuid"]
+	switch {
+	case strings.Contains(condition, "attribute_not_exists(variant_uuid)"):
+		return it["product_uuid"]["S"] == values[":uuid"]["S"] && !isVariant
+	case strings.Contains(condition, "variant_uuid = :variant_uuid"):
+		return it["product_uuid"]["S"] == values[":uuid"]["S"] && isVariant && variant["S"] == values[":variant_uuid"]["S"]
+	}
+	return true
+}
+
+func versionHolds(condition string, values, it item) bool {
+	stored, exists := it["version"]
+	equal := exists && values[":version"] != nil && stored["N"] == values[":version"]["N"]
+	switch {
+	case strings.Contains(condition, "attribute_not_exists(#version) OR #version = :version"):
+		return !exists || equal
+	case strings.Contains(condition, "attribute_not_exists(#version)"):
+		return !exists
+	case strings.Contains(condition, "#version = :version"):
+		return equal
+	}
+	return true
+}
+
+// transactWriteItems checks the conditions of the writes, then stores the
+// puts and updates and removes the deletes. The conditions of puts are only
+// checked against a stored lock.
+func (f *fakeDynamo) transactWriteItems(w http.ResponseWriter, request map[string]json.RawMessage) {
+	var items []struct {
+		Put *struct {
+			TableName                 string
+			Item                      item
+			ConditionExpression       string
+			ExpressionAttributeValues item
+		}
+		Update         *update
+		ConditionCheck *update
+		Delete         *update
+	}
+	json.Unmarshal(request["TransactItems"], &items)
+
+	var (
+		reasons  = make([]map[string]any, len(items))
+		updates  = make([]item, len(items))
+		canceled bool
+	)
+	for i, write := range items {
+		reasons[i] = map[string]any{"Code": "None"}
+
+		ok := true
+		switch {
+		case write.Update != nil:
+			updates[i], ok = f.updated(*write.Update)
+		case write.ConditionCheck != nil:
+			_, ok = f.updated(*write.ConditionCheck)
+		case write.Delete != nil:
+			_, ok = f.updated(*write.Delete)
+		case write.Put != nil && strings.HasPrefix(write.Put.ConditionExpression, "attribute_not_exists(qrcode) OR "):
+			if stored, locked := f.items[write.Put.TableName][key(write.Put.Item)]; locked {
+				ok = lockHolds(write.Put.ConditionExpression, write.Put.ExpressionAttributeValues, stored)
+			}
+		}
+		if !ok {
+			reasons[i]["Code"] = "ConditionalCheckFailed"
+			for _, u := range []*update{write.Update, write.Delete} {
+				if u != nil && u.ReturnValuesOnConditionCheckFailure == "ALL_OLD" {
+					reasons[i]["Item"] = f.items[u.TableName][key(u.Key)]
+				}
+			}
+			canceled = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	if canceled {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{
+			"__type":              "com.amazonaws.dynamodb.v20120810#TransactionCanceledException",
+			"message":             "Transaction cancelled",
+			"CancellationReasons": reasons,
+		})
+		return
+	}
+
+	for i, write := range items {
+		switch {
+		case write.Put != nil:
+			f.put(write.Put.TableName, write.Put.Item)
+		case write.Update != nil:
+			f.put(write.Update.TableName, updates[i])
+		case write.Delete != nil:
+			delete(f.items[write.Delete.TableName], key(write.Delete.Key))
+		}
+	}
+	json.NewEncoder(w).Encode(struct{}{})
+}
+
+// scan returns every item of the table, in pages when pages is set.
+func (f *fakeDynamo) scan(request map[string]json.RawMessage) any {
+	var (
+		table string
+		start item
+	)
+	json.Unmarshal(request["TableName"], &table)
+	json.Unmarshal(request["ExclusiveStartKey"], &start)
+
+	var items []item
+	for _, it := range f.items[table] {
+		items = append(items, it)
+	}
+	sort.Slice(items, func(i, j int) bool { return key(items[i]) < key(items[j]) })
+
+	if start != nil {
+		for i, it := range items {
+			if key(it) == key(start) {
+				items = items[i+1:]
+				break
+			}
+		}
+	}
+
+	response := map[string]any{"Items": items, "Count": len(items)}
+	if f.pages > 0 && len(items) > f.pages {
+		response["Items"] = items[:f.pages]
+		response["Count"] = f.pages
+		response["LastEvaluatedKey"] = item{"uuid": items[f.pages-1]["uuid"]}
+	}
+	return response
+}
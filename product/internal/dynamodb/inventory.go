@@ -0,0 +1,407 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// transactWrite is an item of a transaction together with the error reported
+// when its condition fails.
+type transactWrite struct {
+	item   types.TransactWriteItem
+	failed func(reason types.CancellationReason) error
+}
+
+// AdjustStock applies the adjustment to the product or variant stock, and to
+// the stock held at the location when one is set, writing the ledger entry in
+// the same transaction. Decrements are conditioned on the current stock so it
+// never goes negative.
+func (p *Product) AdjustStock(ctx context.Context, params domain.StockParams) (*models.LedgerEntry, error) {
+	entry := LedgerTable{
+		ProductUuid:  params.ProductUuid,
+		Uuid:         params.Uuid,
+		VariantUuid:  params.VariantUuid,
+		LocationUuid: params.Location,
+		Adjustment:   string(params.Adjustment),
+		Quantity:     params.Adjustment.Delta(params.Quantity),
+		Reason:       params.Reason,
+		Note:         params.Note,
+		CreatedAt:    params.CreatedAt.Format(time.DateTime),
+	}
+
+	var writes []transactWrite
+	if params.VariantUuid != "" {
+		// the parent product must not be in the trash
+		writes = append(writes, p.productCheck(params.ProductUuid))
+	}
+	writes = append(writes, p.stockUpdate(params.ProductUuid, params.VariantUuid, entry.Quantity))
+
+	if params.Location != "" {
+		writes = append(writes,
+			p.locationCheck(params.Location),
+			p.locationStockUpdate(params.ProductUuid, params.VariantUuid, params.Location, entry.Quantity),
+		)
+	}
+
+	ledger, err := p.ledgerPut(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.writeTransaction(ctx, append(writes, ledger), "adjusting stock"); err != nil {
+		return nil, err
+	}
+
+	return assembleLedgerEntry(entry), nil
+}
+
+// TransferStock moves stock between two locations recording a pair of
+// ledger entries, the product or variant total doesn't change.
+func (p *Product) TransferStock(ctx context.Context, params domain.TransferParams) (*models.Transfer, error) {
+	var (
+		createdAt = params.CreatedAt.Format(time.DateTime)
+		out       = LedgerTable{
+			ProductUuid:  params.ProductUuid,
+			Uuid:         params.OutUuid,
+			VariantUuid:  params.VariantUuid,
+			LocationUuid: params.From,
+			TransferUuid: params.Uuid,
+			Adjustment:   string(domain.AdjustmentTransfer),
+			Quantity:     -params.Quantity,
+			Reason:       "transfer_out",
+			Note:         params.Note,
+			CreatedAt:    createdAt,
+		}
+		in = LedgerTable{
+			ProductUuid:  params.ProductUuid,
+			Uuid:         params.InUuid,
+			VariantUuid:  params.VariantUuid,
+			LocationUuid: params.To,
+			TransferUuid: params.Uuid,
+			Adjustment:   string(domain.AdjustmentTransfer),
+			Quantity:     params.Quantity,
+			Reason:       "transfer_in",
+			Note:         params.Note,
+			CreatedAt:    createdAt,
+		}
+	)
+
+	writes := []transactWrite{p.productCheck(params.ProductUuid)}
+	if params.VariantUuid != "" {
+		writes = append(writes, p.variantCheck(params.ProductUuid, params.VariantUuid))
+	}
+	writes = append(writes,
+		p.locationCheck(params.To),
+		p.locationStockUpdate(params.ProductUuid, params.VariantUuid, params.From, out.Quantity),
+		p.locationStockUpdate(params.ProductUuid, params.VariantUuid, params.To, in.Quantity),
+	)
+
+	for _, entry := range []LedgerTable{out, in} {
+		ledger, err := p.ledgerPut(entry)
+		if err != nil {
+			return nil, err
+		}
+		writes = append(writes, ledger)
+	}
+
+	if err := p.writeTransaction(ctx, writes, "transferring stock"); err != nil {
+		return nil, err
+	}
+
+	return &models.Transfer{
+		Uuid: params.Uuid,
+		Out:  assembleLedgerEntry(out),
+		In:   assembleLedgerEntry(in),
+	}, nil
+}
+
+// writeTransaction runs a transaction, mapping a cancelled transaction to the
+// error of the first item whose condition failed.
+func (p *Product) writeTransaction(ctx context.Context, writes []transactWrite, action string) error {
+	items := make([]types.TransactWriteItem, len(writes))
+	for i, write := range writes {
+		items[i] = write.item
+	}
+
+	_, err := p.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if err == nil {
+		return nil
+	}
+
+	var errf *types.TransactionCanceledException
+	if errors.As(err, &errf) {
+		for i, reason := range errf.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" && i < len(writes) && writes[i].failed != nil {
+				return writes[i].failed(reason)
+			}
+		}
+	}
+
+	return fmt.Errorf("error %s: %w", action, err)
+}
+
+// productCheck requires the product to exist and not to be in the trash.
+func (p *Product) productCheck(productUuid string) transactWrite {
+	return transactWrite{
+		item: types.TransactWriteItem{
+			ConditionCheck: &types.ConditionCheck{
+				TableName: aws.String(p.tableName),
+				Key: map[string]types.AttributeValue{
+					"uuid": &types.AttributeValueMemberS{Value: productUuid},
+				},
+				ConditionExpression: aws.String("attribute_exists(#uuid) AND " + notDeleted),
+				ExpressionAttributeNames: map[string]string{
+					"#uuid": "uuid",
+				},
+			},
+		},
+		failed: notFound,
+	}
+}
+
+func (p *Product) variantCheck(productUuid, variantUuid string) transactWrite {
+	return transactWrite{
+		item: types.TransactWriteItem{
+			ConditionCheck: &types.ConditionCheck{
+				TableName: aws.String(p.variantTable),
+				Key: map[string]types.AttributeValue{
+					"product_uuid": &types.AttributeValueMemberS{Value: productUuid},
+					"uuid":         &types.AttributeValueMemberS{Value: variantUuid},
+				},
+				ConditionExpression: aws.String("attribute_exists(#uuid)"),
+				ExpressionAttributeNames: map[string]string{
+					"#uuid": "uuid",
+				},
+			},
+		},
+		failed: notFound,
+	}
+}
+
+func (p *Product) locationCheck(locationUuid string) transactWrite {
+	return transactWrite{
+		item: types.TransactWriteItem{
+			ConditionCheck: &types.ConditionCheck{
+				TableName: aws.String(p.locationTable),
+				Key: map[string]types.AttributeValue{
+					"uuid": &types.AttributeValueMemberS{Value: locationUuid},
+				},
+				ConditionExpression: aws.String("attribute_exists(#uuid)"),
+				ExpressionAttributeNames: map[string]string{
+					"#uuid": "uuid",
+				},
+			},
+		},
+		failed: notFound,
+	}
+}
+
+// stockUpdate builds the conditional update of the product or variant stock.
+func (p *Product) stockUpdate(productUuid, variantUuid string, delta int64) transactWrite {
+	var (
+		condition = "attribute_exists(#uuid)"
+		tableName = p.tableName
+		key       = map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: productUuid},
+		}
+		expressionAttributeValues = map[string]types.AttributeValue{
+			":zero":  &types.AttributeValueMemberN{Value: "0"},
+			":delta": &types.AttributeValueMemberN{Value: strconv.FormatInt(delta, 10)},
+		}
+	)
+
+	if variantUuid != "" {
+		tableName = p.variantTable
+		key = map[string]types.AttributeValue{
+			"product_uuid": &types.AttributeValueMemberS{Value: productUuid},
+			"uuid":         &types.AttributeValueMemberS{Value: variantUuid},
+		}
+	} else {
+		condition += " AND " + notDeleted
+	}
+
+	if delta < 0 {
+		condition += " AND stock >= :required"
+		expressionAttributeValues[":required"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(-delta, 10)}
+	}
+
+	return transactWrite{
+		item: types.TransactWriteItem{
+			Update: &types.Update{
+				TableName:                 aws.String(tableName),
+				Key:                       key,
+				UpdateExpression:          aws.String("SET stock = if_not_exists(stock, :zero) + :delta"),
+				ConditionExpression:       aws.String(condition),
+				ExpressionAttributeValues: expressionAttributeValues,
+				ExpressionAttributeNames: map[string]string{
+					"#uuid": "uuid",
+				},
+				ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+			},
+		},
+		failed: stockFailed,
+	}
+}
+
+// locationStockUpdate builds the update of the stock held at a location,
+// creating the item on the first increment.
+func (p *Product) locationStockUpdate(productUuid, variantUuid, locationUuid string, delta int64) transactWrite {
+	var (
+		expression                = "SET quantity = if_not_exists(quantity, :zero) + :delta, location_uuid = :location_uuid"
+		expressionAttributeValues = map[string]types.AttributeValue{
+			":zero":          &types.AttributeValueMemberN{Value: "0"},
+			":delta":         &types.AttributeValueMemberN{Value: strconv.FormatInt(delta, 10)},
+			":location_uuid": &types.AttributeValueMemberS{Value: locationUuid},
+		}
+		update = &types.Update{
+			TableName: aws.String(p.stockTable),
+			Key: map[string]types.AttributeValue{
+				"product_uuid": &types.AttributeValueMemberS{Value: productUuid},
+				"stock_key":    &types.AttributeValueMemberS{Value: stockKey(locationUuid, variantUuid)},
+			},
+		}
+	)
+
+	if variantUuid != "" {
+		expression += ", variant_uuid = :variant_uuid"
+		expressionAttributeValues[":variant_uuid"] = &types.AttributeValueMemberS{Value: variantUuid}
+	}
+
+	if delta < 0 {
+		update.ConditionExpression = aws.String("quantity >= :required")
+		expressionAttributeValues[":required"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(-delta, 10)}
+	}
+
+	update.UpdateExpression = aws.String(expression)
+	update.ExpressionAttributeValues = expressionAttributeValues
+
+	return transactWrite{
+		item: types.TransactWriteItem{Update: update},
+		failed: func(types.CancellationReason) error {
+			return domain.ErrInsufficientStock
+		},
+	}
+}
+
+func (p *Product) ledgerPut(entry LedgerTable) (transactWrite, error) {
+	item, err := attributevalue.MarshalMap(entry)
+	if err != nil {
+		return transactWrite{}, fmt.Errorf("error marshaling item: %w", err)
+	}
+
+	return transactWrite{
+		item: types.TransactWriteItem{
+			Put: &types.Put{
+				TableName: aws.String(p.ledgerTable),
+				Item:      item,
+			},
+		},
+	}, nil
+}
+
+func notFound(types.CancellationReason) error {
+	return domain.ErrNotFound
+}
+
+// stockFailed tells a missing or deleted item apart from one that didn't
+// have enough stock.
+func stockFailed(reason types.CancellationReason) error {
+	if len(reason.Item) == 0 {
+		return domain.ErrNotFound
+	}
+	if _, ok := reason.Item["deleted_at"]; ok {
+		return domain.ErrNotFound
+	}
+	return domain.ErrInsufficientStock
+}
+
+func stockKey(locationUuid, variantUuid string) string {
+	return locationUuid + "#" + variantUuid
+}
+
+// GetStockLevels returns the stock held at each location by a product and its variants.
+func (p *Product) GetStockLevels(ctx context.Context, productUuid string) ([]*models.StockLevel, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(p.stockTable),
+		KeyConditionExpression: aws.String("product_uuid = :product_uuid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":product_uuid": &types.AttributeValueMemberS{Value: productUuid},
+		},
+	}
+
+	result, err := p.client.Query(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+
+	levels := make([]*models.StockLevel, len(result.Items))
+	for i, item := range result.Items {
+		var stock LocationStockTable
+		if err = attributevalue.UnmarshalMap(item, &stock); err != nil {
+			return nil, fmt.Errorf("error unmarshaling item: %w", err)
+		}
+		levels[i] = &models.StockLevel{
+			LocationUuid: stock.LocationUuid,
+			VariantUuid:  stock.VariantUuid,
+			Quantity:     stock.Quantity,
+		}
+	}
+
+	return levels, nil
+}
+
+// GetLedger returns the latest ledger entries of a product, newest first.
+func (p *Product) GetLedger(ctx context.Context, productUuid string, limit int32) (models.Ledger, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(p.ledgerTable),
+		KeyConditionExpression: aws.String("product_uuid = :product_uuid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":product_uuid": &types.AttributeValueMemberS{Value: productUuid},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(limit),
+	}
+
+	result, err := p.client.Query(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+
+	ledger := make(models.Ledger, len(result.Items))
+	for i, item := range result.Items {
+		var entry LedgerTable
+		if err = attributevalue.UnmarshalMap(item, &entry); err != nil {
+			return nil, fmt.Errorf("error unmarshaling item: %w", err)
+		}
+		ledger[i] = assembleLedgerEntry(entry)
+	}
+
+	return ledger, nil
+}
+
+func assembleLedgerEntry(entry LedgerTable) *models.LedgerEntry {
+	return &models.LedgerEntry{
+		Uuid:         entry.Uuid,
+		ProductUuid:  entry.ProductUuid,
+		VariantUuid:  entry.VariantUuid,
+		LocationUuid: entry.LocationUuid,
+		TransferUuid: entry.TransferUuid,
+		Adjustment:   entry.Adjustment,
+		Quantity:     entry.Quantity,
+		Reason:       entry.Reason,
+		Note:         entry.Note,
+		CreatedAt:    entry.CreatedAt,
+	}
+}
@@ -0,0 +1,118 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func (p *Product) GetLocations(ctx context.Context) (models.Locations, error) {
+	paginator := dynamodb.NewScanPaginator(p.client, &dynamodb.ScanInput{
+		TableName: aws.String(p.locationTable),
+	})
+
+	locations := models.Locations{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error executing query: %w", err)
+		}
+
+		for _, item := range page.Items {
+			var location LocationTable
+			if err = attributevalue.UnmarshalMap(item, &location); err != nil {
+				return nil, fmt.Errorf("error unmarshaling item: %w", err)
+			}
+			locations = append(locations, assembleLocation(location))
+		}
+	}
+
+	return locations, nil
+}
+
+func (p *Product) AddLocation(ctx context.Context, params domain.LocationParams) (*models.Location, error) {
+	location := LocationTable{
+		Uuid:      params.Uuid,
+		Name:      params.Name,
+		Kind:      string(params.Kind),
+		Address:   params.Address,
+		Pickup:    params.Pickup,
+		CreatedAt: params.CreatedAt.Format(time.DateTime),
+		UpdatedAt: params.UpdatedAt.Format(time.DateTime),
+	}
+
+	item, err := attributevalue.MarshalMap(location)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling item: %w", err)
+	}
+
+	_, err = p.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(p.locationTable),
+		Item:      item,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error adding item: %w", err)
+	}
+
+	return assembleLocation(location), nil
+}
+
+func (p *Product) PutLocation(ctx context.Context, params domain.LocationParams) (*models.Location, error) {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(p.locationTable),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: params.Uuid},
+		},
+		UpdateExpression:    aws.String("SET #name = :name, kind = :kind, address = :address, pickup = :pickup, updated_at = :updated_at"),
+		ConditionExpression: aws.String("attribute_exists(#uuid)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":name":       &types.AttributeValueMemberS{Value: params.Name},
+			":kind":       &types.AttributeValueMemberS{Value: string(params.Kind)},
+			":address":    &types.AttributeValueMemberS{Value: params.Address},
+			":pickup":     &types.AttributeValueMemberBOOL{Value: params.Pickup},
+			":updated_at": &types.AttributeValueMemberS{Value: params.UpdatedAt.Format(time.DateTime)},
+		},
+		ExpressionAttributeNames: map[string]string{
+			"#uuid": "uuid",
+			"#name": "name",
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	}
+
+	result, err := p.client.UpdateItem(ctx, input)
+	if err != nil {
+		var errf *types.ConditionalCheckFailedException
+		if errors.As(err, &errf) {
+			return nil, domain.ErrNotFound
+		}
+
+		return nil, fmt.Errorf("error updating item: %w", err)
+	}
+
+	var location LocationTable
+	if err = attributevalue.UnmarshalMap(result.Attributes, &location); err != nil {
+		return nil, fmt.Errorf("error marshaling item: %w", err)
+	}
+
+	return assembleLocation(location), nil
+}
+
+func assembleLocation(location LocationTable) *models.Location {
+	return &models.Location{
+		Uuid:      location.Uuid,
+		Name:      location.Name,
+		Kind:      location.Kind,
+		Address:   location.Address,
+		Pickup:    location.Pickup,
+		CreatedAt: location.CreatedAt,
+		UpdatedAt: location.UpdatedAt,
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 	"shopy/internal/domain"
 	"shopy/internal/models"
+	"shopy/pkg/barcode"
+	"shopy/pkg/money"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -16,17 +20,39 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+// notDeleted filters out products moved to the trash.
+const notDeleted = "attribute_not_exists(deleted_at)"
+
 type Product struct {
-	logger    *slog.Logger
-	client    *dynamodb.Client
-	tableName string
+	logger        *slog.Logger
+	client        *dynamodb.Client
+	tableName     string
+	variantTable  string
+	ledgerTable   string
+	stockTable    string
+	locationTable string
+	imageTable    string
+	categoryTable string
+	slugTable     string
+	qrcodeTable   string
+	currency      string
 }
 
 func NewProduct(logger *slog.Logger, client *dynamodb.Client) *Product {
+	currency := os.Getenv("DEFAULT_CURRENCY")
 	return &Product{
-		logger:    logger,
-		client:    client,
-		tableName: "product",
+		logger:        logger,
+		client:        client,
+		tableName:     "product",
+		variantTable:  "product_variant",
+		ledgerTable:   "inventory_ledger",
+		stockTable:    "location_stock",
+		locationTable: "location",
+		imageTable:    "image_reference",
+		categoryTable: "category",
+		slugTable:     "slug",
+		qrcodeTable:   "product_qrcode",
+		currency:      currency,
 	}
 }
 
@@ -35,6 +61,7 @@ func (p *Product) GetProductsByCategory(ctx context.Context, uuid string) (model
 		TableName:              aws.String(p.tableName),
 		IndexName:              aws.String("GSI_CATEGORY"),
 		KeyConditionExpression: aws.String("category_uuid = :uuid"),
+		FilterExpression:       aws.String(notDeleted),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":uuid": &types.AttributeValueMemberS{Value: uuid},
 		},
@@ -50,13 +77,13 @@ func (p *Product) GetProductsByCategory(ctx context.Context, uuid string) (model
 		return models.Products{}, nil
 	}
 
-	var product ProductTable
 	products := make(models.Products, len(result.Items))
 	for i, item := range result.Items {
+		var product ProductTable
 		if err = attributevalue.UnmarshalMap(item, &product); err != nil {
 			return nil, fmt.Errorf("error unmarshaling item: %w", err)
 		}
-		products[i] = assembleProduct(product)
+		products[i] = p.assembleProduct(product)
 	}
 
 	return products, nil
@@ -67,6 +94,7 @@ func (p *Product) GetProductsByQRCode(ctx context.Context, qrcode string) (model
 		TableName:              aws.String(p.tableName),
 		IndexName:              aws.String("GSI_QRCODE"),
 		KeyConditionExpression: aws.String("qrcode = :qrcode"),
+		FilterExpression:       aws.String(notDeleted),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":qrcode": &types.AttributeValueMemberS{Value: qrcode},
 		},
@@ -81,15 +109,15 @@ func (p *Product) GetProductsByQRCode(ctx context.Context, qrcode string) (model
 		return models.Products{}, nil
 	}
 
-	var product ProductTable
 	products := make(models.Products, len(result.Items))
 
 	for i, item := range result.Items {
+		var product ProductTable
 		if err = attributevalue.UnmarshalMap(item, &product); err != nil {
 			return nil, fmt.Errorf("error unmarshaling item: %w", err)
 		}
 
-		products[i] = assembleProduct(product)
+		products[i] = p.assembleProduct(product)
 	}
 
 	return products, nil
@@ -98,7 +126,7 @@ func (p *Product) GetProductsByQRCode(ctx context.Context, qrcode string) (model
 func (p *Product) GetProductsByName(ctx context.Context, name string) (models.Products, error) {
 	input := &dynamodb.ScanInput{
 		TableName:        aws.String(p.tableName),
-		FilterExpression: aws.String("contains(#name, :name)"),
+		FilterExpression: aws.String("contains(#name, :name) AND " + notDeleted),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":name": &types.AttributeValueMemberS{Value: name},
 		},
@@ -116,13 +144,13 @@ func (p *Product) GetProductsByName(ctx context.Context, name string) (models.Pr
 		return models.Products{}, nil
 	}
 
-	var product ProductTable
 	products := make(models.Products, len(result.Items))
 	for i, item := range result.Items {
+		var product ProductTable
 		if err = attributevalue.UnmarshalMap(item, &product); err != nil {
 			return nil, fmt.Errorf("error unmarshaling item: %w", err)
 		}
-		products[i] = assembleProduct(product)
+		products[i] = p.assembleProduct(product)
 	}
 
 	return products, nil
@@ -131,7 +159,7 @@ func (p *Product) GetProductsByName(ctx context.Context, name string) (models.Pr
 func (p *Product) GetTopProducts(ctx context.Context) (models.Products, error) {
 	input := &dynamodb.ScanInput{
 		TableName:        aws.String(p.tableName),
-		FilterExpression: aws.String("is_top = :is_top"),
+		FilterExpression: aws.String("is_top = :is_top AND " + notDeleted),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":is_top": &types.AttributeValueMemberBOOL{Value: true},
 		},
@@ -146,30 +174,65 @@ func (p *Product) GetTopProducts(ctx context.Context) (models.Products, error) {
 		return models.Products{}, nil
 	}
 
-	var product ProductTable
 	products := make(models.Products, len(result.Items))
 	for i, item := range result.Items {
+		var product ProductTable
 		if err = attributevalue.UnmarshalMap(item, &product); err != nil {
 			return nil, fmt.Errorf("error unmarshaling item: %w", err)
 		}
-		products[i] = assembleProduct(product)
+		products[i] = p.assembleProduct(product)
 	}
 
 	return products, nil
 }
 
+func (p *Product) GetProduct(ctx context.Context, uuid string) (*models.Product, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(p.tableName),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+	}
+
+	result, err := p.client.GetItem(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error getting item: %w", err)
+	}
+
+	if len(result.Item) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	var product ProductTable
+	if err = attributevalue.UnmarshalMap(result.Item, &product); err != nil {
+		return nil, fmt.Errorf("error unmarshaling item: %w", err)
+	}
+
+	if product.DeletedAt != "" {
+		return nil, domain.ErrNotFound
+	}
+
+	return p.assembleProduct(product), nil
+}
+
 func (p *Product) AddProduct(ctx context.Context, params domain.ProductParams) (*models.Product, error) {
+	primary, _ := params.Images.Primary()
 	product := ProductTable{
-		Uuid:         params.Uuid,
-		Name:         params.Name,
-		Price:        params.Price,
-		Image:        params.Location,
-		QRCode:       params.QRCode,
-		IsTop:        params.IsTop,
-		CategoryUuid: params.Category.Uuid,
-		CategoryName: params.Category.Name,
-		CreatedAt:    params.CreatedAt.Format(time.DateTime),
-		UpdatedAt:    params.UpdatedAt.Format(time.DateTime),
+		Uuid:          params.Uuid,
+		Name:          params.Name,
+		Slug:          params.Slug,
+		PriceAmount:   params.Price.Amount,
+		PriceCurrency: params.Price.Currency,
+		Image:         primary.Location,
+		Images:        imageTables(params.Images),
+		QRCode:        params.QRCode,
+		BarcodeType:   string(params.BarcodeType),
+		IsTop:         params.IsTop,
+		CategoryUuid:  params.Category.Uuid,
+		CategoryName:  params.Category.Name,
+		Version:       1,
+		CreatedAt:     params.CreatedAt.Format(time.DateTime),
+		UpdatedAt:     params.UpdatedAt.Format(time.DateTime),
 	}
 
 	item, err := attributevalue.MarshalMap(product)
@@ -177,54 +240,338 @@ func (p *Product) AddProduct(ctx context.Context, params domain.ProductParams) (
 		return nil, fmt.Errorf("error marshaling item: %w", err)
 	}
 
-	_, err = p.client.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(p.tableName),
-		Item:      item,
-	})
+	guard, err := p.slugGuard(params.Slug, params.Uuid, params.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	locks, err := p.qrcodeLocks(ctx, params.Uuid, "", "", params.QRCode, params.CreatedAt)
 	if err != nil {
-		return nil, fmt.Errorf("error adding item: %w", err)
+		return nil, err
+	}
+
+	writes := []transactWrite{
+		{
+			item: types.TransactWriteItem{
+				Put: &types.Put{
+					TableName: aws.String(p.tableName),
+					Item:      item,
+				},
+			},
+		},
+		// the count only applies to a category outside the trash, which
+		// makes it the check of the category
+		p.categoryCount(params.Category.Uuid, 1, "attribute_exists(#uuid) AND "+notDeleted),
+		guard,
+	}
+	if err = p.writeTransaction(ctx, append(writes, locks...), "adding item"); err != nil {
+		return nil, err
 	}
 
-	return assembleProduct(product), nil
+	return p.assembleProduct(product), nil
 }
 
 func (p *Product) PutProduct(ctx context.Context, params domain.ProductParams) (*models.Product, error) {
-	expression := "SET #name = :name, price = :price, qrcode = :qrcode, is_top = :is_top, category_uuid = :category_uuid, category_name = :category_name, updated_at = :updated_at"
+	expression := "SET #name = :name, price_amount = :price_amount, price_currency = :price_currency, qrcode = :qrcode, barcode_type = :barcode_type, is_top = :is_top, category_uuid = :category_uuid, category_name = :category_name, updated_at = :updated_at, #version = if_not_exists(#version, :zero) + :one"
 	expressionAttributeValues := map[string]types.AttributeValue{
-		":name":          &types.AttributeValueMemberS{Value: params.Name},
-		":price":         &types.AttributeValueMemberN{Value: strconv.FormatFloat(params.Price, 'f', -1, 32)},
-		":qrcode":        &types.AttributeValueMemberS{Value: params.QRCode},
-		":is_top":        &types.AttributeValueMemberBOOL{Value: params.IsTop},
-		":category_uuid": &types.AttributeValueMemberS{Value: params.Category.Uuid},
-		":category_name": &types.AttributeValueMemberS{Value: params.Category.Name},
-		":updated_at":    &types.AttributeValueMemberS{Value: params.UpdatedAt.Format(time.DateTime)},
+		":name":           &types.AttributeValueMemberS{Value: params.Name},
+		":price_amount":   &types.AttributeValueMemberN{Value: strconv.FormatInt(params.Price.Amount, 10)},
+		":price_currency": &types.AttributeValueMemberS{Value: params.Price.Currency},
+		":qrcode":         &types.AttributeValueMemberS{Value: params.QRCode},
+		":barcode_type":   &types.AttributeValueMemberS{Value: string(params.BarcodeType)},
+		":is_top":         &types.AttributeValueMemberBOOL{Value: params.IsTop},
+		":category_uuid":  &types.AttributeValueMemberS{Value: params.Category.Uuid},
+		":category_name":  &types.AttributeValueMemberS{Value: params.Category.Name},
+		":updated_at":     &types.AttributeValueMemberS{Value: params.UpdatedAt.Format(time.DateTime)},
+		":zero":           &types.AttributeValueMemberN{Value: "0"},
+		":one":            &types.AttributeValueMemberN{Value: "1"},
 	}
 
-	if params.Location != "" {
-		expression += ", image = :image"
-		expressionAttributeValues[":image"] = &types.AttributeValueMemberS{Value: params.Location}
+	// drop the legacy float price once the item is rewritten with minor units
+	remove := []string{"price"}
+
+	var writes []transactWrite
+	if params.Slug != "" {
+		guard, err := p.slugGuard(params.Slug, params.Uuid, params.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		writes = append(writes, guard)
+
+		expression += ", slug = :slug"
+		expressionAttributeValues[":slug"] = &types.AttributeValueMemberS{Value: params.Slug}
+	}
+
+	if params.Images != nil {
+		set, removeImages, err := setImages(params.Images, expressionAttributeValues)
+		if err != nil {
+			return nil, err
+		}
+		for _, clause := range set {
+			expression += ", " + clause
+		}
+		remove = append(remove, removeImages...)
 	}
 
+	expression += " REMOVE " + strings.Join(remove, ", ")
+
+	condition := versionCondition(params.Version, expressionAttributeValues)
+
 	input := &dynamodb.UpdateItemInput{
 		TableName: aws.String(p.tableName),
 		Key: map[string]types.AttributeValue{
 			"uuid": &types.AttributeValueMemberS{Value: params.Uuid},
 		},
 		UpdateExpression:          aws.String(expression),
-		ConditionExpression:       aws.String("attribute_exists(#uuid)"),
+		ConditionExpression:       aws.String(condition),
 		ExpressionAttributeValues: expressionAttributeValues,
 		ExpressionAttributeNames: map[string]string{
-			"#name": "name",
-			"#uuid": "uuid",
+			"#name":    "name",
+			"#uuid":    "uuid",
+			"#version": "version",
 		},
-		ReturnValues: types.ReturnValueAllNew,
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
 	}
 
+	return p.updateCounted(ctx, input, func(current ProductTable) (string, string) {
+		return current.CategoryUuid, params.Category.Uuid
+	}, func(current ProductTable) ([]transactWrite, error) {
+		locks, err := p.qrcodeLocks(ctx, params.Uuid, "", current.QRCode, params.QRCode, params.UpdatedAt)
+		if current.CategoryUuid == params.Category.Uuid {
+			// a new category is checked by its count
+			locks = append(locks, p.categoryCheck(params.Category.Uuid))
+		}
+		return append(writes, locks...), err
+	})
+}
+
+func (p *Product) PatchProduct(ctx context.Context, patch domain.ProductPatch) (*models.Product, error) {
+	var (
+		set = []string{
+			"updated_at = :updated_at",
+			"#version = if_not_exists(#version, :zero) + :one",
+		}
+		remove                    []string
+		expressionAttributeValues = map[string]types.AttributeValue{
+			":updated_at": &types.AttributeValueMemberS{Value: patch.UpdatedAt.Format(time.DateTime)},
+			":zero":       &types.AttributeValueMemberN{Value: "0"},
+			":one":        &types.AttributeValueMemberN{Value: "1"},
+		}
+	)
+
+	if patch.Name != nil {
+		set = append(set, "#name = :name")
+		expressionAttributeValues[":name"] = &types.AttributeValueMemberS{Value: *patch.Name}
+	}
+
+	var writes []transactWrite
+	if patch.Slug != nil {
+		guard, err := p.slugGuard(*patch.Slug, patch.Uuid, patch.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		writes = append(writes, guard)
+
+		set = append(set, "slug = :slug")
+		expressionAttributeValues[":slug"] = &types.AttributeValueMemberS{Value: *patch.Slug}
+	}
+	if patch.PriceAmount != nil {
+		set = append(set, "price_amount = :price_amount")
+		expressionAttributeValues[":price_amount"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(*patch.PriceAmount, 10)}
+	}
+	if patch.PriceCurrency != nil {
+		set = append(set, "price_currency = :price_currency")
+		expressionAttributeValues[":price_currency"] = &types.AttributeValueMemberS{Value: *patch.PriceCurrency}
+	}
+	if patch.QRCode != nil {
+		set = append(set, "qrcode = :qrcode")
+		expressionAttributeValues[":qrcode"] = &types.AttributeValueMemberS{Value: *patch.QRCode}
+	}
+	if patch.RemoveQRCode {
+		remove = append(remove, "qrcode")
+	}
+	if patch.BarcodeType != nil {
+		set = append(set, "barcode_type = :barcode_type")
+		expressionAttributeValues[":barcode_type"] = &types.AttributeValueMemberS{Value: string(*patch.BarcodeType)}
+	}
+	if patch.IsTop != nil {
+		set = append(set, "is_top = :is_top")
+		expressionAttributeValues[":is_top"] = &types.AttributeValueMemberBOOL{Value: *patch.IsTop}
+	}
+	if patch.CategoryUuid != nil {
+		set = append(set, "category_uuid = :category_uuid")
+		expressionAttributeValues[":category_uuid"] = &types.AttributeValueMemberS{Value: *patch.CategoryUuid}
+	}
+	if patch.CategoryName != nil {
+		set = append(set, "category_name = :category_name")
+		expressionAttributeValues[":category_name"] = &types.AttributeValueMemberS{Value: *patch.CategoryName}
+	}
+	if patch.Images != nil {
+		setImages, removeImages, err := setImages(patch.Images, expressionAttributeValues)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, setImages...)
+		remove = append(remove, removeImages...)
+	}
+	if patch.PublishAt != nil {
+		set = append(set, "publish_at = :publish_at")
+		expressionAttributeValues[":publish_at"] = &types.AttributeValueMemberS{Value: formatTime(patch.PublishAt)}
+	}
+	if patch.RemovePublishAt {
+		remove = append(remove, "publish_at")
+	}
+	if patch.UnpublishAt != nil {
+		set = append(set, "unpublish_at = :unpublish_at")
+		expressionAttributeValues[":unpublish_at"] = &types.AttributeValueMemberS{Value: formatTime(patch.UnpublishAt)}
+	}
+	if patch.RemoveUnpublishAt {
+		remove = append(remove, "unpublish_at")
+	}
+
+	expression := "SET " + strings.Join(set, ", ")
+	if len(remove) > 0 {
+		expression += " REMOVE " + strings.Join(remove, ", ")
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(p.tableName),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: patch.Uuid},
+		},
+		UpdateExpression:          aws.String(expression),
+		ConditionExpression:       aws.String(versionCondition(patch.Version, expressionAttributeValues)),
+		ExpressionAttributeValues: expressionAttributeValues,
+		ExpressionAttributeNames: map[string]string{
+			"#uuid":    "uuid",
+			"#version": "version",
+		},
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}
+	if patch.Name != nil {
+		input.ExpressionAttributeNames["#name"] = "name"
+	}
+
+	qrcode := patch.QRCode != nil || patch.RemoveQRCode
+	if patch.CategoryUuid != nil || qrcode || len(writes) > 0 {
+		return p.updateCounted(ctx, input, func(current ProductTable) (string, string) {
+			if patch.CategoryUuid == nil {
+				return current.CategoryUuid, current.CategoryUuid
+			}
+			return current.CategoryUuid, *patch.CategoryUuid
+		}, func(current ProductTable) ([]transactWrite, error) {
+			writes := writes
+			if patch.CategoryUuid != nil && *patch.CategoryUuid == current.CategoryUuid {
+				// a new category is checked by its count
+				writes = append(writes, p.categoryCheck(current.CategoryUuid))
+			}
+			if !qrcode {
+				return writes, nil
+			}
+
+			var to string
+			if patch.QRCode != nil {
+				to = *patch.QRCode
+			}
+			locks, err := p.qrcodeLocks(ctx, patch.Uuid, "", current.QRCode, to, patch.UpdatedAt)
+			return append(writes, locks...), err
+		})
+	}
+	return p.updateProduct(ctx, input)
+}
+
+// PutImages replaces the gallery of a product and its primary image.
+func (p *Product) PutImages(ctx context.Context, params domain.GalleryParams) (*models.Product, error) {
+	var (
+		expression                = "SET updated_at = :updated_at, #version = if_not_exists(#version, :zero) + :one"
+		expressionAttributeValues = map[string]types.AttributeValue{
+			":updated_at": &types.AttributeValueMemberS{Value: params.UpdatedAt.Format(time.DateTime)},
+			":zero":       &types.AttributeValueMemberN{Value: "0"},
+			":one":        &types.AttributeValueMemberN{Value: "1"},
+		}
+	)
+
+	set, remove, err := setImages(params.Images, expressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	for _, clause := range set {
+		expression += ", " + clause
+	}
+	if len(remove) > 0 {
+		expression += " REMOVE " + strings.Join(remove, ", ")
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(p.tableName),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: params.Uuid},
+		},
+		UpdateExpression:          aws.String(expression),
+		ConditionExpression:       aws.String(versionCondition(params.Version, expressionAttributeValues)),
+		ExpressionAttributeValues: expressionAttributeValues,
+		ExpressionAttributeNames: map[string]string{
+			"#uuid":    "uuid",
+			"#version": "version",
+		},
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}
+
+	return p.updateProduct(ctx, input)
+}
+
+// setImages returns the update clauses writing the gallery and its primary
+// image, an empty gallery removes both attributes.
+func setImages(images domain.Images, values map[string]types.AttributeValue) (set, remove []string, err error) {
+	if len(images) == 0 {
+		return nil, []string{"images", "image"}, nil
+	}
+
+	value, err := attributevalue.Marshal(imageTables(images))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error marshaling images: %w", err)
+	}
+	values[":images"] = value
+	set = append(set, "images = :images")
+
+	primary, ok := images.Primary()
+	if !ok {
+		return set, []string{"image"}, nil
+	}
+	values[":image"] = &types.AttributeValueMemberS{Value: primary.Location}
+	return append(set, "image = :image"), nil, nil
+}
+
+func imageTables(images domain.Images) []ImageTable {
+	if len(images) == 0 {
+		return nil
+	}
+	tables := make([]ImageTable, len(images))
+	for i, image := range images {
+		tables[i] = ImageTable{
+			Uuid:       image.Uuid,
+			Hash:       image.Hash,
+			Location:   image.Location,
+			Alt:        image.Alt,
+			Primary:    image.Primary,
+			Renditions: renditionTables(image.Renditions),
+		}
+	}
+	return tables
+}
+
+func (p *Product) updateProduct(ctx context.Context, input *dynamodb.UpdateItemInput) (*models.Product, error) {
 	result, err := p.client.UpdateItem(ctx, input)
 	if err != nil {
 		var errf *types.ConditionalCheckFailedException
 		if errors.As(err, &errf) {
-			return nil, domain.ErrNotFound
+			if _, deleted := errf.Item["deleted_at"]; len(errf.Item) == 0 || deleted {
+				return nil, domain.ErrNotFound
+			}
+			return nil, domain.ErrVersionMismatch
 		}
 
 		return nil, fmt.Errorf("error updating item: %w", err)
@@ -235,30 +582,97 @@ func (p *Product) PutProduct(ctx context.Context, params domain.ProductParams) (
 		return nil, fmt.Errorf("error marshaling item: %w", err)
 	}
 
-	return assembleProduct(product), nil
+	return p.assembleProduct(product), nil
 }
 
-func (p *Product) DelProduct(ctx context.Context, uuid string) (*models.Product, error) {
-	input := &dynamodb.DeleteItemInput{
+// GetScheduledProducts returns the products whose publish_at or unpublish_at time has been reached.
+func (p *Product) GetScheduledProducts(ctx context.Context, now time.Time) (models.Products, error) {
+	paginator := dynamodb.NewScanPaginator(p.client, &dynamodb.ScanInput{
+		TableName:        aws.String(p.tableName),
+		FilterExpression: aws.String("(publish_at <= :now OR unpublish_at <= :now) AND " + notDeleted),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberS{Value: now.Format(time.DateTime)},
+		},
+	})
+
+	products := models.Products{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error executing query: %w", err)
+		}
+
+		for _, item := range page.Items {
+			var product ProductTable
+			if err = attributevalue.UnmarshalMap(item, &product); err != nil {
+				return nil, fmt.Errorf("error unmarshaling item: %w", err)
+			}
+			products = append(products, p.assembleProduct(product))
+		}
+	}
+
+	return products, nil
+}
+
+// TransitionProduct moves a product to a new status when its current status
+// allows it. Publishing clears publish_at and archiving clears the whole schedule.
+func (p *Product) TransitionProduct(ctx context.Context, params domain.StatusParams) (*models.Product, error) {
+	var (
+		sources    []string
+		expression = "SET #status = :status, updated_at = :updated_at, #version = if_not_exists(#version, :zero) + :one"
+		values     = map[string]types.AttributeValue{
+			":status":     &types.AttributeValueMemberS{Value: string(params.Status)},
+			":updated_at": &types.AttributeValueMemberS{Value: params.UpdatedAt.Format(time.DateTime)},
+			":zero":       &types.AttributeValueMemberN{Value: "0"},
+			":one":        &types.AttributeValueMemberN{Value: "1"},
+		}
+	)
+
+	for i, source := range params.Status.Sources() {
+		key := fmt.Sprintf(":from%d", i)
+		values[key] = &types.AttributeValueMemberS{Value: string(source)}
+		sources = append(sources, "#status = "+key)
+		if source == domain.StatusPublished {
+			// products stored before statuses were introduced are published
+			sources = append(sources, "attribute_not_exists(#status)")
+		}
+	}
+
+	switch params.Status {
+	case domain.StatusPublished:
+		expression += " REMOVE publish_at"
+	case domain.StatusArchived:
+		expression += " REMOVE publish_at, unpublish_at"
+	}
+
+	input := &dynamodb.UpdateItemInput{
 		TableName: aws.String(p.tableName),
 		Key: map[string]types.AttributeValue{
-			"uuid": &types.AttributeValueMemberS{Value: uuid},
+			"uuid": &types.AttributeValueMemberS{Value: params.Uuid},
 		},
-		ConditionExpression: aws.String("attribute_exists(#uuid)"),
+		UpdateExpression:          aws.String(expression),
+		ConditionExpression:       aws.String("attribute_exists(#uuid) AND " + notDeleted + " AND (" + strings.Join(sources, " OR ") + ")"),
+		ExpressionAttributeValues: values,
 		ExpressionAttributeNames: map[string]string{
-			"#uuid": "uuid",
+			"#uuid":    "uuid",
+			"#status":  "status",
+			"#version": "version",
 		},
-		ReturnValues: types.ReturnValueAllOld,
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
 	}
 
-	result, err := p.client.DeleteItem(ctx, input)
+	result, err := p.client.UpdateItem(ctx, input)
 	if err != nil {
 		var errf *types.ConditionalCheckFailedException
 		if errors.As(err, &errf) {
-			return nil, domain.ErrNotFound
+			if _, deleted := errf.Item["deleted_at"]; len(errf.Item) == 0 || deleted {
+				return nil, domain.ErrNotFound
+			}
+			return nil, domain.ErrTransition
 		}
 
-		return nil, fmt.Errorf("error deleting item: %w", err)
+		return nil, fmt.Errorf("error updating item: %w", err)
 	}
 
 	var product ProductTable
@@ -266,22 +680,324 @@ func (p *Product) DelProduct(ctx context.Context, uuid string) (*models.Product,
 		return nil, fmt.Errorf("error marshaling item: %w", err)
 	}
 
-	return assembleProduct(product), nil
+	return p.assembleProduct(product), nil
+}
+
+func (p *Product) GetTrashProducts(ctx context.Context) (models.Products, error) {
+	paginator := dynamodb.NewScanPaginator(p.client, &dynamodb.ScanInput{
+		TableName:        aws.String(p.tableName),
+		FilterExpression: aws.String("attribute_exists(deleted_at)"),
+	})
+
+	products := models.Products{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error executing query: %w", err)
+		}
+
+		for _, item := range page.Items {
+			var product ProductTable
+			if err = attributevalue.UnmarshalMap(item, &product); err != nil {
+				return nil, fmt.Errorf("error unmarshaling item: %w", err)
+			}
+			products = append(products, p.assembleProduct(product))
+		}
+	}
+
+	return products, nil
+}
+
+func (p *Product) TrashProduct(ctx context.Context, uuid string, deletedAt time.Time) (*models.Product, error) {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(p.tableName),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+		UpdateExpression:    aws.String("SET deleted_at = :deleted_at, updated_at = :deleted_at, #version = if_not_exists(#version, :zero) + :one"),
+		ConditionExpression: aws.String("attribute_exists(#uuid) AND " + notDeleted),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":deleted_at": &types.AttributeValueMemberS{Value: deletedAt.Format(time.DateTime)},
+			":zero":       &types.AttributeValueMemberN{Value: "0"},
+			":one":        &types.AttributeValueMemberN{Value: "1"},
+		},
+		ExpressionAttributeNames: map[string]string{
+			"#uuid":    "uuid",
+			"#version": "version",
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	}
+
+	return p.updateCounted(ctx, input, func(current ProductTable) (string, string) {
+		return current.CategoryUuid, ""
+	}, nil)
+}
+
+func (p *Product) RestoreProduct(ctx context.Context, uuid string, updatedAt time.Time) (*models.Product, error) {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(p.tableName),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+		UpdateExpression:    aws.String("SET updated_at = :updated_at, #version = if_not_exists(#version, :zero) + :one REMOVE deleted_at"),
+		ConditionExpression: aws.String("attribute_exists(deleted_at)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":updated_at": &types.AttributeValueMemberS{Value: updatedAt.Format(time.DateTime)},
+			":zero":       &types.AttributeValueMemberN{Value: "0"},
+			":one":        &types.AttributeValueMemberN{Value: "1"},
+		},
+		ExpressionAttributeNames: map[string]string{
+			"#version": "version",
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	}
+
+	return p.updateCounted(ctx, input, func(current ProductTable) (string, string) {
+		return "", current.CategoryUuid
+	}, nil)
+}
+
+// DelProduct permanently deletes a product in the trash and releases the lock
+// of its QR code in the same transaction.
+func (p *Product) DelProduct(ctx context.Context, uuid string) (*models.Product, error) {
+	key := map[string]types.AttributeValue{
+		"uuid": &types.AttributeValueMemberS{Value: uuid},
+	}
+
+	result, err := p.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(p.tableName),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting item: %w", err)
+	}
+
+	var product ProductTable
+	if err = attributevalue.UnmarshalMap(result.Item, &product); err != nil {
+		return nil, fmt.Errorf("error unmarshaling item: %w", err)
+	}
+
+	if len(result.Item) == 0 || product.DeletedAt == "" {
+		return nil, domain.ErrNotFound
+	}
+
+	locks, err := p.qrcodeLocks(ctx, uuid, "", product.QRCode, "", time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]types.AttributeValue{}
+	writes := []transactWrite{
+		{
+			item: types.TransactWriteItem{
+				Delete: &types.Delete{
+					TableName:                 aws.String(p.tableName),
+					Key:                       key,
+					ConditionExpression:       aws.String("attribute_exists(deleted_at) AND " + qrcodeCondition(product.QRCode, values)),
+					ExpressionAttributeValues: values,
+				},
+			},
+			failed: func(types.CancellationReason) error {
+				return domain.ErrNotFound
+			},
+		},
+	}
+	if err = p.writeTransaction(ctx, append(writes, locks...), "deleting item"); err != nil {
+		return nil, err
+	}
+
+	return p.assembleProduct(product), nil
+}
+
+// versionCondition returns the condition expression guarding a write with the
+// version sent by the client in If-Match.
+func versionCondition(version int64, values map[string]types.AttributeValue) string {
+	condition := "attribute_exists(#uuid) AND " + notDeleted
+	switch {
+	case version == domain.AnyVersion:
+	case version == 0:
+		// items written before versioning have no version attribute, those
+		// created before versions started at 1 store 0
+		condition += " AND (attribute_not_exists(#version) OR #version = :version)"
+		values[":version"] = &types.AttributeValueMemberN{Value: "0"}
+	default:
+		condition += " AND #version = :version"
+		values[":version"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)}
+	}
+	return condition
 }
 
-func assembleProduct(product ProductTable) *models.Product {
+func (p *Product) assembleProduct(product ProductTable) *models.Product {
+	kind := barcode.Type(product.BarcodeType)
+	if kind == "" {
+		kind = barcode.QRCode
+	}
+
 	return &models.Product{
-		Uuid:   product.Uuid,
-		Name:   product.Name,
-		Price:  product.Price,
-		Image:  product.Image,
-		QRCode: product.QRCode,
-		IsTop:  product.IsTop,
+		Uuid:        product.Uuid,
+		Name:        product.Name,
+		Slug:        product.Slug,
+		Price:       p.price(product),
+		Image:       product.Image,
+		Images:      assembleImages(product),
+		QRCode:      barcode.Format(kind, product.QRCode),
+		BarcodeType: string(kind),
+		IsTop:       product.IsTop,
 		Category: models.Category{
 			Uuid: product.CategoryUuid,
 			Name: product.CategoryName,
 		},
-		CreatedAt: product.CreatedAt,
-		UpdatedAt: product.UpdatedAt,
+		Stock:       product.Stock,
+		Version:     product.Version,
+		Status:      status(product),
+		PublishAt:   product.PublishAt,
+		UnpublishAt: product.UnpublishAt,
+		CreatedAt:   product.CreatedAt,
+		UpdatedAt:   product.UpdatedAt,
+		DeletedAt:   product.DeletedAt,
+	}
+}
+
+// assembleImages returns the product gallery, the single image of items
+// stored before galleries were introduced is its primary image.
+func assembleImages(product ProductTable) models.Images {
+	if len(product.Images) == 0 && product.Image != "" {
+		return models.Images{{
+			Uuid:    product.Uuid,
+			Url:     product.Image,
+			Primary: true,
+		}}
+	}
+
+	images := make(models.Images, len(product.Images))
+	for i, image := range product.Images {
+		images[i] = &models.Image{
+			Uuid:       image.Uuid,
+			Hash:       image.Hash,
+			Url:        image.Location,
+			Alt:        image.Alt,
+			Primary:    image.Primary,
+			Renditions: assembleRenditions(image.Renditions),
+		}
+	}
+	return images
+}
+
+func renditionTables(renditions domain.Renditions) []RenditionTable {
+	if len(renditions) == 0 {
+		return nil
+	}
+	tables := make([]RenditionTable, len(renditions))
+	for i, rendition := range renditions {
+		tables[i] = RenditionTable{
+			Width:    rendition.Width,
+			Format:   rendition.Format,
+			Location: rendition.Location,
+		}
+	}
+	return tables
+}
+
+func assembleRenditions(tables []RenditionTable) []*models.Rendition {
+	if len(tables) == 0 {
+		return nil
+	}
+	renditions := make([]*models.Rendition, len(tables))
+	for i, rendition := range tables {
+		renditions[i] = &models.Rendition{
+			Width:  rendition.Width,
+			Format: rendition.Format,
+			Url:    rendition.Location,
+		}
 	}
+	return renditions
+}
+
+// status returns the product status, items stored before statuses were
+// introduced were live as soon as they were written.
+func status(product ProductTable) string {
+	if product.Status == "" {
+		return string(domain.StatusPublished)
+	}
+	return product.Status
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.DateTime)
+}
+
+// price returns the product price, converting items stored before prices were
+// kept as minor units using the default currency.
+func (p *Product) price(product ProductTable) money.Money {
+	if product.PriceCurrency != "" {
+		return money.Money{
+			Amount:   product.PriceAmount,
+			Currency: product.PriceCurrency,
+		}
+	}
+
+	price, err := money.FromFloat(product.LegacyPrice, p.currency)
+	if err != nil {
+		p.logger.Warn("error converting legacy price", "uuid", product.Uuid, "error", err)
+	}
+	return price
+}
+
+// MigratePrices rewrites items that still hold a legacy float price as minor
+// units in the default currency, it returns the number of migrated items.
+func (p *Product) MigratePrices(ctx context.Context) (int, error) {
+	paginator := dynamodb.NewScanPaginator(p.client, &dynamodb.ScanInput{
+		TableName:        aws.String(p.tableName),
+		FilterExpression: aws.String("attribute_not_exists(price_currency)"),
+	})
+
+	var migrated int
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return migrated, fmt.Errorf("error executing query: %w", err)
+		}
+
+		for _, item := range page.Items {
+			var product ProductTable
+			if err = attributevalue.UnmarshalMap(item, &product); err != nil {
+				return migrated, fmt.Errorf("error unmarshaling item: %w", err)
+			}
+
+			price, err := money.FromFloat(product.LegacyPrice, p.currency)
+			if err != nil {
+				return migrated, fmt.Errorf("error converting price of %s: %w", product.Uuid, err)
+			}
+
+			_, err = p.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName: aws.String(p.tableName),
+				Key: map[string]types.AttributeValue{
+					"uuid": &types.AttributeValueMemberS{Value: product.Uuid},
+				},
+				UpdateExpression:    aws.String("SET price_amount = :price_amount, price_currency = :price_currency REMOVE price"),
+				ConditionExpression: aws.String("attribute_not_exists(price_currency)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":price_amount":   &types.AttributeValueMemberN{Value: strconv.FormatInt(price.Amount, 10)},
+					":price_currency": &types.AttributeValueMemberS{Value: price.Currency},
+				},
+			})
+			if err != nil {
+				var errf *types.ConditionalCheckFailedException
+				if errors.As(err, &errf) {
+					// already rewritten by a concurrent update
+					continue
+				}
+				return migrated, fmt.Errorf("error updating item: %w", err)
+			}
+
+			p.logger.Info("price migrated", "uuid", product.Uuid, "from", product.LegacyPrice, "to", price.String())
+			migrated++
+		}
+	}
+
+	return migrated, nil
 }
@@ -0,0 +1,226 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/pkg/money"
+	"testing"
+	"time"
+)
+
+func productParams(uuid string, version int64) domain.ProductParams {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return domain.ProductParams{
+		Uuid:      uuid,
+		Name:      "Cola",
+		Price:     money.Money{Amount: 199, Currency: "USD"},
+		Category:  domain.Category{Uuid: "drinks"},
+		Version:   version,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func TestProductVersion(t *testing.T) {
+	const uuid = "6f1c2a52-3f0a-4c39-9d43-5b1c1d7e8a10"
+
+	tests := []struct {
+		name    string
+		stored  item  // the item before the update, nil to create it with AddProduct
+		ifMatch int64 // the version sent in If-Match
+		want    error
+	}{
+		{
+			name:    "created product updated with its version",
+			ifMatch: 1,
+		},
+		{
+			name:    "created product updated with a stale version",
+			ifMatch: 0,
+			want:    domain.ErrVersionMismatch,
+		},
+		{
+			name: "product stored with version 0",
+			stored: item{
+				"uuid":          {"S": uuid},
+				"category_uuid": {"S": "drinks"},
+				"version":       {"N": "0"},
+			},
+			ifMatch: 0,
+		},
+		{
+			name: "product stored before versioning",
+			stored: item{
+				"uuid":          {"S": uuid},
+				"category_uuid": {"S": "drinks"},
+			},
+			ifMatch: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx        = context.Background()
+				fake       = newFakeDynamo()
+				repository = fake.repository(t)
+			)
+			fake.put("category", item{"uuid": {"S": "drinks"}})
+
+			if test.stored == nil {
+				product, err := repository.AddProduct(ctx, productParams(uuid, 0))
+				if err != nil {
+					t.Fatalf("AddProduct: %v", err)
+				}
+				if product.Version != 1 {
+					t.Fatalf("created version = %d, want 1", product.Version)
+				}
+				if stored := fake.items["product"][uuid]["version"]["N"]; stored != "1" {
+					t.Fatalf("stored version = %v, want 1", stored)
+				}
+			} else {
+				fake.put("product", test.stored)
+			}
+
+			product, err := repository.PutProduct(ctx, productParams(uuid, test.ifMatch))
+			if !errors.Is(err, test.want) {
+				t.Fatalf("PutProduct error = %v, want %v", err, test.want)
+			}
+			if err == nil && product.Version != test.ifMatch+1 {
+				t.Errorf("updated version = %d, want %d", product.Version, test.ifMatch+1)
+			}
+		})
+	}
+}
+
+func TestProductCategoryCheck(t *testing.T) {
+	const uuid = "6f1c2a52-3f0a-4c39-9d43-5b1c1d7e8a10"
+
+	tests := []struct {
+		name string
+		// category is the stored category of the product, nil when missing
+		category item
+		// added writes a new product, the others update a stored one
+		added bool
+		write func(ctx context.Context, repository *Product) error
+		want  error
+	}{
+		{
+			name:     "add to a category",
+			category: item{"uuid": {"S": "drinks"}},
+			added:    true,
+			write: func(ctx context.Context, repository *Product) error {
+				_, err := repository.AddProduct(ctx, productParams(uuid, 0))
+				return err
+			},
+		},
+		{
+			name:     "add to a trashed category",
+			category: item{"uuid": {"S": "drinks"}, "deleted_at": {"S": "2024-05-01 11:00:00"}},
+			added:    true,
+			write: func(ctx context.Context, repository *Product) error {
+				_, err := repository.AddProduct(ctx, productParams(uuid, 0))
+				return err
+			},
+			want: domain.ErrCategory,
+		},
+		{
+			name:     "update in a category",
+			category: item{"uuid": {"S": "drinks"}},
+			write: func(ctx context.Context, repository *Product) error {
+				_, err := repository.PutProduct(ctx, productParams(uuid, 1))
+				return err
+			},
+		},
+		{
+			name:     "update in a category trashed since the product was read",
+			category: item{"uuid": {"S": "drinks"}, "deleted_at": {"S": "2024-05-01 11:00:00"}},
+			write: func(ctx context.Context, repository *Product) error {
+				_, err := repository.PutProduct(ctx, productParams(uuid, 1))
+				return err
+			},
+			want: domain.ErrCategory,
+		},
+		{
+			name: "update in a purged category",
+			write: func(ctx context.Context, repository *Product) error {
+				_, err := repository.PutProduct(ctx, productParams(uuid, 1))
+				return err
+			},
+			want: domain.ErrCategory,
+		},
+		{
+			name:     "patch keeping a trashed category",
+			category: item{"uuid": {"S": "drinks"}, "deleted_at": {"S": "2024-05-01 11:00:00"}},
+			write: func(ctx context.Context, repository *Product) error {
+				category := "drinks"
+				_, err := repository.PatchProduct(ctx, domain.ProductPatch{Uuid: uuid, CategoryUuid: &category, Version: 1})
+				return err
+			},
+			want: domain.ErrCategory,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := newFakeDynamo()
+			if !test.added {
+				fake.put("product", item{
+					"uuid":          {"S": uuid},
+					"category_uuid": {"S": "drinks"},
+					"version":       {"N": "1"},
+				})
+			}
+			if test.category != nil {
+				fake.put("category", test.category)
+			}
+
+			err := test.write(context.Background(), fake.repository(t))
+			if !errors.Is(err, test.want) {
+				t.Errorf("error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestListedProductsDontShareFields(t *testing.T) {
+	fake := newFakeDynamo()
+	fake.put("product", item{
+		"uuid":         {"S": "a"},
+		"name":         {"S": "Cola"},
+		"slug":         {"S": "cola"},
+		"status":       {"S": "draft"},
+		"publish_at":   {"S": "2024-06-01 00:00:00"},
+		"barcode_type": {"S": "ean13"},
+		"images": {"L": []any{
+			map[string]any{"M": map[string]any{
+				"uuid":     map[string]any{"S": "image"},
+				"location": map[string]any{"S": "https://bucket/cola.jpg"},
+				"primary":  map[string]any{"BOOL": true},
+			}},
+		}},
+	})
+	// a product stored before slugs, statuses, schedules and galleries
+	fake.put("product", item{
+		"uuid": {"S": "b"},
+		"name": {"S": "Water"},
+	})
+
+	products, err := fake.repository(t).GetTopProducts(context.Background())
+	if err != nil {
+		t.Fatalf("GetTopProducts: %v", err)
+	}
+	if len(products) != 2 || products[1].Uuid != "b" {
+		t.Fatalf("products = %+v, want a and b", products)
+	}
+
+	legacy := products[1]
+	if legacy.Slug != "" || legacy.PublishAt != "" || len(legacy.Images) != 0 || legacy.BarcodeType != "qrcode" {
+		t.Errorf("legacy product inherited fields: slug %q, publish_at %q, images %v, barcode_type %q",
+			legacy.Slug, legacy.PublishAt, legacy.Images, legacy.BarcodeType)
+	}
+	if legacy.Status != string(domain.StatusPublished) {
+		t.Errorf("legacy product status = %q, want %q", legacy.Status, domain.StatusPublished)
+	}
+}
@@ -0,0 +1,286 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"shopy/pkg/barcode"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// GetQRCodeOwner returns the product or variant holding a QR code lock.
+func (p *Product) GetQRCodeOwner(ctx context.Context, qrcode string) (*models.QRCodeOwner, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(p.qrcodeTable),
+		Key: map[string]types.AttributeValue{
+			"qrcode": &types.AttributeValueMemberS{Value: qrcode},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	result, err := p.client.GetItem(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error getting item: %w", err)
+	}
+
+	if len(result.Item) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	var lock QRCodeTable
+	if err = attributevalue.UnmarshalMap(result.Item, &lock); err != nil {
+		return nil, fmt.Errorf("error unmarshaling item: %w", err)
+	}
+
+	return assembleQRCodeOwner(lock), nil
+}
+
+func assembleQRCodeOwner(lock QRCodeTable) *models.QRCodeOwner {
+	return &models.QRCodeOwner{
+		ProductUuid: lock.ProductUuid,
+		VariantUuid: lock.VariantUuid,
+	}
+}
+
+// LockQRCodes locks the QR codes of the products and variants stored before
+// the locks existed. GS1 codes of products stored before barcode types are
+// first rewritten to their GTIN-14 form. A code already locked by another
+// product or variant is logged and left to staff, who must give one of them
+// another code.
+func (p *Product) LockQRCodes(ctx context.Context) (int, error) {
+	paginator := dynamodb.NewScanPaginator(p.client, &dynamodb.ScanInput{
+		TableName:            aws.String(p.tableName),
+		ProjectionExpression: aws.String("#uuid, qrcode, barcode_type, created_at"),
+		FilterExpression:     aws.String("attribute_exists(qrcode) AND qrcode <> :empty"),
+		ExpressionAttributeNames: map[string]string{
+			"#uuid": "uuid",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":empty": &types.AttributeValueMemberS{Value: ""},
+		},
+	})
+
+	var locked int
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return locked, fmt.Errorf("error executing scan: %w", err)
+		}
+
+		var products []ProductTable
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &products); err != nil {
+			return locked, fmt.Errorf("error unmarshaling items: %w", err)
+		}
+
+		for _, product := range products {
+			if product.BarcodeType == "" {
+				if product.QRCode, err = p.normalizeQRCode(ctx, product.Uuid, product.QRCode); err != nil {
+					return locked, err
+				}
+				if product.QRCode == "" {
+					continue
+				}
+			}
+
+			ok, err := p.lockQRCode(ctx, p.qrcodeLock(product.QRCode, product.Uuid, "", product.CreatedAt))
+			if err != nil {
+				return locked, err
+			}
+			if !ok {
+				p.logger.Warn("qrcode held by another product", "uuid", product.Uuid, "qrcode", product.QRCode)
+				continue
+			}
+			locked++
+		}
+	}
+
+	variants := dynamodb.NewScanPaginator(p.client, &dynamodb.ScanInput{
+		TableName:            aws.String(p.variantTable),
+		ProjectionExpression: aws.String("product_uuid, #uuid, qrcode, created_at"),
+		FilterExpression:     aws.String("attribute_exists(qrcode)"),
+		ExpressionAttributeNames: map[string]string{
+			"#uuid": "uuid",
+		},
+	})
+	for variants.HasMorePages() {
+		page, err := variants.NextPage(ctx)
+		if err != nil {
+			return locked, fmt.Errorf("error executing scan: %w", err)
+		}
+
+		var items []VariantTable
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return locked, fmt.Errorf("error unmarshaling items: %w", err)
+		}
+
+		for _, variant := range items {
+			ok, err := p.lockQRCode(ctx, p.qrcodeLock(variant.QRCode, variant.ProductUuid, variant.Uuid, variant.CreatedAt))
+			if err != nil {
+				return locked, err
+			}
+			if !ok {
+				p.logger.Warn("qrcode held by another product", "product_uuid", variant.ProductUuid, "uuid", variant.Uuid, "qrcode", variant.QRCode)
+				continue
+			}
+			locked++
+		}
+	}
+
+	return locked, nil
+}
+
+// lockQRCode writes a lock on its own, it reports false when another product
+// or variant holds the code.
+func (p *Product) lockQRCode(ctx context.Context, lock transactWrite) (bool, error) {
+	put := lock.item.Put
+	_, err := p.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 put.TableName,
+		Item:                      put.Item,
+		ConditionExpression:       put.ConditionExpression,
+		ExpressionAttributeValues: put.ExpressionAttributeValues,
+	})
+	if err != nil {
+		var errf *types.ConditionalCheckFailedException
+		if errors.As(err, &errf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error adding item: %w", err)
+	}
+	return true, nil
+}
+
+// normalizeQRCode stores a legacy GS1 code in its GTIN-14 form with the
+// barcode type its length stands for, so it keeps its representation. The
+// code is empty when the product was saved meanwhile.
+func (p *Product) normalizeQRCode(ctx context.Context, uuid, qrcode string) (string, error) {
+	kind := barcode.GTIN14
+	switch len(qrcode) {
+	case 12:
+		kind = barcode.UPCA
+	case 13:
+		kind = barcode.EAN13
+	}
+
+	normalized := barcode.Normalize(kind, qrcode)
+	if normalized == qrcode {
+		return qrcode, nil
+	}
+
+	_, err := p.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(p.tableName),
+		Key: map[string]types.AttributeValue{
+			"uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+		UpdateExpression:    aws.String("SET qrcode = :qrcode, barcode_type = :barcode_type"),
+		ConditionExpression: aws.String("qrcode = :current_qrcode AND attribute_not_exists(barcode_type)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":qrcode":         &types.AttributeValueMemberS{Value: normalized},
+			":barcode_type":   &types.AttributeValueMemberS{Value: string(kind)},
+			":current_qrcode": &types.AttributeValueMemberS{Value: qrcode},
+		},
+	})
+	if err != nil {
+		var errf *types.ConditionalCheckFailedException
+		if errors.As(err, &errf) {
+			// saved since the scan, the save locked its code
+			p.logger.Warn("qrcode changed while normalizing", "uuid", uuid, "qrcode", qrcode)
+			return "", nil
+		}
+		return "", fmt.Errorf("error updating item: %w", err)
+	}
+	return normalized, nil
+}
+
+// qrcodeLocks returns the writes moving the QR code lock of a product, or of
+// one of its variants when variantUuid is set, from a code to another, either
+// may be empty. The code left is only unlocked when its lock is held by the
+// same product or variant, items stored before the locks may share it.
+func (p *Product) qrcodeLocks(ctx context.Context, uuid, variantUuid, from, to string, now time.Time) ([]transactWrite, error) {
+	if from == to {
+		return nil, nil
+	}
+
+	var writes []transactWrite
+	if from != "" {
+		owner, err := p.GetQRCodeOwner(ctx, from)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return nil, err
+		}
+		if owner != nil && owner.ProductUuid == uuid && owner.VariantUuid == variantUuid {
+			values := map[string]types.AttributeValue{}
+			writes = append(writes, transactWrite{
+				item: types.TransactWriteItem{
+					Delete: &types.Delete{
+						TableName: aws.String(p.qrcodeTable),
+						Key: map[string]types.AttributeValue{
+							"qrcode": &types.AttributeValueMemberS{Value: from},
+						},
+						ConditionExpression:       aws.String(lockHolder(uuid, variantUuid, values)),
+						ExpressionAttributeValues: values,
+					},
+				},
+			})
+		}
+	}
+	if to != "" {
+		writes = append(writes, p.qrcodeLock(to, uuid, variantUuid, now.Format(time.DateTime)))
+	}
+	return writes, nil
+}
+
+// qrcodeLock locks a QR code to a product, or to one of its variants when
+// variantUuid is set, it fails when another product or variant holds the
+// code.
+func (p *Product) qrcodeLock(qrcode, uuid, variantUuid, createdAt string) transactWrite {
+	item := map[string]types.AttributeValue{
+		"qrcode":       &types.AttributeValueMemberS{Value: qrcode},
+		"product_uuid": &types.AttributeValueMemberS{Value: uuid},
+		"created_at":   &types.AttributeValueMemberS{Value: createdAt},
+	}
+	if variantUuid != "" {
+		item["variant_uuid"] = &types.AttributeValueMemberS{Value: variantUuid}
+	}
+
+	values := map[string]types.AttributeValue{}
+	return transactWrite{
+		item: types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:                 aws.String(p.qrcodeTable),
+				Item:                      item,
+				ConditionExpression:       aws.String("attribute_not_exists(qrcode) OR " + lockHolder(uuid, variantUuid, values)),
+				ExpressionAttributeValues: values,
+			},
+		},
+		failed: func(types.CancellationReason) error {
+			return domain.ErrQRCode
+		},
+	}
+}
+
+// lockHolder makes a lock write apply only to a lock held by the product, or
+// by the variant when variantUuid is set.
+func lockHolder(uuid, variantUuid string, values map[string]types.AttributeValue) string {
+	values[":uuid"] = &types.AttributeValueMemberS{Value: uuid}
+	if variantUuid == "" {
+		return "(product_uuid = :uuid AND attribute_not_exists(variant_uuid))"
+	}
+	values[":variant_uuid"] = &types.AttributeValueMemberS{Value: variantUuid}
+	return "(product_uuid = :uuid AND variant_uuid = :variant_uuid)"
+}
+
+// qrcodeCondition makes an update apply only while the product still has
+// the QR code it was read with, so the locks written with it stay right.
+func qrcodeCondition(current string, values map[string]types.AttributeValue) string {
+	values[":current_qrcode"] = &types.AttributeValueMemberS{Value: current}
+	if current == "" {
+		return "(attribute_not_exists(qrcode) OR qrcode = :current_qrcode)"
+	}
+	return "qrcode = :current_qrcode"
+}
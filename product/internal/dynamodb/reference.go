@@ -0,0 +1,140 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// AcquireImage adds a reference to the content stored under hash and returns
+// the reference record, without renditions for the first reference.
+func (p *Product) AcquireImage(ctx context.Context, hash string, updatedAt time.Time) (*models.ImageReference, error) {
+	result, err := p.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(p.imageTable),
+		Key: map[string]types.AttributeValue{
+			"hash": &types.AttributeValueMemberS{Value: hash},
+		},
+		UpdateExpression: aws.String("ADD #references :one SET updated_at = :updated_at, created_at = if_not_exists(created_at, :updated_at)"),
+		ExpressionAttributeNames: map[string]string{
+			"#references": "references",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one":        &types.AttributeValueMemberN{Value: "1"},
+			":updated_at": &types.AttributeValueMemberS{Value: updatedAt.Format(time.DateTime)},
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error updating item: %w", err)
+	}
+
+	var reference ImageReferenceTable
+	if err = attributevalue.UnmarshalMap(result.Attributes, &reference); err != nil {
+		return nil, fmt.Errorf("error unmarshaling item: %w", err)
+	}
+
+	return &models.ImageReference{
+		Hash:       reference.Hash,
+		References: reference.References,
+		Renditions: assembleRenditions(reference.Renditions),
+		CreatedAt:  reference.CreatedAt,
+		UpdatedAt:  reference.UpdatedAt,
+	}, nil
+}
+
+// PutImageRenditions records the renditions stored under hash so later
+// references reuse them.
+func (p *Product) PutImageRenditions(ctx context.Context, hash string, renditions domain.Renditions) error {
+	value, err := attributevalue.Marshal(renditionTables(renditions))
+	if err != nil {
+		return fmt.Errorf("error marshaling renditions: %w", err)
+	}
+
+	_, err = p.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(p.imageTable),
+		Key: map[string]types.AttributeValue{
+			"hash": &types.AttributeValueMemberS{Value: hash},
+		},
+		UpdateExpression:    aws.String("SET renditions = :renditions"),
+		ConditionExpression: aws.String("attribute_exists(#hash)"),
+		ExpressionAttributeNames: map[string]string{
+			"#hash": "hash",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":renditions": value,
+		},
+	})
+	if err != nil {
+		var errf *types.ConditionalCheckFailedException
+		if errors.As(err, &errf) {
+			return domain.ErrNotFound
+		}
+		return fmt.Errorf("error updating item: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseImage removes a reference to the content stored under hash and
+// reports whether it was the last one, in which case the record is deleted
+// and the caller deletes the stored renditions. Content without a record,
+// stored before references were counted, has no other reference.
+func (p *Product) ReleaseImage(ctx context.Context, hash string) (bool, error) {
+	var (
+		key = map[string]types.AttributeValue{
+			"hash": &types.AttributeValueMemberS{Value: hash},
+		}
+		names = map[string]string{
+			"#hash":       "hash",
+			"#references": "references",
+		}
+		values = map[string]types.AttributeValue{
+			":one": &types.AttributeValueMemberN{Value: "1"},
+		}
+		errf *types.ConditionalCheckFailedException
+	)
+
+	_, err := p.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(p.imageTable),
+		Key:                       key,
+		ConditionExpression:       aws.String("attribute_not_exists(#hash) OR #references <= :one"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if err == nil {
+		return true, nil
+	}
+	if !errors.As(err, &errf) {
+		return false, fmt.Errorf("error deleting item: %w", err)
+	}
+
+	// other images still point at the content
+	_, err = p.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(p.imageTable),
+		Key:                      key,
+		UpdateExpression:         aws.String("ADD #references :minus"),
+		ConditionExpression:      aws.String("#references > :one"),
+		ExpressionAttributeNames: map[string]string{"#references": "references"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one":   &types.AttributeValueMemberN{Value: "1"},
+			":minus": &types.AttributeValueMemberN{Value: "-1"},
+		},
+	})
+	if errors.As(err, &errf) {
+		// the other references were released in between
+		return p.ReleaseImage(ctx, hash)
+	}
+	if err != nil {
+		return false, fmt.Errorf("error updating item: %w", err)
+	}
+
+	return false, nil
+}
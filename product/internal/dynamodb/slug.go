@@ -0,0 +1,124 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shopy/internal/domain"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// slugKind prefixes the slugs of products in the slug table, which is shared
+// with the categories.
+const slugKind = "product#"
+
+// GetSlugOwner returns the UUID of the product holding a slug, either as its
+// current slug or as one it had before a rename.
+func (p *Product) GetSlugOwner(ctx context.Context, slug string) (string, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(p.slugTable),
+		Key: map[string]types.AttributeValue{
+			"slug": &types.AttributeValueMemberS{Value: slugKind + slug},
+		},
+	}
+
+	result, err := p.client.GetItem(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("error getting item: %w", err)
+	}
+
+	if len(result.Item) == 0 {
+		return "", domain.ErrNotFound
+	}
+
+	var item SlugTable
+	if err = attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return "", fmt.Errorf("error unmarshaling item: %w", err)
+	}
+
+	return item.Uuid, nil
+}
+
+// DelSlugs frees the current and former slugs of a purged product.
+func (p *Product) DelSlugs(ctx context.Context, uuid string) error {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(p.slugTable),
+		IndexName:              aws.String("GSI_UUID"),
+		KeyConditionExpression: aws.String("#uuid = :uuid"),
+		ExpressionAttributeNames: map[string]string{
+			"#uuid": "uuid",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":uuid": &types.AttributeValueMemberS{Value: uuid},
+		},
+	}
+
+	paginator := dynamodb.NewQueryPaginator(p.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("error executing query: %w", err)
+		}
+
+		for _, item := range page.Items {
+			_, err = p.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(p.slugTable),
+				Key: map[string]types.AttributeValue{
+					"slug": item["slug"],
+				},
+				// the slug may have been taken since the query
+				ConditionExpression: aws.String("#uuid = :uuid"),
+				ExpressionAttributeNames: map[string]string{
+					"#uuid": "uuid",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":uuid": &types.AttributeValueMemberS{Value: uuid},
+				},
+			})
+			var errf *types.ConditionalCheckFailedException
+			if err != nil && !errors.As(err, &errf) {
+				return fmt.Errorf("error deleting item: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// slugGuard reserves slug for a product in the transaction writing it, it
+// fails when another product holds the slug. A product may take back one of
+// its former slugs.
+func (p *Product) slugGuard(slug, uuid string, createdAt time.Time) (transactWrite, error) {
+	item, err := attributevalue.MarshalMap(SlugTable{
+		Slug:      slugKind + slug,
+		Uuid:      uuid,
+		CreatedAt: createdAt.Format(time.DateTime),
+	})
+	if err != nil {
+		return transactWrite{}, fmt.Errorf("error marshaling item: %w", err)
+	}
+
+	return transactWrite{
+		item: types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:           aws.String(p.slugTable),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(slug) OR #uuid = :uuid"),
+				ExpressionAttributeNames: map[string]string{
+					"#uuid": "uuid",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":uuid": &types.AttributeValueMemberS{Value: uuid},
+				},
+			},
+		},
+		failed: func(types.CancellationReason) error {
+			return domain.ErrSlug
+		},
+	}, nil
+}
@@ -1,14 +1,119 @@
 package dynamodb
 
 type ProductTable struct {
-	Uuid         string  `dynamodbav:"uuid"`
-	Name         string  `dynamodbav:"name"`
-	Price        float64 `dynamodbav:"price"`
-	Image        string  `dynamodbav:"image"`
-	QRCode       string  `dynamodbav:"qrcode"`
-	IsTop        bool    `dynamodbav:"is_top"`
-	CategoryUuid string  `dynamodbav:"category_uuid"`
-	CategoryName string  `dynamodbav:"category_name"`
-	CreatedAt    string  `dynamodbav:"created_at"`
-	UpdatedAt    string  `dynamodbav:"updated_at"`
+	Uuid          string       `dynamodbav:"uuid"`
+	Name          string       `dynamodbav:"name"`
+	Slug          string       `dynamodbav:"slug,omitempty"`
+	PriceAmount   int64        `dynamodbav:"price_amount"`
+	PriceCurrency string       `dynamodbav:"price_currency"`
+	LegacyPrice   float64      `dynamodbav:"price,omitempty"`
+	Image         string       `dynamodbav:"image"`
+	Images        []ImageTable `dynamodbav:"images,omitempty"`
+	QRCode        string       `dynamodbav:"qrcode"`
+	BarcodeType   string       `dynamodbav:"barcode_type,omitempty"`
+	IsTop         bool         `dynamodbav:"is_top"`
+	CategoryUuid  string       `dynamodbav:"category_uuid"`
+	CategoryName  string       `dynamodbav:"category_name"`
+	Stock         int64        `dynamodbav:"stock"`
+	Version       int64        `dynamodbav:"version"`
+	Status        string       `dynamodbav:"status,omitempty"`
+	PublishAt     string       `dynamodbav:"publish_at,omitempty"`
+	UnpublishAt   string       `dynamodbav:"unpublish_at,omitempty"`
+	CreatedAt     string       `dynamodbav:"created_at"`
+	UpdatedAt     string       `dynamodbav:"updated_at"`
+	DeletedAt     string       `dynamodbav:"deleted_at,omitempty"`
+}
+
+type ImageTable struct {
+	Uuid       string           `dynamodbav:"uuid"`
+	Hash       string           `dynamodbav:"hash,omitempty"`
+	Location   string           `dynamodbav:"location"`
+	Alt        string           `dynamodbav:"alt,omitempty"`
+	Primary    bool             `dynamodbav:"primary"`
+	Renditions []RenditionTable `dynamodbav:"renditions,omitempty"`
+}
+
+type RenditionTable struct {
+	Width    int    `dynamodbav:"width"`
+	Format   string `dynamodbav:"format"`
+	Location string `dynamodbav:"location"`
+}
+
+type VariantTable struct {
+	ProductUuid   string            `dynamodbav:"product_uuid"`
+	Uuid          string            `dynamodbav:"uuid"`
+	Sku           string            `dynamodbav:"sku"`
+	Options       map[string]string `dynamodbav:"options"`
+	PriceAmount   *int64            `dynamodbav:"price_amount,omitempty"`
+	PriceCurrency string            `dynamodbav:"price_currency,omitempty"`
+	QRCode        string            `dynamodbav:"qrcode,omitempty"`
+	BarcodeType   string            `dynamodbav:"barcode_type,omitempty"`
+	Image         string            `dynamodbav:"image,omitempty"`
+	ImageHash     string            `dynamodbav:"image_hash,omitempty"`
+	Renditions    []RenditionTable  `dynamodbav:"renditions,omitempty"`
+	Stock         int64             `dynamodbav:"stock"`
+	CreatedAt     string            `dynamodbav:"created_at"`
+	UpdatedAt     string            `dynamodbav:"updated_at"`
+}
+
+type LedgerTable struct {
+	ProductUuid  string `dynamodbav:"product_uuid"`
+	Uuid         string `dynamodbav:"uuid"`
+	VariantUuid  string `dynamodbav:"variant_uuid,omitempty"`
+	LocationUuid string `dynamodbav:"location_uuid,omitempty"`
+	TransferUuid string `dynamodbav:"transfer_uuid,omitempty"`
+	Adjustment   string `dynamodbav:"adjustment"`
+	Quantity     int64  `dynamodbav:"quantity"`
+	Reason       string `dynamodbav:"reason"`
+	Note         string `dynamodbav:"note,omitempty"`
+	CreatedAt    string `dynamodbav:"created_at"`
+}
+
+type LocationTable struct {
+	Uuid      string `dynamodbav:"uuid"`
+	Name      string `dynamodbav:"name"`
+	Kind      string `dynamodbav:"kind"`
+	Address   string `dynamodbav:"address"`
+	Pickup    bool   `dynamodbav:"pickup"`
+	CreatedAt string `dynamodbav:"created_at"`
+	UpdatedAt string `dynamodbav:"updated_at"`
+}
+
+// LocationStockTable holds the stock of a product, or of one of its variants,
+// at a location. The sort key is the location UUID followed by the variant UUID.
+type LocationStockTable struct {
+	ProductUuid  string `dynamodbav:"product_uuid"`
+	StockKey     string `dynamodbav:"stock_key"`
+	LocationUuid string `dynamodbav:"location_uuid"`
+	VariantUuid  string `dynamodbav:"variant_uuid,omitempty"`
+	Quantity     int64  `dynamodbav:"quantity"`
+}
+
+// ImageReferenceTable counts the images pointing at the renditions stored
+// under a content hash, the item is deleted with the last reference.
+type ImageReferenceTable struct {
+	Hash       string           `dynamodbav:"hash"`
+	References int64            `dynamodbav:"references"`
+	Renditions []RenditionTable `dynamodbav:"renditions,omitempty"`
+	CreatedAt  string           `dynamodbav:"created_at"`
+	UpdatedAt  string           `dynamodbav:"updated_at"`
+}
+
+// SlugTable reserves a slug for the product or category holding it, the key
+// is the kind of item followed by the slug such as "product#cola". The item
+// is kept after a rename so the old slug still leads to its owner.
+type SlugTable struct {
+	Slug      string `dynamodbav:"slug"`
+	Uuid      string `dynamodbav:"uuid"`
+	CreatedAt string `dynamodbav:"created_at"`
+}
+
+// QRCodeTable locks a QR code to the product or variant holding it, so no two
+// products or variants, trashed ones included, share a code. The lock is
+// released when its holder changes its code or is deleted for good.
+type QRCodeTable struct {
+	QRCode      string `dynamodbav:"qrcode"`
+	ProductUuid string `dynamodbav:"product_uuid"`
+	VariantUuid string `dynamodbav:"variant_uuid,omitempty"`
+	CreatedAt   string `dynamodbav:"created_at"`
 }
@@ -0,0 +1,352 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"shopy/pkg/barcode"
+	"shopy/pkg/money"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func (p *Product) GetVariants(ctx context.Context, productUuid string) (models.Variants, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(p.variantTable),
+		KeyConditionExpression: aws.String("product_uuid = :product_uuid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":product_uuid": &types.AttributeValueMemberS{Value: productUuid},
+		},
+	}
+
+	result, err := p.client.Query(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+
+	return assembleVariants(result.Items)
+}
+
+func (p *Product) GetVariant(ctx context.Context, productUuid, uuid string) (*models.Variant, error) {
+	variant, err := p.getVariant(ctx, productUuid, uuid)
+	if err != nil {
+		return nil, err
+	}
+	return assembleVariant(*variant), nil
+}
+
+// getVariant reads a variant consistently, so its QR code is the one locked.
+func (p *Product) getVariant(ctx context.Context, productUuid, uuid string) (*VariantTable, error) {
+	result, err := p.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(p.variantTable),
+		Key:            variantKey(productUuid, uuid),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting item: %w", err)
+	}
+	if len(result.Item) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	var variant VariantTable
+	if err = attributevalue.UnmarshalMap(result.Item, &variant); err != nil {
+		return nil, fmt.Errorf("error unmarshaling item: %w", err)
+	}
+	return &variant, nil
+}
+
+func (p *Product) GetVariantsByQRCode(ctx context.Context, qrcode string) (models.Variants, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(p.variantTable),
+		IndexName:              aws.String("GSI_QRCODE"),
+		KeyConditionExpression: aws.String("qrcode = :qrcode"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":qrcode": &types.AttributeValueMemberS{Value: qrcode},
+		},
+	}
+
+	result, err := p.client.Query(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+
+	return assembleVariants(result.Items)
+}
+
+func (p *Product) AddVariant(ctx context.Context, params domain.VariantParams) (*models.Variant, error) {
+	variant := VariantTable{
+		ProductUuid: params.ProductUuid,
+		Uuid:        params.Uuid,
+		Sku:         params.Sku,
+		Options:     params.Options,
+		QRCode:      params.QRCode,
+		BarcodeType: string(params.BarcodeType),
+		Image:       params.Location,
+		ImageHash:   params.Hash,
+		Renditions:  renditionTables(params.Renditions),
+		CreatedAt:   params.CreatedAt.Format(time.DateTime),
+		UpdatedAt:   params.UpdatedAt.Format(time.DateTime),
+	}
+	if params.Price != nil {
+		variant.PriceAmount = &params.Price.Amount
+		variant.PriceCurrency = params.Price.Currency
+	}
+
+	item, err := attributevalue.MarshalMap(variant)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling item: %w", err)
+	}
+
+	// the code is locked in the same transaction, as for products
+	locks, err := p.qrcodeLocks(ctx, params.ProductUuid, params.Uuid, "", params.QRCode, params.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	writes := []transactWrite{
+		{
+			item: types.TransactWriteItem{
+				Put: &types.Put{
+					TableName: aws.String(p.variantTable),
+					Item:      item,
+				},
+			},
+		},
+	}
+	if err = p.writeTransaction(ctx, append(writes, locks...), "adding item"); err != nil {
+		return nil, err
+	}
+
+	return assembleVariant(variant), nil
+}
+
+// PutVariant replaces a variant, moving the lock of its QR code in the same
+// transaction when the code changes. Like product updates, the write only
+// applies while the variant still has the code it was read with.
+func (p *Product) PutVariant(ctx context.Context, params domain.VariantParams) (*models.Variant, error) {
+	current, err := p.getVariant(ctx, params.ProductUuid, params.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := attributevalue.Marshal(params.Options)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling options: %w", err)
+	}
+
+	var (
+		set = []string{
+			"sku = :sku",
+			"#options = :options",
+			"updated_at = :updated_at",
+		}
+		remove                    []string
+		expressionAttributeValues = map[string]types.AttributeValue{
+			":sku":        &types.AttributeValueMemberS{Value: params.Sku},
+			":options":    options,
+			":updated_at": &types.AttributeValueMemberS{Value: params.UpdatedAt.Format(time.DateTime)},
+		}
+	)
+
+	if params.Price != nil {
+		set = append(set, "price_amount = :price_amount", "price_currency = :price_currency")
+		expressionAttributeValues[":price_amount"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(params.Price.Amount, 10)}
+		expressionAttributeValues[":price_currency"] = &types.AttributeValueMemberS{Value: params.Price.Currency}
+	} else {
+		remove = append(remove, "price_amount", "price_currency")
+	}
+
+	if params.QRCode != "" {
+		set = append(set, "qrcode = :qrcode", "barcode_type = :barcode_type")
+		expressionAttributeValues[":qrcode"] = &types.AttributeValueMemberS{Value: params.QRCode}
+		expressionAttributeValues[":barcode_type"] = &types.AttributeValueMemberS{Value: string(params.BarcodeType)}
+	} else {
+		// an empty string can't be stored as a GSI_QRCODE key
+		remove = append(remove, "qrcode", "barcode_type")
+	}
+
+	if params.Location != "" {
+		renditions, err := attributevalue.Marshal(renditionTables(params.Renditions))
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling renditions: %w", err)
+		}
+		set = append(set, "image = :image", "image_hash = :image_hash", "renditions = :renditions")
+		expressionAttributeValues[":image"] = &types.AttributeValueMemberS{Value: params.Location}
+		expressionAttributeValues[":image_hash"] = &types.AttributeValueMemberS{Value: params.Hash}
+		expressionAttributeValues[":renditions"] = renditions
+	}
+
+	expression := "SET " + strings.Join(set, ", ")
+	if len(remove) > 0 {
+		expression += " REMOVE " + strings.Join(remove, ", ")
+	}
+
+	var (
+		key       = variantKey(params.ProductUuid, params.Uuid)
+		condition = "attribute_exists(#uuid) AND " + qrcodeCondition(current.QRCode, expressionAttributeValues)
+		names     = map[string]string{
+			"#uuid":    "uuid",
+			"#options": "options",
+		}
+	)
+
+	locks, err := p.qrcodeLocks(ctx, params.ProductUuid, params.Uuid, current.QRCode, params.QRCode, params.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(locks) > 0 {
+		update := transactWrite{
+			item: types.TransactWriteItem{
+				Update: &types.Update{
+					TableName:                           aws.String(p.variantTable),
+					Key:                                 key,
+					UpdateExpression:                    aws.String(expression),
+					ConditionExpression:                 aws.String(condition),
+					ExpressionAttributeValues:           expressionAttributeValues,
+					ExpressionAttributeNames:            names,
+					ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+				},
+			},
+			failed: func(reason types.CancellationReason) error {
+				return variantChanged(reason.Item)
+			},
+		}
+		if err = p.writeTransaction(ctx, append([]transactWrite{update}, locks...), "updating item"); err != nil {
+			return nil, err
+		}
+		return p.GetVariant(ctx, params.ProductUuid, params.Uuid)
+	}
+
+	result, err := p.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                           aws.String(p.variantTable),
+		Key:                                 key,
+		UpdateExpression:                    aws.String(expression),
+		ConditionExpression:                 aws.String(condition),
+		ExpressionAttributeValues:           expressionAttributeValues,
+		ExpressionAttributeNames:            names,
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		var errf *types.ConditionalCheckFailedException
+		if errors.As(err, &errf) {
+			return nil, variantChanged(errf.Item)
+		}
+
+		return nil, fmt.Errorf("error updating item: %w", err)
+	}
+
+	var variant VariantTable
+	if err = attributevalue.UnmarshalMap(result.Attributes, &variant); err != nil {
+		return nil, fmt.Errorf("error marshaling item: %w", err)
+	}
+
+	return assembleVariant(variant), nil
+}
+
+// DelVariant deletes a variant and releases the lock of its QR code in the
+// same transaction.
+func (p *Product) DelVariant(ctx context.Context, productUuid, uuid string) (*models.Variant, error) {
+	variant, err := p.getVariant(ctx, productUuid, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	locks, err := p.qrcodeLocks(ctx, productUuid, uuid, variant.QRCode, "", time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]types.AttributeValue{}
+	writes := []transactWrite{
+		{
+			item: types.TransactWriteItem{
+				Delete: &types.Delete{
+					TableName:           aws.String(p.variantTable),
+					Key:                 variantKey(productUuid, uuid),
+					ConditionExpression: aws.String("attribute_exists(#uuid) AND " + qrcodeCondition(variant.QRCode, values)),
+					ExpressionAttributeNames: map[string]string{
+						"#uuid": "uuid",
+					},
+					ExpressionAttributeValues:           values,
+					ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+				},
+			},
+			failed: func(reason types.CancellationReason) error {
+				return variantChanged(reason.Item)
+			},
+		},
+	}
+	if err = p.writeTransaction(ctx, append(writes, locks...), "deleting item"); err != nil {
+		return nil, err
+	}
+
+	return assembleVariant(*variant), nil
+}
+
+// variantChanged tells a variant deleted since it was read from one whose QR
+// code changed meanwhile.
+func variantChanged(item map[string]types.AttributeValue) error {
+	if len(item) == 0 {
+		return domain.ErrNotFound
+	}
+	return domain.ErrVersionMismatch
+}
+
+func variantKey(productUuid, uuid string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"product_uuid": &types.AttributeValueMemberS{Value: productUuid},
+		"uuid":         &types.AttributeValueMemberS{Value: uuid},
+	}
+}
+
+func assembleVariants(items []map[string]types.AttributeValue) (models.Variants, error) {
+	variants := make(models.Variants, len(items))
+	for i, item := range items {
+		var variant VariantTable
+		if err := attributevalue.UnmarshalMap(item, &variant); err != nil {
+			return nil, fmt.Errorf("error unmarshaling item: %w", err)
+		}
+		variants[i] = assembleVariant(variant)
+	}
+	return variants, nil
+}
+
+func assembleVariant(variant VariantTable) *models.Variant {
+	kind := barcode.Type(variant.BarcodeType)
+	if kind == "" {
+		kind = barcode.QRCode
+	}
+
+	var price *money.Money
+	if variant.PriceAmount != nil {
+		price = &money.Money{
+			Amount:   *variant.PriceAmount,
+			Currency: variant.PriceCurrency,
+		}
+	}
+	return &models.Variant{
+		Uuid:        variant.Uuid,
+		ProductUuid: variant.ProductUuid,
+		Sku:         variant.Sku,
+		Options:     variant.Options,
+		Price:       price,
+		QRCode:      barcode.Format(kind, variant.QRCode),
+		BarcodeType: string(kind),
+		Image:       variant.Image,
+		ImageHash:   variant.ImageHash,
+		Renditions:  assembleRenditions(variant.Renditions),
+		Stock:       variant.Stock,
+		CreatedAt:   variant.CreatedAt,
+		UpdatedAt:   variant.UpdatedAt,
+	}
+}
@@ -0,0 +1,71 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"testing"
+	"time"
+)
+
+func TestVariantQRCodeLocks(t *testing.T) {
+	const (
+		product = "7a8b9c0d-1e2f-4a3b-8c4d-5e6f7a8b9c01"
+		first   = "8b9c0d1e-2f3a-4b4c-9d5e-6f7a8b9c0d12"
+		second  = "9c0d1e2f-3a4b-4c5d-8e6f-7a8b9c0d1e23"
+	)
+
+	fake := newFakeDynamo()
+	fake.put("product_qrcode", item{"qrcode": {"S": "COLA"}, "product_uuid": {"S": product}})
+	repository := fake.repository(t)
+	ctx := context.Background()
+
+	variant := func(uuid, qrcode string) domain.VariantParams {
+		now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		return domain.VariantParams{Uuid: uuid, ProductUuid: product, Sku: uuid, QRCode: qrcode, CreatedAt: now, UpdatedAt: now}
+	}
+	owner := func(qrcode string) string {
+		lock, ok := fake.items["product_qrcode"][qrcode]
+		if !ok {
+			return ""
+		}
+		uuid, _ := lock["variant_uuid"]["S"].(string)
+		return uuid
+	}
+
+	if _, err := repository.AddVariant(ctx, variant(first, "COLA-1L")); err != nil {
+		t.Fatalf("AddVariant() error = %v", err)
+	}
+	if got := owner("COLA-1L"); got != first {
+		t.Errorf("COLA-1L locked by %q, want %q", got, first)
+	}
+
+	for _, code := range []string{"COLA-1L", "COLA"} {
+		if _, err := repository.AddVariant(ctx, variant(second, code)); !errors.Is(err, domain.ErrQRCode) {
+			t.Errorf("AddVariant() with the taken code %s error = %v, want %v", code, err, domain.ErrQRCode)
+		}
+	}
+	if _, ok := fake.items["product_variant"][second]; ok {
+		t.Error("variant with a taken code was stored")
+	}
+
+	if _, err := repository.PutVariant(ctx, variant(first, "COLA-2L")); err != nil {
+		t.Fatalf("PutVariant() error = %v", err)
+	}
+	if got := owner("COLA-1L"); got != "" {
+		t.Errorf("COLA-1L still locked by %q", got)
+	}
+	if got := owner("COLA-2L"); got != first {
+		t.Errorf("COLA-2L locked by %q, want %q", got, first)
+	}
+
+	if _, err := repository.DelVariant(ctx, product, first); err != nil {
+		t.Fatalf("DelVariant() error = %v", err)
+	}
+	if got := owner("COLA-2L"); got != "" {
+		t.Errorf("COLA-2L still locked by %q", got)
+	}
+	if _, ok := fake.items["product_qrcode"]["COLA"]; !ok {
+		t.Error("product lock was released")
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+type CollectReport struct {
+	DryRun     bool      `json:"dry_run"`
+	Before     string    `json:"before"`
+	Scanned    int       `json:"scanned"`
+	Referenced int       `json:"referenced"`
+	Orphans    []*Orphan `json:"orphans"`
+	Deleted    int       `json:"deleted"`
+	Bytes      int64     `json:"bytes"`
+}
+
+type Orphan struct {
+	Key          string `json:"key"`
+	Size         int64  `json:"size"`
+	LastModified string `json:"last_modified"`
+}
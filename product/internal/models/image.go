@@ -0,0 +1,25 @@
+package models
+
+type Images []*Image
+type Image struct {
+	Uuid       string       `json:"uuid"`
+	Hash       string       `json:"-"`
+	Url        string       `json:"url"`
+	Alt        string       `json:"alt"`
+	Primary    bool         `json:"primary"`
+	Renditions []*Rendition `json:"renditions,omitempty"`
+}
+
+type Rendition struct {
+	Width  int    `json:"width"`
+	Format string `json:"format"`
+	Url    string `json:"url"`
+}
+
+type ImageReference struct {
+	Hash       string
+	References int64
+	Renditions []*Rendition
+	CreatedAt  string
+	UpdatedAt  string
+}
@@ -0,0 +1,55 @@
+package models
+
+type Ledger []*LedgerEntry
+type LedgerEntry struct {
+	Uuid         string `json:"uuid"`
+	ProductUuid  string `json:"product_uuid"`
+	VariantUuid  string `json:"variant_uuid,omitempty"`
+	LocationUuid string `json:"location_uuid,omitempty"`
+	TransferUuid string `json:"transfer_uuid,omitempty"`
+	Adjustment   string `json:"adjustment"`
+	Quantity     int64  `json:"quantity"`
+	Reason       string `json:"reason"`
+	Note         string `json:"note,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type VariantStock struct {
+	Uuid     string `json:"uuid"`
+	Sku      string `json:"sku"`
+	Quantity int64  `json:"quantity"`
+}
+
+type Stock struct {
+	ProductUuid string          `json:"product_uuid"`
+	Quantity    int64           `json:"quantity"`
+	Variants    []*VariantStock `json:"variants"`
+	Ledger      Ledger          `json:"ledger"`
+}
+
+type Transfer struct {
+	Uuid string       `json:"uuid"`
+	Out  *LedgerEntry `json:"out"`
+	In   *LedgerEntry `json:"in"`
+}
+
+type LocationStock struct {
+	Location *Location       `json:"location"`
+	Quantity int64           `json:"quantity"`
+	Variants []*VariantStock `json:"variants,omitempty"`
+}
+
+// Availability lists the stock of a product by location, Unallocated is the
+// stock adjusted without a location.
+type Availability struct {
+	ProductUuid string           `json:"product_uuid"`
+	Quantity    int64            `json:"quantity"`
+	Unallocated int64            `json:"unallocated"`
+	Locations   []*LocationStock `json:"locations"`
+}
+
+type StockLevel struct {
+	LocationUuid string `json:"location_uuid"`
+	VariantUuid  string `json:"variant_uuid,omitempty"`
+	Quantity     int64  `json:"quantity"`
+}
@@ -0,0 +1,12 @@
+package models
+
+type Locations []*Location
+type Location struct {
+	Uuid      string `json:"uuid"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Address   string `json:"address"`
+	Pickup    bool   `json:"pickup"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
@@ -1,19 +1,49 @@
 package models
 
+import "shopy/pkg/money"
+
 type Products []*Product
 type Product struct {
-	Uuid      string   `json:"uuid"`
-	Name      string   `json:"name"`
-	Price     float64  `json:"price"`
-	Image     string   `json:"image"`
-	QRCode    string   `json:"qrcode"`
-	IsTop     bool     `json:"is_top"`
-	Category  Category `json:"category"`
-	CreatedAt string   `json:"created_at"`
-	UpdatedAt string   `json:"updated_at"`
+	Uuid        string      `json:"uuid"`
+	Name        string      `json:"name"`
+	Slug        string      `json:"slug"`
+	Price       money.Money `json:"price"`
+	Image       string      `json:"image"`
+	Images      Images      `json:"images"`
+	QRCode      string      `json:"qrcode"`
+	BarcodeType string      `json:"barcode_type"`
+	IsTop       bool        `json:"is_top"`
+	Category    Category    `json:"category"`
+	Stock       int64       `json:"stock"`
+	Version     int64       `json:"version"`
+	Status      string      `json:"status"`
+	PublishAt   string      `json:"publish_at,omitempty"`
+	UnpublishAt string      `json:"unpublish_at,omitempty"`
+	CreatedAt   string      `json:"created_at"`
+	UpdatedAt   string      `json:"updated_at"`
+	DeletedAt   string      `json:"deleted_at,omitempty"`
+	Variant     *Variant    `json:"variant,omitempty"`
 }
 
 type Category struct {
 	Uuid string `json:"uuid"`
 	Name string `json:"name"`
 }
+
+// QRCodeOwner holds a QR code lock: a product, or one of its variants when
+// the variant UUID is set.
+type QRCodeOwner struct {
+	ProductUuid string
+	VariantUuid string
+}
+
+// BatchItem is the result of one key of a batch lookup, in the order of the
+// request. Keys matching no product are reported with found false.
+type BatchItem struct {
+	Uuid    string   `json:"uuid,omitempty"`
+	QRCode  string   `json:"qrcode,omitempty"`
+	Found   bool     `json:"found"`
+	Product *Product `json:"product,omitempty"`
+}
+
+type BatchItems []*BatchItem
@@ -0,0 +1,9 @@
+package models
+
+type Upload struct {
+	Uuid      string            `json:"uuid"`
+	Url       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt string            `json:"expires_at"`
+}
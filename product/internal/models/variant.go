@@ -0,0 +1,20 @@
+package models
+
+import "shopy/pkg/money"
+
+type Variants []*Variant
+type Variant struct {
+	Uuid        string            `json:"uuid"`
+	ProductUuid string            `json:"product_uuid"`
+	Sku         string            `json:"sku"`
+	Options     map[string]string `json:"options"`
+	Price       *money.Money      `json:"price,omitempty"`
+	QRCode      string            `json:"qrcode,omitempty"`
+	BarcodeType string            `json:"barcode_type"`
+	Image       string            `json:"image,omitempty"`
+	ImageHash   string            `json:"-"`
+	Renditions  []*Rendition      `json:"renditions,omitempty"`
+	Stock       int64             `json:"stock"`
+	CreatedAt   string            `json:"created_at"`
+	UpdatedAt   string            `json:"updated_at"`
+}
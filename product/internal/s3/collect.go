@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"shopy/internal/domain"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// categoryFolder holds the category images stored before they were content
+// addressed, it is only listed by the garbage collector.
+const categoryFolder = "category"
+
+// collectFolders are the folders reconciled against the stored image URLs.
+var collectFolders = []string{imageFolder, folderName, categoryFolder}
+
+// ListImages returns every object stored under the image folders.
+func (p *Product) ListImages(ctx context.Context) ([]domain.StoredImage, error) {
+	var images []domain.StoredImage
+	for _, folder := range collectFolders {
+		paginator := s3.NewListObjectsV2Paginator(p.client, &s3.ListObjectsV2Input{
+			Bucket: aws.String(p.bucket),
+			Prefix: aws.String(folder + "/"),
+		})
+
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("error listing images: %w", err)
+			}
+
+			for _, object := range page.Contents {
+				images = append(images, domain.StoredImage{
+					Key:          aws.ToString(object.Key),
+					Size:         aws.ToInt64(object.Size),
+					LastModified: aws.ToTime(object.LastModified),
+				})
+			}
+		}
+	}
+
+	return images, nil
+}
+
+// ImageKey returns the key of the object behind an image URL.
+func (p *Product) ImageKey(location string) (string, error) {
+	return objectKey(location, collectFolders...)
+}
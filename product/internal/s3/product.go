@@ -5,59 +5,99 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"net/http"
+	"net/url"
 	"os"
-	"path"
+	"shopy/internal/domain"
+	"shopy/pkg/imagex"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
-const folderName = "product"
+const (
+	folderName = "product"
+	// imageFolder holds the renditions of every image keyed by the SHA-256 of
+	// the upload, shared with the categories.
+	imageFolder = domain.ImageFolder
+)
+
+// deleteBatch is the most keys a DeleteObjects request accepts.
+const deleteBatch = 1000
+
+// cacheControl lets clients and CDNs cache renditions forever, the content
+// behind a key never changes.
+const cacheControl = "public, max-age=31536000, immutable"
 
 type Product struct {
-	logger *slog.Logger
-	client *s3.Client
-	bucket string
+	logger    *slog.Logger
+	client    *s3.Client
+	bucket    string
+	processor *imagex.Processor
 }
 
 func NewProduct(logger *slog.Logger, client *s3.Client) *Product {
 	bucket := os.Getenv("BUCKET_NAME")
+	widths, err := imagex.ParseWidths(os.Getenv("IMAGE_WIDTHS"))
+	if err != nil {
+		logger.Error("invalid image widths, using defaults", "error", err)
+	}
 	return &Product{
-		logger: logger,
-		client: client,
-		bucket: bucket,
+		logger:    logger,
+		client:    client,
+		bucket:    bucket,
+		processor: imagex.NewProcessor(widths),
 	}
 }
 
-func (p *Product) UploadImage(ctx context.Context, uuid string, image []byte) (string, error) {
+// UploadImage renders the image into its renditions and stores them under
+// the content hash of the upload.
+func (p *Product) UploadImage(ctx context.Context, hash string, image []byte) (domain.Renditions, error) {
+	processed, err := p.processor.Process(image)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
-		contentType = http.DetectContentType(image)
-		filename    = fmt.Sprintf("%s.%s", uuid, "png")
-		filepath    = fmt.Sprintf("%s/%s", folderName, filename)
-		uploader    = manager.NewUploader(p.client)
+		renditions = make(domain.Renditions, len(processed))
+		uploader   = manager.NewUploader(p.client)
 	)
+	for i, rendition := range processed {
+		var (
+			filename = fmt.Sprintf("%d.%s", rendition.Width, rendition.Format.Extension())
+			filepath = fmt.Sprintf("%s/%s/%s", imageFolder, hash, filename)
+		)
 
-	result, err := uploader.Upload(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(p.bucket),
-		Key:         aws.String(filepath),
-		Body:        bytes.NewReader(image),
-		ContentType: aws.String(contentType),
-	})
-	if err != nil {
-		return "", fmt.Errorf("error uploading image: %w", err)
+		result, err := uploader.Upload(ctx, &s3.PutObjectInput{
+			Bucket:       aws.String(p.bucket),
+			Key:          aws.String(filepath),
+			Body:         bytes.NewReader(rendition.Data),
+			ContentType:  aws.String(rendition.Format.ContentType()),
+			CacheControl: aws.String(cacheControl),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error uploading image: %w", err)
+		}
+
+		renditions[i] = domain.Rendition{
+			Width:    rendition.Width,
+			Format:   string(rendition.Format),
+			Location: result.Location,
+		}
 	}
 
-	return result.Location, nil
+	return renditions, nil
 }
 
 func (p *Product) DeleteImage(ctx context.Context, location string) error {
-	var (
-		filename = path.Base(location)
-		filepath = fmt.Sprintf("%s/%s", folderName, filename)
-	)
-	_, err := p.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+	filepath, err := objectKey(location, imageFolder, folderName)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(p.bucket),
 		Key:    aws.String(filepath),
 	})
@@ -67,3 +107,76 @@ func (p *Product) DeleteImage(ctx context.Context, location string) error {
 
 	return nil
 }
+
+// DeleteImages deletes every image stored under prefix in the product folder,
+// the UUID of the product that owned them before images were content
+// addressed.
+func (p *Product) DeleteImages(ctx context.Context, prefix string) error {
+	paginator := s3.NewListObjectsV2Paginator(p.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(p.bucket),
+		Prefix: aws.String(fmt.Sprintf("%s/%s/", folderName, prefix)),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("error listing images: %w", err)
+		}
+		if len(page.Contents) == 0 {
+			continue
+		}
+
+		keys := make([]string, len(page.Contents))
+		for i, object := range page.Contents {
+			keys[i] = aws.ToString(object.Key)
+		}
+
+		if err = p.DeleteObjects(ctx, keys); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteObjects deletes the objects stored under keys, in batches of the
+// most keys a single request accepts.
+func (p *Product) DeleteObjects(ctx context.Context, keys []string) error {
+	for start := 0; start < len(keys); start += deleteBatch {
+		end := min(start+deleteBatch, len(keys))
+
+		objects := make([]types.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		_, err := p.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(p.bucket),
+			Delete: &types.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("error deleting images: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// objectKey returns the key of the object behind an image URL stored in one
+// of folders, for both virtual-hosted and path-style URLs.
+func objectKey(location string, folders ...string) (string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("error parsing image location: %w", err)
+	}
+
+	for _, folder := range folders {
+		if i := strings.Index(u.Path, "/"+folder+"/"); i >= 0 {
+			return u.Path[i+1:], nil
+		}
+	}
+	return "", fmt.Errorf("image %q is not in the %s folders", location, strings.Join(folders, ", "))
+}
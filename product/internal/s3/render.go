@@ -0,0 +1,58 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"shopy/internal/domain"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// renderCacheControl lets clients cache a render while the bucket keeps it,
+// the content behind a key never changes.
+const renderCacheControl = "public, max-age=86400"
+
+// GetRender returns the URL of a cached render, or ErrNotFound when it was
+// never rendered or has expired.
+func (p *Product) GetRender(ctx context.Context, key string) (string, error) {
+	_, err := p.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return "", domain.ErrNotFound
+		}
+		return "", fmt.Errorf("error getting render: %w", err)
+	}
+
+	return p.objectURL(key), nil
+}
+
+// PutRender caches a render and returns its URL.
+func (p *Product) PutRender(ctx context.Context, key, contentType string, render []byte) (string, error) {
+	result, err := manager.NewUploader(p.client).Upload(ctx, &s3.PutObjectInput{
+		Bucket:       aws.String(p.bucket),
+		Key:          aws.String(key),
+		Body:         bytes.NewReader(render),
+		ContentType:  aws.String(contentType),
+		CacheControl: aws.String(renderCacheControl),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error uploading render: %w", err)
+	}
+
+	return result.Location, nil
+}
+
+// objectURL returns the virtual-hosted URL of an object, the form the
+// uploader reports.
+func (p *Product) objectURL(key string) string {
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", p.bucket, p.client.Options().Region, key)
+}
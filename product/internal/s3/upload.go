@@ -0,0 +1,85 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// uploadExpires bounds how long a presigned upload URL can be used.
+const uploadExpires = 15 * time.Minute
+
+// PresignUpload returns a URL the client uploads the image to with a PUT
+// request, the content type and length are signed so the object stored must
+// match them.
+func (p *Product) PresignUpload(ctx context.Context, params domain.UploadParams) (*models.Upload, error) {
+	request, err := s3.NewPresignClient(p.client).PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(p.bucket),
+		Key:           aws.String(domain.UploadKey(params.Uuid)),
+		ContentType:   aws.String(params.ContentType),
+		ContentLength: aws.Int64(params.Size),
+	}, s3.WithPresignExpires(uploadExpires))
+	if err != nil {
+		return nil, fmt.Errorf("error presigning upload: %w", err)
+	}
+
+	headers := make(map[string]string, len(request.SignedHeader))
+	for name := range request.SignedHeader {
+		if name == "Host" {
+			continue
+		}
+		headers[name] = request.SignedHeader.Get(name)
+	}
+
+	return &models.Upload{
+		Uuid:      params.Uuid,
+		Url:       request.URL,
+		Method:    request.Method,
+		Headers:   headers,
+		ExpiresAt: params.CreatedAt.Add(uploadExpires).Format(time.DateTime),
+	}, nil
+}
+
+// GetUpload reads an uploaded image, at most maxBytes+1 bytes are read so
+// oversized objects are detected without loading them whole.
+func (p *Product) GetUpload(ctx context.Context, uuid string, maxBytes int) ([]byte, error) {
+	object, err := p.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(domain.UploadKey(uuid)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, domain.ErrUpload
+		}
+		return nil, fmt.Errorf("error getting upload: %w", err)
+	}
+	defer object.Body.Close()
+
+	image, err := io.ReadAll(io.LimitReader(object.Body, int64(maxBytes)+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading upload: %w", err)
+	}
+
+	return image, nil
+}
+
+func (p *Product) DeleteUpload(ctx context.Context, uuid string) error {
+	_, err := p.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(domain.UploadKey(uuid)),
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting upload: %w", err)
+	}
+
+	return nil
+}
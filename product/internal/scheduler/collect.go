@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"strconv"
+	"time"
+)
+
+const defaultGraceHours = 24
+
+type CollectService interface {
+	CollectImages(ctx context.Context, params domain.CollectParams) (*models.CollectReport, error)
+}
+
+type Collect struct {
+	logger  *slog.Logger
+	service CollectService
+	grace   time.Duration
+	dryRun  bool
+}
+
+func NewCollect(logger *slog.Logger, service CollectService) *Collect {
+	hours, err := strconv.Atoi(os.Getenv("GRACE_HOURS"))
+	if err != nil || hours < 0 {
+		logger.Warn("invalid grace hours, using default", "default", defaultGraceHours)
+		hours = defaultGraceHours
+	}
+	dryRun, _ := strconv.ParseBool(os.Getenv("DRY_RUN"))
+	return &Collect{
+		logger:  logger,
+		service: service,
+		grace:   time.Duration(hours) * time.Hour,
+		dryRun:  dryRun,
+	}
+}
+
+// CollectEvent is the input of the collector, scheduled events carry no
+// dry_run and use the configured mode.
+type CollectEvent struct {
+	DryRun *bool `json:"dry_run"`
+}
+
+// Handler deletes the images no product, variant or category points at that
+// are older than the grace period, or only reports them in dry-run mode.
+func (c *Collect) Handler() func(ctx context.Context, event CollectEvent) (*models.CollectReport, error) {
+	return func(ctx context.Context, event CollectEvent) (*models.CollectReport, error) {
+		params := domain.CollectParams{
+			Before: time.Now().UTC().Add(-c.grace),
+			DryRun: c.dryRun,
+		}
+		if event.DryRun != nil {
+			params.DryRun = *event.DryRun
+		}
+
+		report, err := c.service.CollectImages(ctx, params)
+		if err != nil {
+			c.logger.Error("error collecting images", "dry_run", params.DryRun, "error", err)
+			return report, err
+		}
+
+		c.logger.Info("images collected", "dry_run", report.DryRun, "scanned", report.Scanned,
+			"orphans", len(report.Orphans), "deleted", report.Deleted, "bytes", report.Bytes)
+		return report, nil
+	}
+}
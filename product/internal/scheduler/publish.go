@@ -0,0 +1,40 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type PublishService interface {
+	ApplySchedule(ctx context.Context, now time.Time) (int, error)
+}
+
+type Publish struct {
+	logger  *slog.Logger
+	service PublishService
+}
+
+func NewPublish(logger *slog.Logger, service PublishService) *Publish {
+	return &Publish{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// Handler publishes and archives the products whose publish_at or unpublish_at time has been reached.
+func (p *Publish) Handler() func(ctx context.Context, event events.EventBridgeEvent) error {
+	return func(ctx context.Context, event events.EventBridgeEvent) error {
+		now := time.Now().UTC()
+		applied, err := p.service.ApplySchedule(ctx, now)
+		if err != nil {
+			p.logger.Error("error applying product schedule", "applied", applied, "error", err)
+			return err
+		}
+
+		p.logger.Info("product schedule applied", "applied", applied, "now", now.Format(time.DateTime))
+		return nil
+	}
+}
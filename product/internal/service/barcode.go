@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"shopy/internal/domain"
+	"shopy/pkg/barcode"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// patchBarcode checks the code of a patched product against its barcode type,
+// each taken from the patch or else from the stored product, and normalizes
+// the code whenever either changes so it is stored in the form of its type.
+func (p *Product) patchBarcode(ctx context.Context, patch *domain.ProductPatch) error {
+	current, err := p.repository.GetProduct(ctx, patch.Uuid)
+	if err != nil {
+		return err
+	}
+
+	kind := barcode.Type(current.BarcodeType)
+	if patch.BarcodeType != nil {
+		kind = *patch.BarcodeType
+	}
+
+	var code string
+	switch {
+	case patch.QRCode != nil:
+		code = *patch.QRCode
+	case patch.RemoveQRCode:
+	case kind == barcode.QRCode, kind == "":
+		// a GS1 code becomes a QR code in the representation of its type
+		code = barcode.Format(barcode.Type(current.BarcodeType), current.QRCode)
+	default:
+		// the stored code in the representation of the new type
+		code = barcode.Format(kind, barcode.Normalize(kind, current.QRCode))
+	}
+
+	if code == "" {
+		return nil
+	}
+	if err = barcode.Validate(kind, code); err != nil {
+		return domain.ErrParams.Wrap(validation.Errors{"qrcode": err})
+	}
+
+	normalized := barcode.Normalize(kind, code)
+	patch.QRCode = &normalized
+	return nil
+}
+
+// normalizeBarcode returns a code in its stored form with its barcode type,
+// codes sent without a type are free QR codes and stored as sent.
+func normalizeBarcode(code string, kind barcode.Type) (string, barcode.Type) {
+	if kind == "" {
+		kind = barcode.QRCode
+	}
+	return barcode.Normalize(kind, code), kind
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"shopy/pkg/barcode"
+	"testing"
+)
+
+func TestPatchBarcode(t *testing.T) {
+	const uuid = "0b6f9e8c-2d4a-4c1e-9f3b-7a5d6c8e1f20"
+
+	code := func(code string) *string { return &code }
+	kind := func(kind barcode.Type) *barcode.Type { return &kind }
+
+	tests := []struct {
+		name    string
+		current models.Product
+		patch   domain.ProductPatch
+		want    string
+	}{
+		{
+			name:  "QR code of GS1 digits",
+			patch: domain.ProductPatch{QRCode: code("036000291452"), BarcodeType: kind(barcode.QRCode)},
+			want:  "036000291452",
+		},
+		{
+			name:  "UPC-A code",
+			patch: domain.ProductPatch{QRCode: code("036000291452"), BarcodeType: kind(barcode.UPCA)},
+			want:  "00036000291452",
+		},
+		{
+			name:    "QR code retyped as UPC-A",
+			current: models.Product{QRCode: "036000291452", BarcodeType: string(barcode.QRCode)},
+			patch:   domain.ProductPatch{BarcodeType: kind(barcode.UPCA)},
+			want:    "00036000291452",
+		},
+		{
+			name:    "UPC-A code retyped as EAN-13",
+			current: models.Product{QRCode: "00036000291452", BarcodeType: string(barcode.UPCA)},
+			patch:   domain.ProductPatch{BarcodeType: kind(barcode.EAN13)},
+			want:    "00036000291452",
+		},
+		{
+			name:    "UPC-A code retyped as QR code",
+			current: models.Product{QRCode: "00036000291452", BarcodeType: string(barcode.UPCA)},
+			patch:   domain.ProductPatch{BarcodeType: kind(barcode.QRCode)},
+			want:    "036000291452",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeRepository()
+			current := tt.current
+			current.Uuid = uuid
+			repository.products[uuid] = &current
+
+			patch := tt.patch
+			patch.Uuid = uuid
+			if err := testService(repository, newFakeStorage()).patchBarcode(context.Background(), &patch); err != nil {
+				t.Fatalf("patchBarcode() error = %v", err)
+			}
+			if patch.QRCode == nil || *patch.QRCode != tt.want {
+				t.Errorf("patched qrcode = %v, want %q", patch.QRCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetProductByQRCode(t *testing.T) {
+	const (
+		qrcodeUuid  = "1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f"
+		upcaUuid    = "2d3e4f5a-6b7c-4d8e-9f0a-1b2c3d4e5f60"
+		variantUuid = "5a6b7c8d-9e0f-4a1b-2c3d-4e5f6a7b8c90"
+	)
+
+	tests := []struct {
+		name        string
+		qrcode      string
+		want        string
+		wantVariant string
+	}{
+		{name: "QR code as sent", qrcode: "036000291452", want: qrcodeUuid},
+		{name: "UPC-A code in another representation", qrcode: "0036000291452", want: upcaUuid},
+		{name: "variant code", qrcode: "LATTE1L", want: qrcodeUuid, wantVariant: variantUuid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeRepository()
+			repository.products[qrcodeUuid] = &models.Product{Uuid: qrcodeUuid, Status: string(domain.StatusPublished)}
+			repository.products[upcaUuid] = &models.Product{Uuid: upcaUuid, Status: string(domain.StatusPublished)}
+			repository.variants[variantUuid] = &models.Variant{Uuid: variantUuid, ProductUuid: qrcodeUuid, QRCode: "LATTE1L"}
+			repository.qrcodes["036000291452"] = &models.QRCodeOwner{ProductUuid: qrcodeUuid}
+			repository.qrcodes["00036000291452"] = &models.QRCodeOwner{ProductUuid: upcaUuid}
+			repository.qrcodes["LATTE1L"] = &models.QRCodeOwner{ProductUuid: qrcodeUuid, VariantUuid: variantUuid}
+
+			product, err := testService(repository, newFakeStorage()).GetProductByQRCode(context.Background(), tt.qrcode, false)
+			if err != nil {
+				t.Fatalf("GetProductByQRCode() error = %v", err)
+			}
+			if product.Uuid != tt.want {
+				t.Errorf("GetProductByQRCode() = %s, want %s", product.Uuid, tt.want)
+			}
+			var variant string
+			if product.Variant != nil {
+				variant = product.Variant.Uuid
+			}
+			if variant != tt.wantVariant {
+				t.Errorf("GetProductByQRCode() variant = %q, want %q", variant, tt.wantVariant)
+			}
+		})
+	}
+}
+
+func TestAddVariantBarcode(t *testing.T) {
+	const (
+		product = "6b7c8d9e-0f1a-4b2c-8d3e-4f5a6b7c8d01"
+		variant = "7c8d9e0f-1a2b-4c3d-9e4f-5a6b7c8d9e12"
+	)
+
+	tests := []struct {
+		name     string
+		code     string
+		kind     barcode.Type
+		want     string
+		wantKind barcode.Type
+	}{
+		{name: "UPC-A code", code: "036000291452", kind: barcode.UPCA, want: "00036000291452", wantKind: barcode.UPCA},
+		{name: "QR code of GS1 digits", code: "036000291452", kind: barcode.QRCode, want: "036000291452", wantKind: barcode.QRCode},
+		{name: "untyped code", code: "LATTE1L", want: "LATTE1L", wantKind: barcode.QRCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeRepository()
+			repository.products[product] = &models.Product{Uuid: product}
+
+			got, err := testService(repository, newFakeStorage()).AddVariant(context.Background(), domain.VariantParams{
+				Uuid:        variant,
+				ProductUuid: product,
+				QRCode:      tt.code,
+				BarcodeType: tt.kind,
+			})
+			if err != nil {
+				t.Fatalf("AddVariant() error = %v", err)
+			}
+			if got.QRCode != tt.want || got.BarcodeType != string(tt.wantKind) {
+				t.Errorf("stored %q as %s, want %q as %s", got.QRCode, got.BarcodeType, tt.want, tt.wantKind)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"shopy/pkg/barcode"
+	"sync"
+)
+
+// variantQueries bounds the concurrent variant queries of a batch, a batch of
+// unknown codes would otherwise start one per code at once.
+const variantQueries = 10
+
+// BatchGetProducts looks up products by UUID or QR code and returns a result
+// for every key in the order of the request. Codes locked by a product are
+// resolved with the products in batch reads, the others are searched among
+// the variant codes with concurrent queries, a variant match returns its
+// parent product with the variant. The forms of a code are tried in the order
+// of barcode.Lookup. Only published products are found unless the request
+// comes from staff.
+func (p *Product) BatchGetProducts(ctx context.Context, params domain.BatchParams) (models.BatchItems, error) {
+	var uuids, qrcodes []string
+	for _, key := range params.Keys {
+		if key.QRCode != "" {
+			qrcodes = append(qrcodes, barcode.Lookup(key.QRCode)...)
+		} else {
+			uuids = append(uuids, key.Uuid)
+		}
+	}
+
+	owners, err := p.repository.GetQRCodeOwners(ctx, qrcodes)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		unowned []string
+		queued  = make(map[string]bool)
+	)
+	for _, qrcode := range qrcodes {
+		if owner, ok := owners[qrcode]; ok && owner.VariantUuid == "" {
+			uuids = append(uuids, owner.ProductUuid)
+		} else if !queued[qrcode] {
+			// variant codes are read with their variant, locked or not
+			queued[qrcode] = true
+			unowned = append(unowned, qrcode)
+		}
+	}
+
+	variants, err := p.variantsByQRCode(ctx, unowned)
+	if err != nil {
+		return nil, err
+	}
+	for _, matches := range variants {
+		for _, variant := range matches {
+			uuids = append(uuids, variant.ProductUuid)
+		}
+	}
+
+	products, err := p.repository.GetProducts(ctx, uuids)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := func(uuid string) (*models.Product, bool) {
+		product, ok := products[uuid]
+		if !ok || !params.Staff && !domain.Status(product.Status).Visible() {
+			return nil, false
+		}
+		return product, true
+	}
+
+	items := make(models.BatchItems, len(params.Keys))
+	for i, key := range params.Keys {
+		item := &models.BatchItem{Uuid: key.Uuid, QRCode: key.QRCode}
+		items[i] = item
+
+		if key.QRCode == "" {
+			item.Product, item.Found = visible(key.Uuid)
+			continue
+		}
+
+		for _, qrcode := range barcode.Lookup(key.QRCode) {
+			if item.Product, item.Found = resolve(owners[qrcode], variants[qrcode], visible); item.Found {
+				break
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// resolve returns the visible product holding a code, or the parent of the
+// variant holding it with the variant attached. A code locked by a variant
+// only matches that variant.
+func resolve(owner *models.QRCodeOwner, variants models.Variants, visible func(uuid string) (*models.Product, bool)) (*models.Product, bool) {
+	if owner != nil && owner.VariantUuid == "" {
+		return visible(owner.ProductUuid)
+	}
+	for _, variant := range variants {
+		if owner != nil && variant.Uuid != owner.VariantUuid {
+			continue
+		}
+		if product, ok := visible(variant.ProductUuid); ok {
+			// products are shared by the keys, the variant belongs to this one
+			withVariant := *product
+			withVariant.Variant = variant
+			return &withVariant, true
+		}
+	}
+	return nil, false
+}
+
+// variantsByQRCode returns the variants matching each QR code, queried
+// concurrently by up to variantQueries at a time. The first failed query
+// cancels the others.
+func (p *Product) variantsByQRCode(ctx context.Context, qrcodes []string) (map[string]models.Variants, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		slots    = make(chan struct{}, variantQueries)
+		variants = make(map[string]models.Variants, len(qrcodes))
+		failed   error
+	)
+	for _, qrcode := range qrcodes {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(qrcode string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			matches, err := p.repository.GetVariantsByQRCode(ctx, qrcode)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if failed == nil {
+					failed = err
+					cancel()
+				}
+				return
+			}
+			variants[qrcode] = matches
+		}(qrcode)
+	}
+	wg.Wait()
+
+	if failed != nil {
+		return nil, failed
+	}
+	if err := ctx.Err(); err != nil {
+		// the request was canceled before every code was queried
+		return nil, err
+	}
+	return variants, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shopy/internal/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+// variantRepository answers the variant queries with query.
+type variantRepository struct {
+	Repository
+
+	query func(ctx context.Context, qrcode string) (models.Variants, error)
+}
+
+func (r *variantRepository) GetVariantsByQRCode(ctx context.Context, qrcode string) (models.Variants, error) {
+	return r.query(ctx, qrcode)
+}
+
+func qrcodes(n int) []string {
+	codes := make([]string, n)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("CODE%d", i)
+	}
+	return codes
+}
+
+func TestVariantsByQRCodeLimit(t *testing.T) {
+	var (
+		mu               sync.Mutex
+		running, highest int
+	)
+	repository := &variantRepository{query: func(_ context.Context, qrcode string) (models.Variants, error) {
+		mu.Lock()
+		running++
+		highest = max(highest, running)
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return models.Variants{{QRCode: qrcode}}, nil
+	}}
+
+	variants, err := testService(repository, newFakeStorage()).variantsByQRCode(context.Background(), qrcodes(50))
+	if err != nil {
+		t.Fatalf("variantsByQRCode() error = %v", err)
+	}
+	if len(variants) != 50 {
+		t.Errorf("variantsByQRCode() matched %d codes, want 50", len(variants))
+	}
+	if highest > variantQueries {
+		t.Errorf("%d concurrent queries, want at most %d", highest, variantQueries)
+	}
+}
+
+func TestVariantsByQRCodeCancel(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		queried int
+	)
+	repository := &variantRepository{query: func(ctx context.Context, qrcode string) (models.Variants, error) {
+		mu.Lock()
+		queried++
+		mu.Unlock()
+
+		if qrcode == "CODE3" {
+			return nil, errFake
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil, nil
+		}
+	}}
+
+	start := time.Now()
+	_, err := testService(repository, newFakeStorage()).variantsByQRCode(context.Background(), qrcodes(50))
+	if !errors.Is(err, errFake) {
+		t.Fatalf("variantsByQRCode() error = %v, want %v", err, errFake)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("variantsByQRCode() took %s, the failure didn't cancel the queries", elapsed)
+	}
+	if queried >= 50 {
+		t.Errorf("%d codes queried, want the failure to stop the queries", queried)
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// category returns the stored category a product is assigned to, its name is
+// the one kept on the product whatever name the client sent.
+func (p *Product) category(ctx context.Context, uuid string) (domain.Category, error) {
+	category, err := p.repository.GetCategory(ctx, uuid)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.Category{}, domain.ErrCategory.Wrap(validation.Errors{
+			"category": validation.Errors{"uuid": errors.New("must reference an existing category")},
+		})
+	}
+	if err != nil {
+		return domain.Category{}, err
+	}
+
+	return domain.Category{
+		Uuid: category.Uuid,
+		Name: category.Name,
+	}, nil
+}
+
+// searchCategoryTree returns the products of a category followed by the
+// products of its subcategories, level by level.
+func (p *Product) searchCategoryTree(ctx context.Context, uuid string) (models.Products, error) {
+	parents, err := p.repository.GetCategoryParents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	products := models.Products{}
+	for _, category := range domain.Descendants(parents, uuid) {
+		found, err := p.repository.GetProductsByCategory(ctx, category)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, found...)
+	}
+	return products, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"sort"
+	"time"
+)
+
+// CollectImages reconciles the stored images with the image URLs of the
+// products, variants and categories, deleting the objects no item points at
+// once they are older than params.Before. Content addressed renditions are
+// kept while any of them is referenced and their reference record is deleted
+// with them. A dry run only reports the orphans.
+func (p *Product) CollectImages(ctx context.Context, params domain.CollectParams) (*models.CollectReport, error) {
+	locations, err := p.repository.GetImageLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		referenced = make(map[string]bool, len(locations))
+		hashes     = make(map[string]bool)
+	)
+	for _, location := range locations {
+		key, err := p.storage.ImageKey(location)
+		if err != nil {
+			p.logger.Warn("image outside the collected folders", "location", location, "error", err)
+			continue
+		}
+		referenced[key] = true
+		if hash, ok := domain.ContentHash(key); ok {
+			hashes[hash] = true
+		}
+	}
+
+	stored, err := p.storage.ListImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		orphans []domain.StoredImage
+		byHash  = make(map[string][]domain.StoredImage)
+		report  = &models.CollectReport{
+			DryRun:  params.DryRun,
+			Before:  params.Before.Format(time.DateTime),
+			Scanned: len(stored),
+			Orphans: []*models.Orphan{},
+		}
+	)
+	for _, image := range stored {
+		hash, content := domain.ContentHash(image.Key)
+		switch {
+		case referenced[image.Key] || content && hashes[hash]:
+			report.Referenced++
+		case !image.LastModified.Before(params.Before):
+			// still within the grace period, it may belong to a write in progress
+		case content:
+			byHash[hash] = append(byHash[hash], image)
+		default:
+			orphans = append(orphans, image)
+		}
+	}
+
+	for hash, images := range byHash {
+		if !params.DryRun {
+			deleted, err := p.repository.DelImageReference(ctx, hash, params.Before)
+			if err != nil {
+				return report, err
+			}
+			if !deleted {
+				// acquired again since the scan
+				continue
+			}
+		}
+		orphans = append(orphans, images...)
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].Key < orphans[j].Key
+	})
+
+	keys := make([]string, len(orphans))
+	for i, orphan := range orphans {
+		keys[i] = orphan.Key
+		report.Bytes += orphan.Size
+		report.Orphans = append(report.Orphans, &models.Orphan{
+			Key:          orphan.Key,
+			Size:         orphan.Size,
+			LastModified: orphan.LastModified.UTC().Format(time.DateTime),
+		})
+		p.logger.Info("orphaned image", "key", orphan.Key, "size", orphan.Size, "dry_run", params.DryRun)
+	}
+
+	if params.DryRun || len(keys) == 0 {
+		return report, nil
+	}
+
+	if err = p.storage.DeleteObjects(ctx, keys); err != nil {
+		return report, err
+	}
+
+	report.Deleted = len(keys)
+	return report, nil
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"shopy/pkg/imagex"
+	"time"
+)
+
+var errFake = errors.New("fake failure")
+
+// fakeRepository keeps categories, slugs, products, variants, QR code locks
+// and image references in memory. The methods a test doesn't use panic through the nil Repository.
+type fakeRepository struct {
+	Repository
+
+	categories map[string]*models.Category
+	slugs      map[string]string
+	products   map[string]*models.Product
+	variants   map[string]*models.Variant
+	qrcodes    map[string]*models.QRCodeOwner
+	references map[string]*models.ImageReference
+
+	// addErr fails the product writes
+	addErr error
+	// race runs before each product write with the slug being written,
+	// standing for a concurrent write
+	race func(slug string)
+	adds int
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		categories: map[string]*models.Category{},
+		slugs:      map[string]string{},
+		products:   map[string]*models.Product{},
+		variants:   map[string]*models.Variant{},
+		qrcodes:    map[string]*models.QRCodeOwner{},
+		references: map[string]*models.ImageReference{},
+	}
+}
+
+func (r *fakeRepository) GetCategory(_ context.Context, uuid string) (*models.Category, error) {
+	category, ok := r.categories[uuid]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return category, nil
+}
+
+func (r *fakeRepository) GetSlugOwner(_ context.Context, slug string) (string, error) {
+	owner, ok := r.slugs[slug]
+	if !ok {
+		return "", domain.ErrNotFound
+	}
+	return owner, nil
+}
+
+func (r *fakeRepository) GetProduct(_ context.Context, uuid string) (*models.Product, error) {
+	product, ok := r.products[uuid]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return product, nil
+}
+
+func (r *fakeRepository) GetVariant(_ context.Context, productUuid, uuid string) (*models.Variant, error) {
+	variant, ok := r.variants[uuid]
+	if !ok || variant.ProductUuid != productUuid {
+		return nil, domain.ErrNotFound
+	}
+	return variant, nil
+}
+
+func (r *fakeRepository) AddVariant(_ context.Context, params domain.VariantParams) (*models.Variant, error) {
+	variant := &models.Variant{
+		Uuid:        params.Uuid,
+		ProductUuid: params.ProductUuid,
+		Sku:         params.Sku,
+		QRCode:      params.QRCode,
+		BarcodeType: string(params.BarcodeType),
+	}
+	r.variants[params.Uuid] = variant
+	return variant, nil
+}
+
+func (r *fakeRepository) GetQRCodeOwner(_ context.Context, qrcode string) (*models.QRCodeOwner, error) {
+	owner, ok := r.qrcodes[qrcode]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return owner, nil
+}
+
+func (r *fakeRepository) AddProduct(_ context.Context, params domain.ProductParams) (*models.Product, error) {
+	r.adds++
+	if r.race != nil {
+		r.race(params.Slug)
+	}
+	if r.addErr != nil {
+		return nil, r.addErr
+	}
+	if _, ok := r.slugs[params.Slug]; ok {
+		return nil, domain.ErrSlug
+	}
+	r.slugs[params.Slug] = params.Uuid
+
+	product := &models.Product{Uuid: params.Uuid, Name: params.Name, Slug: params.Slug, Version: 1}
+	r.products[params.Uuid] = product
+	return product, nil
+}
+
+func (r *fakeRepository) AcquireImage(_ context.Context, hash string, updatedAt time.Time) (*models.ImageReference, error) {
+	reference, ok := r.references[hash]
+	if !ok {
+		reference = &models.ImageReference{Hash: hash}
+		r.references[hash] = reference
+	}
+	reference.References++
+	return reference, nil
+}
+
+func (r *fakeRepository) PutImageRenditions(_ context.Context, hash string, renditions domain.Renditions) error {
+	for _, rendition := range renditions {
+		r.references[hash].Renditions = append(r.references[hash].Renditions, &models.Rendition{
+			Width:  rendition.Width,
+			Format: rendition.Format,
+			Url:    rendition.Location,
+		})
+	}
+	return nil
+}
+
+func (r *fakeRepository) ReleaseImage(_ context.Context, hash string) (bool, error) {
+	reference, ok := r.references[hash]
+	if !ok {
+		return false, nil
+	}
+	reference.References--
+	if reference.References > 0 {
+		return false, nil
+	}
+	delete(r.references, hash)
+	return true, nil
+}
+
+// fakeStorage keeps the uploaded renditions and the renders in memory.
+type fakeStorage struct {
+	Storage
+
+	objects map[string]bool
+
+	// deleteErr fails the image deletions
+	deleteErr error
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{objects: map[string]bool{}}
+}
+
+func (s *fakeStorage) UploadImage(_ context.Context, hash string, _ []byte) (domain.Renditions, error) {
+	var renditions domain.Renditions
+	for _, width := range []int{320, 1280} {
+		location := fmt.Sprintf("https://cdn.example.com/images/%s/%d.webp", hash, width)
+		s.objects[location] = true
+		renditions = append(renditions, domain.Rendition{Width: width, Format: "webp", Location: location})
+	}
+	return renditions, nil
+}
+
+func (s *fakeStorage) DeleteImage(_ context.Context, image string) error {
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	delete(s.objects, image)
+	return nil
+}
+
+func (s *fakeStorage) DeleteUpload(context.Context, string) error {
+	return nil
+}
+
+func (s *fakeStorage) GetRender(_ context.Context, key string) (string, error) {
+	location := "https://cdn.example.com/renders/" + key
+	if !s.objects[location] {
+		return "", domain.ErrNotFound
+	}
+	return location, nil
+}
+
+func (s *fakeStorage) PutRender(_ context.Context, key, _ string, _ []byte) (string, error) {
+	location := "https://cdn.example.com/renders/" + key
+	s.objects[location] = true
+	return location, nil
+}
+
+func testService(repository Repository, storage Storage) *Product {
+	return &Product{
+		logger:     slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		repository: repository,
+		storage:    storage,
+		limits:     imagex.Limits{MaxBytes: imagex.DefaultMaxBytes, MaxDimension: imagex.DefaultMaxDimension},
+	}
+}
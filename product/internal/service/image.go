@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"shopy/pkg/saga"
+	"time"
+)
+
+// galleryRetries bounds the attempts to write a gallery modified concurrently.
+const galleryRetries = 3
+
+func (p *Product) AddImage(ctx context.Context, params domain.ImageParams) (*models.Product, error) {
+	if _, err := p.repository.GetProduct(ctx, params.ProductUuid); err != nil {
+		return nil, err
+	}
+
+	image, err := p.uploadedImage(ctx, params.Image, params.Upload)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := saga.New(p.logger)
+	hash, renditions, err := p.storeImage(ctx, tx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	var product *models.Product
+	err = tx.Do(ctx, "add image", func(ctx context.Context) (err error) {
+		product, err = p.updateImages(ctx, params.ProductUuid, params.UpdatedAt, func(images domain.Images) (domain.Images, error) {
+			return images.Add(domain.Image{
+				Uuid:       params.Uuid,
+				Hash:       hash,
+				Location:   renditions.Location(),
+				Alt:        params.Alt,
+				Primary:    params.Primary,
+				Renditions: renditions,
+			}), nil
+		})
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p.discardUpload(ctx, params.Upload)
+	return product, nil
+}
+
+func (p *Product) PatchImage(ctx context.Context, patch domain.ImagePatch) (*models.Product, error) {
+	return p.updateImages(ctx, patch.ProductUuid, patch.UpdatedAt, func(images domain.Images) (domain.Images, error) {
+		images, ok := images.Update(patch.Uuid, patch.Alt, patch.Primary)
+		if !ok {
+			return nil, domain.ErrNotFound
+		}
+		return images, nil
+	})
+}
+
+func (p *Product) ReorderImages(ctx context.Context, order domain.ImageOrder) (*models.Product, error) {
+	return p.updateImages(ctx, order.ProductUuid, order.UpdatedAt, func(images domain.Images) (domain.Images, error) {
+		return images.Reorder(order.Uuids)
+	})
+}
+
+func (p *Product) DelImage(ctx context.Context, productUuid, uuid string) (*models.Product, error) {
+	var removed *domain.Image
+	product, err := p.updateImages(ctx, productUuid, time.Now().UTC(), func(images domain.Images) (domain.Images, error) {
+		images, removed = images.Remove(uuid)
+		if removed == nil {
+			return nil, domain.ErrNotFound
+		}
+		return images, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	saga.New(p.logger).Finally(ctx, "delete image", func(ctx context.Context) error {
+		return p.deleteImage(ctx, removed)
+	})
+	return product, nil
+}
+
+// updateImages applies change to the current gallery of a product and writes
+// it back at the version that was read, retrying when the product changed in
+// between.
+func (p *Product) updateImages(ctx context.Context, uuid string, updatedAt time.Time, change func(domain.Images) (domain.Images, error)) (*models.Product, error) {
+	for attempt := 1; ; attempt++ {
+		product, err := p.repository.GetProduct(ctx, uuid)
+		if err != nil {
+			return nil, err
+		}
+
+		images, err := change(gallery(product))
+		if err != nil {
+			return nil, err
+		}
+
+		product, err = p.repository.PutImages(ctx, domain.GalleryParams{
+			Uuid:      uuid,
+			Images:    images,
+			Version:   product.Version,
+			UpdatedAt: updatedAt,
+		})
+		if errors.Is(err, domain.ErrVersionMismatch) && attempt < galleryRetries {
+			continue
+		}
+		return product, err
+	}
+}
+
+// replacePrimary uploads image as steps of tx and returns the gallery with it
+// in place of the primary image, together with the image it replaces.
+func (p *Product) replacePrimary(ctx context.Context, tx *saga.Saga, productUuid, uuid string, image []byte) (domain.Images, *domain.Image, error) {
+	current, err := p.repository.GetProduct(ctx, productUuid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hash, renditions, err := p.storeImage(ctx, tx, image)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	images, replaced := gallery(current).ReplacePrimary(domain.Image{
+		Uuid:       uuid,
+		Hash:       hash,
+		Location:   renditions.Location(),
+		Renditions: renditions,
+	})
+	return images, replaced, nil
+}
+
+// purgeImages deletes every image owned by a product, including the images
+// stored under the product folder before they were content addressed.
+func (p *Product) purgeImages(ctx context.Context, product *models.Product) error {
+	for _, image := range gallery(product) {
+		if err := p.deleteImage(ctx, &image); err != nil {
+			return err
+		}
+	}
+	return p.storage.DeleteImages(ctx, product.Uuid)
+}
+
+// storeImage stores an image under its content hash as steps of tx and
+// returns the hash with the renditions, which are only rendered and uploaded
+// for the first reference to the content. When tx is compensated the
+// reference is released and the renditions deleted if it was the last one.
+func (p *Product) storeImage(ctx context.Context, tx *saga.Saga, image []byte) (string, domain.Renditions, error) {
+	var (
+		hash   = domain.ImageHash(image)
+		stored domain.Renditions
+	)
+
+	err := tx.Do(ctx, "acquire image", func(ctx context.Context) error {
+		reference, err := p.repository.AcquireImage(ctx, hash, time.Now().UTC())
+		if err == nil {
+			stored = renditions(reference.Renditions)
+		}
+		return err
+	}, func(ctx context.Context) error {
+		return p.deleteImage(ctx, &domain.Image{Hash: hash, Renditions: stored})
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if len(stored) > 0 {
+		return hash, stored, nil
+	}
+
+	err = tx.Do(ctx, "upload image", func(ctx context.Context) (err error) {
+		stored, err = p.storage.UploadImage(ctx, hash, image)
+		return err
+	}, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = tx.Do(ctx, "record renditions", func(ctx context.Context) error {
+		return p.repository.PutImageRenditions(ctx, hash, stored)
+	}, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return hash, stored, nil
+}
+
+// deleteImage releases the reference of a content addressed image and only
+// deletes its renditions with the last reference, images stored before are
+// owned by a single product and always deleted.
+func (p *Product) deleteImage(ctx context.Context, image *domain.Image) error {
+	if image == nil {
+		return nil
+	}
+	if image.Hash != "" {
+		last, err := p.repository.ReleaseImage(ctx, image.Hash)
+		if err != nil || !last {
+			return err
+		}
+	}
+	// one object failing to delete doesn't keep the others
+	var errs []error
+	for _, location := range image.Locations() {
+		if err := p.storage.DeleteImage(ctx, location); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// gallery converts the images of a product back to its domain gallery.
+func gallery(product *models.Product) domain.Images {
+	images := make(domain.Images, len(product.Images))
+	for i, image := range product.Images {
+		images[i] = domain.Image{
+			Uuid:       image.Uuid,
+			Hash:       image.Hash,
+			Location:   image.Url,
+			Alt:        image.Alt,
+			Primary:    image.Primary,
+			Renditions: renditions(image.Renditions),
+		}
+	}
+	return images
+}
+
+func renditions(renditions []*models.Rendition) domain.Renditions {
+	if len(renditions) == 0 {
+		return nil
+	}
+	converted := make(domain.Renditions, len(renditions))
+	for i, rendition := range renditions {
+		converted[i] = domain.Rendition{
+			Width:    rendition.Width,
+			Format:   rendition.Format,
+			Location: rendition.Url,
+		}
+	}
+	return converted
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"sort"
+)
+
+func (p *Product) AdjustStock(ctx context.Context, params domain.StockParams) (*models.LedgerEntry, error) {
+	return p.repository.AdjustStock(ctx, params)
+}
+
+// GetStock returns the stock of the product and its variants together with
+// the latest ledger entries.
+func (p *Product) GetStock(ctx context.Context, productUuid string, limit int32) (*models.Stock, error) {
+	product, err := p.repository.GetProduct(ctx, productUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := p.repository.GetVariants(ctx, productUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := p.repository.GetLedger(ctx, productUuid, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	stock := &models.Stock{
+		ProductUuid: product.Uuid,
+		Quantity:    product.Stock,
+		Variants:    make([]*models.VariantStock, len(variants)),
+		Ledger:      ledger,
+	}
+	for i, variant := range variants {
+		stock.Variants[i] = &models.VariantStock{
+			Uuid:     variant.Uuid,
+			Sku:      variant.Sku,
+			Quantity: variant.Stock,
+		}
+	}
+	return stock, nil
+}
+
+func (p *Product) TransferStock(ctx context.Context, params domain.TransferParams) (*models.Transfer, error) {
+	return p.repository.TransferStock(ctx, params)
+}
+
+// GetAvailability returns the stock of the product and its variants grouped
+// by location, locations are sorted by name.
+func (p *Product) GetAvailability(ctx context.Context, productUuid string) (*models.Availability, error) {
+	product, err := p.repository.GetProduct(ctx, productUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := p.repository.GetVariants(ctx, productUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	levels, err := p.repository.GetStockLevels(ctx, productUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := p.repository.GetLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	availability := &models.Availability{
+		ProductUuid: product.Uuid,
+		Quantity:    product.Stock,
+		Locations:   []*models.LocationStock{},
+	}
+
+	skus := make(map[string]string, len(variants))
+	for _, variant := range variants {
+		skus[variant.Uuid] = variant.Sku
+		availability.Quantity += variant.Stock
+	}
+
+	registry := make(map[string]*models.Location, len(locations))
+	for _, location := range locations {
+		registry[location.Uuid] = location
+	}
+
+	var (
+		allocated int64
+		byUuid    = map[string]*models.LocationStock{}
+	)
+	for _, level := range levels {
+		stock, ok := byUuid[level.LocationUuid]
+		if !ok {
+			location, ok := registry[level.LocationUuid]
+			if !ok {
+				location = &models.Location{Uuid: level.LocationUuid}
+			}
+			stock = &models.LocationStock{Location: location}
+			byUuid[level.LocationUuid] = stock
+			availability.Locations = append(availability.Locations, stock)
+		}
+
+		stock.Quantity += level.Quantity
+		allocated += level.Quantity
+		if level.VariantUuid != "" {
+			stock.Variants = append(stock.Variants, &models.VariantStock{
+				Uuid:     level.VariantUuid,
+				Sku:      skus[level.VariantUuid],
+				Quantity: level.Quantity,
+			})
+		}
+	}
+
+	sort.Slice(availability.Locations, func(i, j int) bool {
+		return availability.Locations[i].Location.Name < availability.Locations[j].Location.Name
+	})
+	availability.Unallocated = availability.Quantity - allocated
+
+	return availability, nil
+}
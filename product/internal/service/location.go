@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+)
+
+func (p *Product) GetLocations(ctx context.Context) (models.Locations, error) {
+	return p.repository.GetLocations(ctx)
+}
+
+func (p *Product) AddLocation(ctx context.Context, params domain.LocationParams) (*models.Location, error) {
+	return p.repository.AddLocation(ctx, params)
+}
+
+func (p *Product) PutLocation(ctx context.Context, params domain.LocationParams) (*models.Location, error) {
+	return p.repository.PutLocation(ctx, params)
+}
@@ -2,9 +2,16 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"os"
 	"shopy/internal/domain"
 	"shopy/internal/models"
+	"shopy/pkg/barcode"
+	"shopy/pkg/imagex"
+	"shopy/pkg/saga"
+	"time"
 )
 
 type Repository interface {
@@ -12,20 +19,63 @@ type Repository interface {
 	GetProductsByQRCode(ctx context.Context, qrcode string) (models.Products, error)
 	GetProductsByName(ctx context.Context, name string) (models.Products, error)
 	GetTopProducts(ctx context.Context) (models.Products, error)
+	GetProduct(ctx context.Context, uuid string) (*models.Product, error)
+	GetProducts(ctx context.Context, uuids []string) (map[string]*models.Product, error)
+	GetCategory(ctx context.Context, uuid string) (*models.Category, error)
+	GetCategoryParents(ctx context.Context) (map[string]string, error)
+	GetSlugOwner(ctx context.Context, slug string) (string, error)
+	GetQRCodeOwner(ctx context.Context, qrcode string) (*models.QRCodeOwner, error)
+	GetQRCodeOwners(ctx context.Context, qrcodes []string) (map[string]*models.QRCodeOwner, error)
+	DelSlugs(ctx context.Context, uuid string) error
 	AddProduct(ctx context.Context, params domain.ProductParams) (*models.Product, error)
 	PutProduct(ctx context.Context, params domain.ProductParams) (*models.Product, error)
+	PatchProduct(ctx context.Context, patch domain.ProductPatch) (*models.Product, error)
+	GetScheduledProducts(ctx context.Context, now time.Time) (models.Products, error)
+	TransitionProduct(ctx context.Context, params domain.StatusParams) (*models.Product, error)
+	GetTrashProducts(ctx context.Context) (models.Products, error)
+	TrashProduct(ctx context.Context, uuid string, deletedAt time.Time) (*models.Product, error)
+	RestoreProduct(ctx context.Context, uuid string, updatedAt time.Time) (*models.Product, error)
 	DelProduct(ctx context.Context, uuid string) (*models.Product, error)
+	GetVariants(ctx context.Context, productUuid string) (models.Variants, error)
+	GetVariant(ctx context.Context, productUuid, uuid string) (*models.Variant, error)
+	GetVariantsByQRCode(ctx context.Context, qrcode string) (models.Variants, error)
+	AddVariant(ctx context.Context, params domain.VariantParams) (*models.Variant, error)
+	PutVariant(ctx context.Context, params domain.VariantParams) (*models.Variant, error)
+	DelVariant(ctx context.Context, productUuid, uuid string) (*models.Variant, error)
+	PutImages(ctx context.Context, params domain.GalleryParams) (*models.Product, error)
+	AcquireImage(ctx context.Context, hash string, updatedAt time.Time) (*models.ImageReference, error)
+	PutImageRenditions(ctx context.Context, hash string, renditions domain.Renditions) error
+	ReleaseImage(ctx context.Context, hash string) (bool, error)
+	GetImageLocations(ctx context.Context) ([]string, error)
+	DelImageReference(ctx context.Context, hash string, before time.Time) (bool, error)
+	AdjustStock(ctx context.Context, params domain.StockParams) (*models.LedgerEntry, error)
+	GetLedger(ctx context.Context, productUuid string, limit int32) (models.Ledger, error)
+	TransferStock(ctx context.Context, params domain.TransferParams) (*models.Transfer, error)
+	GetStockLevels(ctx context.Context, productUuid string) ([]*models.StockLevel, error)
+	GetLocations(ctx context.Context) (models.Locations, error)
+	AddLocation(ctx context.Context, params domain.LocationParams) (*models.Location, error)
+	PutLocation(ctx context.Context, params domain.LocationParams) (*models.Location, error)
 }
 
 type Storage interface {
-	UploadImage(ctx context.Context, uuid string, image []byte) (string, error)
+	UploadImage(ctx context.Context, hash string, image []byte) (domain.Renditions, error)
 	DeleteImage(ctx context.Context, image string) error
+	DeleteImages(ctx context.Context, prefix string) error
+	PresignUpload(ctx context.Context, params domain.UploadParams) (*models.Upload, error)
+	GetUpload(ctx context.Context, uuid string, maxBytes int) ([]byte, error)
+	DeleteUpload(ctx context.Context, uuid string) error
+	ListImages(ctx context.Context) ([]domain.StoredImage, error)
+	ImageKey(location string) (string, error)
+	DeleteObjects(ctx context.Context, keys []string) error
+	GetRender(ctx context.Context, key string) (string, error)
+	PutRender(ctx context.Context, key, contentType string, render []byte) (string, error)
 }
 
 type Product struct {
 	logger     *slog.Logger
 	repository Repository
 	storage    Storage
+	limits     imagex.Limits
 }
 
 func NewProduct(logger *slog.Logger, repository Repository, storage Storage) *Product {
@@ -33,15 +83,34 @@ func NewProduct(logger *slog.Logger, repository Repository, storage Storage) *Pr
 		logger:     logger,
 		repository: repository,
 		storage:    storage,
+		limits:     imagex.NewLimits(os.Getenv("IMAGE_MAX_BYTES"), os.Getenv("IMAGE_MAX_DIMENSION")),
 	}
 }
 
 func (p *Product) SearchProducts(ctx context.Context, params domain.ProductParams) (models.Products, error) {
+	products, err := p.searchProducts(ctx, params)
+	if err != nil || params.Staff {
+		return products, err
+	}
+
+	// public listings only show published products
+	published := make(models.Products, 0, len(products))
+	for _, product := range products {
+		if domain.Status(product.Status).Visible() {
+			published = append(published, product)
+		}
+	}
+	return published, nil
+}
+
+func (p *Product) searchProducts(ctx context.Context, params domain.ProductParams) (models.Products, error) {
 	switch {
+	case params.Category.Uuid != "" && params.Descendants:
+		return p.searchCategoryTree(ctx, params.Category.Uuid)
 	case params.Category.Uuid != "":
 		return p.repository.GetProductsByCategory(ctx, params.Category.Uuid)
 	case params.QRCode != "":
-		return p.repository.GetProductsByQRCode(ctx, params.QRCode)
+		return p.searchQRCode(ctx, params.QRCode)
 	case params.Name != "":
 		return p.repository.GetProductsByName(ctx, params.Name)
 	default:
@@ -49,32 +118,337 @@ func (p *Product) SearchProducts(ctx context.Context, params domain.ProductParam
 	}
 }
 
+// GetProductByQRCode returns the product holding a QR code, GS1 codes match
+// in any of their representations. A variant code returns its parent product
+// with the variant. Only published products are found unless the request
+// comes from staff.
+func (p *Product) GetProductByQRCode(ctx context.Context, qrcode string, staff bool) (*models.Product, error) {
+	var (
+		owner *models.QRCodeOwner
+		err   error = domain.ErrNotFound
+	)
+	for _, code := range barcode.Lookup(qrcode) {
+		owner, err = p.repository.GetQRCodeOwner(ctx, code)
+		if !errors.Is(err, domain.ErrNotFound) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := p.repository.GetProduct(ctx, owner.ProductUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !staff && !domain.Status(product.Status).Visible() {
+		return nil, domain.ErrNotFound
+	}
+
+	if owner.VariantUuid != "" {
+		if product.Variant, err = p.repository.GetVariant(ctx, owner.ProductUuid, owner.VariantUuid); err != nil {
+			return nil, err
+		}
+	}
+	return product, nil
+}
+
 func (p *Product) AddProduct(ctx context.Context, params domain.ProductParams) (*models.Product, error) {
-	location, err := p.storage.UploadImage(ctx, params.Uuid, params.Image)
+	category, err := p.category(ctx, params.Category.Uuid)
+	if err != nil {
+		return nil, err
+	}
+	params.Category = category
+
+	custom := params.Slug
+	params.Slug, err = p.newSlug(ctx, params.Uuid, params.Name, custom)
+	if err != nil {
+		return nil, err
+	}
+	params.QRCode, params.BarcodeType = normalizeBarcode(params.QRCode, params.BarcodeType)
+
+	image, err := p.uploadedImage(ctx, params.Image, params.Upload)
 	if err != nil {
 		return nil, err
 	}
 
-	params.Location = location
-	return p.repository.AddProduct(ctx, params)
+	tx := saga.New(p.logger)
+	hash, renditions, err := p.storeImage(ctx, tx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	params.Images = domain.Images{}.Add(domain.Image{
+		Uuid:       params.ImageUuid,
+		Hash:       hash,
+		Location:   renditions.Location(),
+		Primary:    true,
+		Renditions: renditions,
+	})
+	var product *models.Product
+	err = tx.Do(ctx, "add product", func(ctx context.Context) error {
+		return retrySlug(params.Slug, custom == "", func() (string, error) {
+			return p.newSlug(ctx, params.Uuid, params.Name, "")
+		}, func(slug string) (err error) {
+			params.Slug = slug
+			product, err = p.repository.AddProduct(ctx, params)
+			return err
+		})
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p.discardUpload(ctx, params.Upload)
+	return product, nil
 }
 
+// PutProduct updates the product, an image sent with it replaces the primary
+// image of the gallery.
 func (p *Product) PutProduct(ctx context.Context, params domain.ProductParams) (*models.Product, error) {
-	if params.Image != nil {
-		location, err := p.storage.UploadImage(ctx, params.Uuid, params.Image)
+	category, err := p.category(ctx, params.Category.Uuid)
+	if err != nil {
+		return nil, err
+	}
+	params.Category = category
+
+	// a rename moves the product to a new slug, the old one still leads to it
+	custom := params.Slug
+	params.Slug, err = p.renamedSlug(ctx, params.Uuid, params.Name, custom)
+	if err != nil {
+		return nil, err
+	}
+	params.QRCode, params.BarcodeType = normalizeBarcode(params.QRCode, params.BarcodeType)
+
+	var product *models.Product
+	put := func(ctx context.Context) error {
+		return retrySlug(params.Slug, custom == "" && params.Slug != "", func() (string, error) {
+			return p.renamedSlug(ctx, params.Uuid, params.Name, "")
+		}, func(slug string) (err error) {
+			params.Slug = slug
+			product, err = p.repository.PutProduct(ctx, params)
+			return err
+		})
+	}
+
+	if params.Image == nil && params.Upload == "" {
+		if err = put(ctx); err != nil {
+			return nil, err
+		}
+		return product, nil
+	}
+
+	image, err := p.uploadedImage(ctx, params.Image, params.Upload)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := saga.New(p.logger)
+	images, replaced, err := p.replacePrimary(ctx, tx, params.Uuid, params.ImageUuid, image)
+	if err != nil {
+		return nil, err
+	}
+
+	params.Images = images
+	if err = tx.Do(ctx, "put product", put, nil); err != nil {
+		return nil, err
+	}
+
+	p.discardUpload(ctx, params.Upload)
+	tx.Finally(ctx, "delete replaced image", func(ctx context.Context) error {
+		return p.deleteImage(ctx, replaced)
+	})
+	return product, nil
+}
+
+// PatchProduct applies a partial update, an image sent with it replaces the
+// primary image of the gallery and a null image removes it.
+func (p *Product) PatchProduct(ctx context.Context, patch domain.ProductPatch) (*models.Product, error) {
+	var (
+		err      error
+		replaced *domain.Image
+		tx       = saga.New(p.logger)
+	)
+
+	if patch.CategoryUuid != nil {
+		category, err := p.category(ctx, *patch.CategoryUuid)
+		if err != nil {
+			return nil, err
+		}
+		patch.CategoryName = &category.Name
+	}
+
+	if patch.QRCode != nil || patch.BarcodeType != nil {
+		if err = p.patchBarcode(ctx, &patch); err != nil {
+			return nil, err
+		}
+	}
+
+	var slug, name, custom string
+	if patch.Name != nil || patch.Slug != nil {
+		if patch.Name != nil {
+			name = *patch.Name
+		}
+		if patch.Slug != nil {
+			custom = *patch.Slug
+		}
+
+		slug, err = p.renamedSlug(ctx, patch.Uuid, name, custom)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case patch.Image != nil || patch.Upload != "":
+		image, err := p.uploadedImage(ctx, patch.Image, patch.Upload)
+		if err != nil {
+			return nil, err
+		}
+
+		patch.Images, replaced, err = p.replacePrimary(ctx, tx, patch.Uuid, patch.ImageUuid, image)
 		if err != nil {
 			return nil, err
 		}
-		params.Location = location
+	case patch.RemoveImage:
+		current, err := p.repository.GetProduct(ctx, patch.Uuid)
+		if err != nil {
+			return nil, err
+		}
+
+		images := gallery(current)
+		if primary, ok := images.Primary(); ok {
+			patch.Images, replaced = images.Remove(primary.Uuid)
+		}
+	}
+
+	var product *models.Product
+	err = tx.Do(ctx, "patch product", func(ctx context.Context) error {
+		return retrySlug(slug, custom == "" && slug != "", func() (string, error) {
+			return p.renamedSlug(ctx, patch.Uuid, name, "")
+		}, func(slug string) (err error) {
+			patch.Slug = nil
+			if slug != "" {
+				patch.Slug = &slug
+			}
+			product, err = p.repository.PatchProduct(ctx, patch)
+			return err
+		})
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p.discardUpload(ctx, patch.Upload)
+	tx.Finally(ctx, "delete replaced image", func(ctx context.Context) error {
+		return p.deleteImage(ctx, replaced)
+	})
+	return product, nil
+}
+
+func (p *Product) TransitionProduct(ctx context.Context, params domain.StatusParams) (*models.Product, error) {
+	return p.repository.TransitionProduct(ctx, params)
+}
+
+// ApplySchedule publishes the drafts whose publish_at time has been reached and
+// archives the published products whose unpublish_at time has been reached.
+func (p *Product) ApplySchedule(ctx context.Context, now time.Time) (int, error) {
+	products, err := p.repository.GetScheduledProducts(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		applied int
+		cutoff  = now.Format(time.DateTime)
+	)
+	for _, product := range products {
+		var (
+			current = domain.Status(product.Status)
+			target  domain.Status
+		)
+		switch {
+		case product.UnpublishAt != "" && product.UnpublishAt <= cutoff && current == domain.StatusPublished:
+			target = domain.StatusArchived
+		case product.PublishAt != "" && product.PublishAt <= cutoff && current == domain.StatusDraft:
+			target = domain.StatusPublished
+		default:
+			continue
+		}
+
+		_, err = p.repository.TransitionProduct(ctx, domain.StatusParams{
+			Uuid:      product.Uuid,
+			Status:    target,
+			UpdatedAt: now,
+		})
+		if errors.Is(err, domain.ErrTransition) {
+			// the status was changed by staff since the scan
+			continue
+		}
+		if err != nil {
+			return applied, err
+		}
+
+		p.logger.Info("product status changed", "uuid", product.Uuid, "from", current, "to", target)
+		applied++
 	}
 
-	return p.repository.PutProduct(ctx, params)
+	return applied, nil
 }
 
 func (p *Product) DelProduct(ctx context.Context, uuid string) error {
-	product, err := p.repository.DelProduct(ctx, uuid)
+	_, err := p.repository.TrashProduct(ctx, uuid, time.Now().UTC())
+	return err
+}
+
+func (p *Product) GetTrashProducts(ctx context.Context) (models.Products, error) {
+	return p.repository.GetTrashProducts(ctx)
+}
+
+func (p *Product) RestoreProduct(ctx context.Context, uuid string) (*models.Product, error) {
+	return p.repository.RestoreProduct(ctx, uuid, time.Now().UTC())
+}
+
+// PurgeProducts permanently deletes the products and images trashed before the given time.
+func (p *Product) PurgeProducts(ctx context.Context, before time.Time) (int, error) {
+	products, err := p.repository.GetTrashProducts(ctx)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	var (
+		purged int
+		errs   []error
+		cutoff = before.Format(time.DateTime)
+	)
+	for _, product := range products {
+		if product.DeletedAt >= cutoff {
+			continue
+		}
+
+		err = p.purgeVariants(ctx, product.Uuid)
+		if err == nil {
+			_, err = p.repository.DelProduct(ctx, product.Uuid)
+		}
+		if err != nil {
+			// a product failing to purge is retried on the next run
+			p.logger.Error("error purging product", "uuid", product.Uuid, "error", err)
+			errs = append(errs, fmt.Errorf("purging product %s: %w", product.Uuid, err))
+			continue
+		}
+
+		tx := saga.New(p.logger)
+		tx.Finally(ctx, "purge images", func(ctx context.Context) error {
+			return p.purgeImages(ctx, product)
+		})
+		tx.Finally(ctx, "free slugs", func(ctx context.Context) error {
+			return p.repository.DelSlugs(ctx, product.Uuid)
+		})
+
+		p.logger.Info("product purged", "uuid", product.Uuid, "deleted_at", product.DeletedAt)
+		purged++
 	}
-	return p.storage.DeleteImage(ctx, product.Image)
+
+	return purged, errors.Join(errs...)
 }
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"testing"
+)
+
+const testCategoryUuid = "8c1a8a5e-8bd6-4b0e-9d5c-0a4c43f7d0a1"
+
+func TestAddProductImage(t *testing.T) {
+	image := []byte("image")
+	hash := domain.ImageHash(image)
+
+	tests := []struct {
+		name string
+		// shared is the number of products already holding the image
+		shared         int64
+		addErr         error
+		wantErr        error
+		wantReferences int64
+		wantObjects    int
+	}{
+		{name: "new image", wantReferences: 1, wantObjects: 2},
+		{name: "write fails deletes the uploaded image", addErr: errFake, wantErr: errFake},
+		{name: "write fails keeps a shared image", shared: 1, addErr: errFake, wantErr: errFake, wantReferences: 1, wantObjects: 2},
+		{name: "shared image", shared: 1, wantReferences: 2, wantObjects: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				ctx        = context.Background()
+				repository = newFakeRepository()
+				storage    = newFakeStorage()
+			)
+			repository.categories[testCategoryUuid] = &models.Category{Uuid: testCategoryUuid, Name: "Boissons"}
+			repository.addErr = tt.addErr
+			if tt.shared > 0 {
+				renditions, _ := storage.UploadImage(ctx, hash, image)
+				repository.references[hash] = &models.ImageReference{Hash: hash, References: tt.shared}
+				_ = repository.PutImageRenditions(ctx, hash, renditions)
+			}
+
+			_, err := testService(repository, storage).AddProduct(ctx, domain.ProductParams{
+				Uuid:     "0b8d5c4e-2a43-4f4b-9f0c-6a9b8f2e5d11",
+				Name:     "Café",
+				Category: domain.Category{Uuid: testCategoryUuid},
+				Image:    image,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			var references int64
+			if reference, ok := repository.references[hash]; ok {
+				references = reference.References
+			}
+			if references != tt.wantReferences {
+				t.Errorf("references = %d, want %d", references, tt.wantReferences)
+			}
+			if len(storage.objects) != tt.wantObjects {
+				t.Errorf("stored objects = %v, want %d", storage.objects, tt.wantObjects)
+			}
+		})
+	}
+}
+
+func TestAddProductMissingCategory(t *testing.T) {
+	storage := newFakeStorage()
+	_, err := testService(newFakeRepository(), storage).AddProduct(context.Background(), domain.ProductParams{
+		Uuid:     "0b8d5c4e-2a43-4f4b-9f0c-6a9b8f2e5d11",
+		Name:     "Café",
+		Category: domain.Category{Uuid: testCategoryUuid},
+		Image:    []byte("image"),
+	})
+	if !errors.Is(err, domain.ErrCategory) {
+		t.Errorf("error = %v, want %v", err, domain.ErrCategory)
+	}
+	if len(storage.objects) != 0 {
+		t.Errorf("stored objects = %v, want none", storage.objects)
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shopy/internal/domain"
+	"shopy/pkg/label"
+	"strconv"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// labelLayout is part of the key of every label sheet, bump it when the sheet
+// layout changes so cached sheets are printed again.
+const labelLayout = "1"
+
+var errLabelProduct = errors.New("product not found")
+
+// GetQRCodeImage returns the URL of the PNG rendering the QR code of a
+// product, rendered and cached on the first request. Only published products
+// are rendered unless the request comes from staff.
+func (p *Product) GetQRCodeImage(ctx context.Context, params domain.QRCodeParams) (string, error) {
+	product, err := p.repository.GetProduct(ctx, params.ProductUuid)
+	if err != nil {
+		return "", err
+	}
+	if !params.Staff && !domain.Status(product.Status).Visible() {
+		return "", domain.ErrNotFound
+	}
+	if product.QRCode == "" {
+		return "", domain.ErrNoQRCode
+	}
+
+	key := domain.RenderKey("png", product.QRCode, strconv.Itoa(params.Size), string(params.Level))
+	return p.render(ctx, key, "image/png", func() ([]byte, error) {
+		return label.QRCode(product.QRCode, params.Size, params.Level)
+	})
+}
+
+// GetLabels returns the URL of a PDF sheet with the shelf label of each
+// product in order, printed and cached on the first request for the same
+// names, prices and codes. Only published products are printed unless the
+// request comes from staff.
+func (p *Product) GetLabels(ctx context.Context, uuids []string, staff bool) (string, error) {
+	var (
+		labels = make([]label.Label, len(uuids))
+		parts  = []string{labelLayout}
+		errs   = validation.Errors{}
+	)
+	for i, uuid := range uuids {
+		product, err := p.repository.GetProduct(ctx, uuid)
+		if errors.Is(err, domain.ErrNotFound) {
+			errs[strconv.Itoa(i)] = errLabelProduct
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if !staff && !domain.Status(product.Status).Visible() {
+			errs[strconv.Itoa(i)] = errLabelProduct
+			continue
+		}
+
+		labels[i] = label.Label{
+			Name:  product.Name,
+			Price: product.Price.String(),
+			Code:  product.QRCode,
+		}
+		parts = append(parts, labels[i].Name, labels[i].Price, labels[i].Code)
+	}
+	if len(errs) > 0 {
+		return "", domain.ErrParams.Wrap(validation.Errors{"uuids": errs})
+	}
+
+	return p.render(ctx, domain.RenderKey("pdf", parts...), "application/pdf", func() ([]byte, error) {
+		return label.Sheet(labels)
+	})
+}
+
+// render returns the URL of the render cached under key, rendering and
+// storing it when it's missing.
+func (p *Product) render(ctx context.Context, key, contentType string, render func() ([]byte, error)) (string, error) {
+	location, err := p.storage.GetRender(ctx, key)
+	if err == nil {
+		return location, nil
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return "", err
+	}
+
+	data, err := render()
+	if err != nil {
+		return "", fmt.Errorf("error rendering %s: %w", key, err)
+	}
+	return p.storage.PutRender(ctx, key, contentType, data)
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"testing"
+)
+
+func TestGetLabelsHidden(t *testing.T) {
+	const (
+		published = "3e4f5a6b-7c8d-4e9f-0a1b-2c3d4e5f6a70"
+		draft     = "4f5a6b7c-8d9e-4f0a-1b2c-3d4e5f6a7b80"
+	)
+
+	tests := []struct {
+		name    string
+		staff   bool
+		wantErr error
+	}{
+		{name: "customer", wantErr: domain.ErrParams},
+		{name: "staff", staff: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeRepository()
+			repository.products[published] = &models.Product{Uuid: published, Name: "Espresso", Status: string(domain.StatusPublished)}
+			repository.products[draft] = &models.Product{Uuid: draft, Name: "Secret blend", Status: string(domain.StatusDraft)}
+
+			location, err := testService(repository, newFakeStorage()).GetLabels(context.Background(), []string{published, draft}, tt.staff)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetLabels() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && location == "" {
+				t.Error("GetLabels() returned no location")
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"shopy/pkg/slug"
+	"strings"
+)
+
+// slugAttempts bounds the numbered candidates tried for a generated slug.
+const slugAttempts = 100
+
+// slugWrites bounds the writes of a generated slug, another product may take
+// it between the check and the write.
+const slugWrites = 3
+
+// GetProductBySlug returns the product holding a slug, the current one or one
+// it had before a rename. Only published products are found unless the
+// request comes from staff.
+func (p *Product) GetProductBySlug(ctx context.Context, slug string, staff bool) (*models.Product, error) {
+	uuid, err := p.repository.GetSlugOwner(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := p.repository.GetProduct(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !staff && !domain.Status(product.Status).Visible() {
+		return nil, domain.ErrNotFound
+	}
+	return product, nil
+}
+
+// newSlug returns the slug of a product: the one sent by the client unless
+// another product holds it, or one made from the name and numbered until a
+// free one is found.
+func (p *Product) newSlug(ctx context.Context, uuid, name, custom string) (string, error) {
+	if custom != "" {
+		free, err := p.freeSlug(ctx, uuid, custom)
+		if err != nil {
+			return "", err
+		}
+		if !free {
+			return "", domain.ErrSlug
+		}
+		return custom, nil
+	}
+
+	base := slug.Make(name)
+	if base == "" {
+		// the name has no letters to transliterate
+		base, _, _ = strings.Cut(uuid, "-")
+	}
+	for n := 1; n <= slugAttempts; n++ {
+		candidate := slug.Number(base, n)
+		free, err := p.freeSlug(ctx, uuid, candidate)
+		if err != nil {
+			return "", err
+		}
+		if free {
+			return candidate, nil
+		}
+	}
+	return "", domain.ErrSlug
+}
+
+// renamedSlug returns the new slug of a product being updated, or an empty
+// one when it keeps its slug: the client slug when it differs, or a slug made
+// from the name when the name changes or the product has none yet.
+func (p *Product) renamedSlug(ctx context.Context, uuid, name, custom string) (string, error) {
+	current, err := p.repository.GetProduct(ctx, uuid)
+	if err != nil {
+		return "", err
+	}
+
+	if custom == "" && (name == "" || name == current.Name) && current.Slug != "" {
+		return "", nil
+	}
+	if name == "" {
+		name = current.Name
+	}
+
+	slug, err := p.newSlug(ctx, uuid, name, custom)
+	if err != nil || slug == current.Slug {
+		return "", err
+	}
+	return slug, nil
+}
+
+// retrySlug runs write with slug. When the slug was generated and another
+// product took it since it was checked, the write is retried with the slug
+// returned by next, which is then the next free candidate. Client slugs keep
+// failing with ErrSlug.
+func retrySlug(slug string, generated bool, next func() (string, error), write func(slug string) error) error {
+	for attempt := 1; ; attempt++ {
+		err := write(slug)
+		if !errors.Is(err, domain.ErrSlug) || !generated || attempt == slugWrites {
+			return err
+		}
+
+		if slug, err = next(); err != nil {
+			return err
+		}
+	}
+}
+
+// freeSlug reports whether a product can take a slug, either nobody holds it
+// or the product held it before.
+func (p *Product) freeSlug(ctx context.Context, uuid, slug string) (bool, error) {
+	owner, err := p.repository.GetSlugOwner(ctx, slug)
+	if errors.Is(err, domain.ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return owner == uuid, nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"testing"
+)
+
+func TestAddProductSlugRace(t *testing.T) {
+	const other = "5d7e0a3c-1b2f-4e8a-9c6d-3f4a5b6c7d8e"
+
+	tests := []struct {
+		name     string
+		custom   string
+		race     func(repository *fakeRepository) func(slug string)
+		want     string
+		wantErr  error
+		wantAdds int
+	}{
+		{
+			name:     "free slug",
+			race:     func(*fakeRepository) func(string) { return nil },
+			want:     "cafe",
+			wantAdds: 1,
+		},
+		{
+			name: "generated slug taken before the write",
+			race: func(repository *fakeRepository) func(string) {
+				return func(slug string) {
+					if slug == "cafe" {
+						repository.slugs["cafe"] = other
+					}
+				}
+			},
+			want:     "cafe-2",
+			wantAdds: 2,
+		},
+		{
+			name:   "client slug taken before the write",
+			custom: "cafe",
+			race: func(repository *fakeRepository) func(string) {
+				return func(slug string) { repository.slugs[slug] = other }
+			},
+			wantErr:  domain.ErrSlug,
+			wantAdds: 1,
+		},
+		{
+			name: "generated slugs always taken",
+			race: func(repository *fakeRepository) func(string) {
+				return func(slug string) { repository.slugs[slug] = other }
+			},
+			wantErr:  domain.ErrSlug,
+			wantAdds: slugWrites,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeRepository()
+			repository.categories[testCategoryUuid] = &models.Category{Uuid: testCategoryUuid, Name: "Boissons"}
+			repository.race = tt.race(repository)
+
+			product, err := testService(repository, newFakeStorage()).AddProduct(context.Background(), domain.ProductParams{
+				Uuid:     "0b8d5c4e-2a43-4f4b-9f0c-6a9b8f2e5d11",
+				Name:     "Café",
+				Slug:     tt.custom,
+				Category: domain.Category{Uuid: testCategoryUuid},
+				Image:    []byte("image"),
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && product.Slug != tt.want {
+				t.Errorf("slug = %q, want %q", product.Slug, tt.want)
+			}
+			if repository.adds != tt.wantAdds {
+				t.Errorf("writes = %d, want %d", repository.adds, tt.wantAdds)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"shopy/pkg/imagex"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func (p *Product) CreateUpload(ctx context.Context, params domain.UploadParams) (*models.Upload, error) {
+	return p.storage.PresignUpload(ctx, params)
+}
+
+// uploadedImage returns image unless upload references a presigned upload, in
+// which case the uploaded object is read and put through the same checks as
+// an image sent in the body before it can be attached.
+func (p *Product) uploadedImage(ctx context.Context, image []byte, upload string) ([]byte, error) {
+	if upload == "" {
+		return image, nil
+	}
+
+	image, err := p.storage.GetUpload(ctx, upload, p.limits.MaxBytes)
+	if errors.Is(err, domain.ErrUpload) {
+		return nil, domain.ErrUpload.Wrap(validation.Errors{"upload_uuid": errors.New("must reference an uploaded image")})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.limits.Validate(image); err != nil {
+		errs := validation.Errors{"upload_uuid": err}
+		if imagex.TooLarge(err) {
+			return nil, domain.ErrImageTooLarge.Wrap(errs)
+		}
+		return nil, domain.ErrImage.Wrap(errs)
+	}
+
+	return image, nil
+}
+
+// discardUpload deletes an upload once its image is attached, a failure only
+// leaves the object for the bucket lifecycle rule to expire.
+func (p *Product) discardUpload(ctx context.Context, upload string) {
+	if upload == "" {
+		return
+	}
+	if err := p.storage.DeleteUpload(ctx, upload); err != nil {
+		p.logger.Error("error deleting upload", "upload", upload, "error", err)
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shopy/internal/domain"
+	"shopy/internal/models"
+	"shopy/pkg/barcode"
+	"shopy/pkg/saga"
+)
+
+func (p *Product) GetVariants(ctx context.Context, productUuid string) (models.Variants, error) {
+	if _, err := p.repository.GetProduct(ctx, productUuid); err != nil {
+		return nil, err
+	}
+	return p.repository.GetVariants(ctx, productUuid)
+}
+
+func (p *Product) AddVariant(ctx context.Context, params domain.VariantParams) (*models.Variant, error) {
+	if _, err := p.repository.GetProduct(ctx, params.ProductUuid); err != nil {
+		return nil, err
+	}
+	params.QRCode, params.BarcodeType = normalizeBarcode(params.QRCode, params.BarcodeType)
+
+	image, err := p.uploadedImage(ctx, params.Image, params.Upload)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := saga.New(p.logger)
+	if image != nil {
+		params.Hash, params.Renditions, err = p.storeImage(ctx, tx, image)
+		if err != nil {
+			return nil, err
+		}
+		params.Location = params.Renditions.Location()
+	}
+
+	var variant *models.Variant
+	err = tx.Do(ctx, "add variant", func(ctx context.Context) (err error) {
+		variant, err = p.repository.AddVariant(ctx, params)
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p.discardUpload(ctx, params.Upload)
+	return variant, nil
+}
+
+func (p *Product) PutVariant(ctx context.Context, params domain.VariantParams) (*models.Variant, error) {
+	if _, err := p.repository.GetProduct(ctx, params.ProductUuid); err != nil {
+		return nil, err
+	}
+	params.QRCode, params.BarcodeType = normalizeBarcode(params.QRCode, params.BarcodeType)
+
+	image, err := p.uploadedImage(ctx, params.Image, params.Upload)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		replaced *domain.Image
+		tx       = saga.New(p.logger)
+	)
+	if image != nil {
+		variants, err := p.repository.GetVariants(ctx, params.ProductUuid)
+		if err != nil {
+			return nil, err
+		}
+		for _, variant := range variants {
+			if variant.Uuid == params.Uuid {
+				replaced = variantImage(variant)
+			}
+		}
+
+		params.Hash, params.Renditions, err = p.storeImage(ctx, tx, image)
+		if err != nil {
+			return nil, err
+		}
+		params.Location = params.Renditions.Location()
+	}
+
+	var variant *models.Variant
+	err = tx.Do(ctx, "put variant", func(ctx context.Context) (err error) {
+		variant, err = p.repository.PutVariant(ctx, params)
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p.discardUpload(ctx, params.Upload)
+	tx.Finally(ctx, "delete replaced image", func(ctx context.Context) error {
+		return p.deleteImage(ctx, replaced)
+	})
+	return variant, nil
+}
+
+func (p *Product) DelVariant(ctx context.Context, productUuid, uuid string) error {
+	variant, err := p.repository.DelVariant(ctx, productUuid, uuid)
+	if err != nil {
+		return err
+	}
+
+	saga.New(p.logger).Finally(ctx, "delete variant image", func(ctx context.Context) error {
+		return p.deleteImage(ctx, variantImage(variant))
+	})
+	return nil
+}
+
+// variantImage returns the image of a variant, if it has one.
+func variantImage(variant *models.Variant) *domain.Image {
+	if variant.Image == "" {
+		return nil
+	}
+	return &domain.Image{
+		Hash:       variant.ImageHash,
+		Location:   variant.Image,
+		Renditions: renditions(variant.Renditions),
+	}
+}
+
+// searchQRCode returns the products whose QR code matches, followed by the
+// parent products of matching variants with the variant attached. GS1 codes
+// match as sent and in their stored GTIN-14 form.
+func (p *Product) searchQRCode(ctx context.Context, qrcode string) (models.Products, error) {
+	var (
+		products models.Products
+		variants models.Variants
+	)
+	for _, code := range barcode.Lookup(qrcode) {
+		matches, err := p.repository.GetProductsByQRCode(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, matches...)
+
+		variantMatches, err := p.repository.GetVariantsByQRCode(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, variantMatches...)
+	}
+
+	for _, variant := range variants {
+		product, err := p.repository.GetProduct(ctx, variant.ProductUuid)
+		if errors.Is(err, domain.ErrNotFound) {
+			// the parent product is in the trash
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		product.Variant = variant
+		products = append(products, product)
+	}
+
+	return products, nil
+}
+
+// purgeVariants permanently deletes the variants of a product and their images.
+func (p *Product) purgeVariants(ctx context.Context, productUuid string) error {
+	variants, err := p.repository.GetVariants(ctx, productUuid)
+	if err != nil {
+		return err
+	}
+
+	for _, variant := range variants {
+		if err = p.DelVariant(ctx, productUuid, variant.Uuid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"shopy/internal/dynamodb"
+	"shopy/pkg/money"
+)
+
+// Rewrites products stored with a float "price" attribute as integer minor
+// units plus the currency configured in DEFAULT_CURRENCY.
+func main() {
+	if _, err := money.ParseCurrency(os.Getenv("DEFAULT_CURRENCY")); err != nil {
+		log.Fatalf("invalid default currency: %v", err)
+	}
+
+	dynamoClient, err := dynamodb.Connection()
+	if err != nil {
+		log.Fatalf("error connecting to dynamodb: %v", err)
+	}
+
+	var (
+		logger     = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+		repository = dynamodb.NewProduct(logger, dynamoClient)
+	)
+
+	migrated, err := repository.MigratePrices(context.Background())
+	if err != nil {
+		log.Fatalf("error migrating prices after %d items: %v", migrated, err)
+	}
+
+	logger.Info("price migration finished", "migrated", migrated)
+}
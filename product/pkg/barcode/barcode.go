@@ -0,0 +1,138 @@
+// Package barcode validates the codes read by the scanners and normalizes the
+// GS1 ones, EAN-13, UPC-A and GTIN-14, to a single 14 digit form.
+package barcode
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Type is the symbology of a product code.
+type Type string
+
+const (
+	// QRCode is a free alphanumeric code, the default.
+	QRCode Type = "qrcode"
+	EAN13  Type = "ean13"
+	UPCA   Type = "upca"
+	GTIN14 Type = "gtin14"
+)
+
+// gtinLength is the length of the normalized form of the GS1 codes.
+const gtinLength = 14
+
+var (
+	ErrType       = errors.New("must be one of qrcode, ean13, upca or gtin14")
+	ErrCode       = errors.New("must contain only letters and digits")
+	ErrEAN13      = errors.New("must be 13 digits")
+	ErrUPCA       = errors.New("must be 12 digits")
+	ErrGTIN14     = errors.New("must be 14 digits")
+	ErrCheckDigit = errors.New("has an invalid check digit")
+
+	alphanumeric = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+)
+
+// lengths holds the number of digits of each GS1 symbology.
+var lengths = map[Type]int{
+	EAN13:  13,
+	UPCA:   12,
+	GTIN14: gtinLength,
+}
+
+// Valid reports whether t is a known symbology, the empty type stands for QRCode.
+func (t Type) Valid() bool {
+	_, gs1 := lengths[t]
+	return gs1 || t == QRCode || t == ""
+}
+
+// Validate checks a code against its symbology, GS1 codes must have the
+// exact number of digits and a valid check digit.
+func Validate(t Type, code string) error {
+	length, gs1 := lengths[t]
+	switch {
+	case !t.Valid():
+		return ErrType
+	case !gs1:
+		if !alphanumeric.MatchString(code) {
+			return ErrCode
+		}
+		return nil
+	case len(code) != length || !digits(code):
+		switch t {
+		case EAN13:
+			return ErrEAN13
+		case UPCA:
+			return ErrUPCA
+		default:
+			return ErrGTIN14
+		}
+	case !checked(code):
+		return ErrCheckDigit
+	}
+	return nil
+}
+
+// Normalize returns the GTIN-14 form of a code of a GS1 symbology, padded
+// with leading zeros, so every representation of a code is stored the same
+// way. QR codes are free codes and returned unchanged, like codes that don't
+// read as GS1 codes.
+func Normalize(t Type, code string) string {
+	if _, gs1 := lengths[t]; !gs1 {
+		return code
+	}
+	return gtin(code)
+}
+
+// Lookup returns the stored forms a scanned code of unknown symbology may
+// have: the code as read, as QR codes are stored, followed by its GTIN-14
+// form when it reads as a GS1 code in another representation.
+func Lookup(code string) []string {
+	if normalized := gtin(code); normalized != code {
+		return []string{code, normalized}
+	}
+	return []string{code}
+}
+
+// gtin pads a UPC-A, EAN-13 or GTIN-14 code to its GTIN-14 form, other codes
+// are returned unchanged.
+func gtin(code string) string {
+	if len(code) < lengths[UPCA] || len(code) > gtinLength || !digits(code) || !checked(code) {
+		return code
+	}
+	return strings.Repeat("0", gtinLength-len(code)) + code
+}
+
+// Format returns a normalized code in the representation of its symbology,
+// "4006381333931" for the EAN-13 stored as "04006381333931". Codes that
+// don't fit the symbology are returned unchanged.
+func Format(t Type, code string) string {
+	length, gs1 := lengths[t]
+	if !gs1 || len(code) != gtinLength || strings.Trim(code[:gtinLength-length], "0") != "" {
+		return code
+	}
+	return code[gtinLength-length:]
+}
+
+// checked reports whether the last digit of a GS1 code is the modulo 10 check
+// digit of the others, weighted 3 and 1 alternately from the right.
+func checked(code string) bool {
+	var sum int
+	for i := len(code) - 2; i >= 0; i-- {
+		weight := 1
+		if (len(code)-2-i)%2 == 0 {
+			weight = 3
+		}
+		sum += int(code[i]-'0') * weight
+	}
+	return int(code[len(code)-1]-'0') == (10-sum%10)%10
+}
+
+func digits(code string) bool {
+	for i := 0; i < len(code); i++ {
+		if code[i] < '0' || code[i] > '9' {
+			return false
+		}
+	}
+	return code != ""
+}
@@ -0,0 +1,48 @@
+package barcode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		kind Type
+		code string
+		want string
+	}{
+		{name: "UPC-A", kind: UPCA, code: "036000291452", want: "00036000291452"},
+		{name: "EAN-13", kind: EAN13, code: "4006381333931", want: "04006381333931"},
+		{name: "GTIN-14", kind: GTIN14, code: "00036000291452", want: "00036000291452"},
+		{name: "QR code of GS1 digits", kind: QRCode, code: "036000291452", want: "036000291452"},
+		{name: "untyped code of GS1 digits", code: "036000291452", want: "036000291452"},
+		{name: "invalid check digit", kind: UPCA, code: "036000291453", want: "036000291453"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.kind, tt.code); got != tt.want {
+				t.Errorf("Normalize(%q, %q) = %q, want %q", tt.kind, tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want []string
+	}{
+		{name: "UPC-A", code: "036000291452", want: []string{"036000291452", "00036000291452"}},
+		{name: "GTIN-14", code: "00036000291452", want: []string{"00036000291452"}},
+		{name: "free code", code: "SKU42", want: []string{"SKU42"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lookup(tt.code); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lookup(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package imagex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+)
+
+var (
+	// ErrType and ErrInvalid reject the content of an upload.
+	ErrType    = errors.New("must be a PNG, JPEG, WebP or GIF image")
+	ErrInvalid = errors.New("must be a valid image")
+
+	// ErrSize and ErrDimensions reject uploads that are too large.
+	ErrSize       = errors.New("exceeds the maximum image size")
+	ErrDimensions = errors.New("exceeds the maximum image dimensions")
+)
+
+// Default limits applied when none are configured.
+const (
+	DefaultMaxBytes     = 5 << 20
+	DefaultMaxDimension = 4096
+)
+
+// signatures maps the magic bytes of the accepted formats to their names,
+// WebP is checked apart since its signature is split by the RIFF size.
+var signatures = []struct {
+	magic []byte
+	name  string
+}{
+	{[]byte("\x89PNG\r\n\x1a\n"), "png"},
+	{[]byte("\xff\xd8\xff"), "jpeg"},
+	{[]byte("GIF87a"), "gif"},
+	{[]byte("GIF89a"), "gif"},
+}
+
+// Sniff returns the format of data based on its magic bytes, reporting
+// whether it is one of the accepted formats.
+func Sniff(data []byte) (string, bool) {
+	for _, signature := range signatures {
+		if bytes.HasPrefix(data, signature.magic) {
+			return signature.name, true
+		}
+	}
+	if len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")) {
+		return "webp", true
+	}
+	return "", false
+}
+
+// Limits bounds the size in bytes and the width and height in pixels of an upload.
+type Limits struct {
+	MaxBytes     int
+	MaxDimension int
+}
+
+// NewLimits parses the configured limits, falling back to the defaults for
+// empty or invalid values.
+func NewLimits(maxBytes, maxDimension string) Limits {
+	limits := Limits{
+		MaxBytes:     DefaultMaxBytes,
+		MaxDimension: DefaultMaxDimension,
+	}
+	if n, err := strconv.Atoi(maxBytes); err == nil && n > 0 {
+		limits.MaxBytes = n
+	}
+	if n, err := strconv.Atoi(maxDimension); err == nil && n > 0 {
+		limits.MaxDimension = n
+	}
+	return limits
+}
+
+// Validate checks the magic bytes of data against the accepted formats and
+// its size and dimensions against the limits. Only the image header is
+// decoded so oversized images are rejected before any pixel is allocated.
+func (l Limits) Validate(data []byte) error {
+	if len(data) > l.MaxBytes {
+		return fmt.Errorf("%w of %d bytes", ErrSize, l.MaxBytes)
+	}
+
+	sniffed, ok := Sniff(data)
+	if !ok {
+		return ErrType
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || format != sniffed {
+		return ErrInvalid
+	}
+
+	if config.Width > l.MaxDimension || config.Height > l.MaxDimension {
+		return fmt.Errorf("%w of %dx%d pixels", ErrDimensions, l.MaxDimension, l.MaxDimension)
+	}
+	return nil
+}
+
+// TooLarge reports whether err rejects an upload for its size or dimensions.
+func TooLarge(err error) bool {
+	return errors.Is(err, ErrSize) || errors.Is(err, ErrDimensions)
+}
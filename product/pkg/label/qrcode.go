@@ -0,0 +1,68 @@
+// Package label renders product codes as QR code images and prints shelf
+// labels, with the name, price and QR code of each product, on PDF sheets.
+package label
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Level is the error correction level of a QR code, the share of the symbol
+// that can be damaged and still be read.
+type Level string
+
+const (
+	LevelLow      Level = "L" // 7%
+	LevelMedium   Level = "M" // 15%, the default
+	LevelQuartile Level = "Q" // 25%
+	LevelHigh     Level = "H" // 30%
+)
+
+const (
+	DefaultSize = 256
+	MinSize     = 64
+	MaxSize     = 2048
+)
+
+var (
+	ErrLevel = errors.New("must be one of L, M, Q or H")
+	ErrSize  = fmt.Errorf("must be between %d and %d", MinSize, MaxSize)
+)
+
+var levels = map[Level]qrcode.RecoveryLevel{
+	LevelLow:      qrcode.Low,
+	LevelMedium:   qrcode.Medium,
+	LevelQuartile: qrcode.High,
+	LevelHigh:     qrcode.Highest,
+}
+
+// ParseLevel returns the level named by value, the empty value stands for
+// LevelMedium.
+func ParseLevel(value string) (Level, error) {
+	if value == "" {
+		return LevelMedium, nil
+	}
+	if _, ok := levels[Level(value)]; !ok {
+		return "", ErrLevel
+	}
+	return Level(value), nil
+}
+
+// QRCode renders content as a square PNG of size pixels with a quiet zone.
+func QRCode(content string, size int, level Level) ([]byte, error) {
+	if size < MinSize || size > MaxSize {
+		return nil, ErrSize
+	}
+	recovery, ok := levels[level]
+	if !ok {
+		return nil, ErrLevel
+	}
+
+	code, err := qrcode.New(content, recovery)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding qrcode: %w", err)
+	}
+	return code.PNG(size)
+}
@@ -0,0 +1,122 @@
+package label
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Label is the content of a shelf label, a label without code has no QR code.
+type Label struct {
+	Name  string
+	Price string
+	Code  string
+}
+
+// The sheet is A4 with 3 columns of 8 labels of 70x37 mm, the layout of the
+// common adhesive label sheets. Lengths are in millimeters.
+const (
+	columns     = 3
+	rows        = 8
+	perSheet    = columns * rows
+	labelWidth  = 70.0
+	labelHeight = 37.0
+	marginTop   = (297 - rows*labelHeight) / 2
+	padding     = 3.0
+	codeSize    = labelHeight - 2*padding
+	// codePixels renders the QR codes sharp enough for 300 dpi printers.
+	codePixels = 384
+)
+
+// Sheet prints labels in order on as many A4 pages as they need. Names are
+// written in the Go fonts, which cover Latin, Greek and Cyrillic scripts,
+// emoji and other characters outside the Basic Multilingual Plane are left out.
+func Sheet(labels []Label) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddUTF8FontFromBytes("go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("go", "B", gobold.TTF)
+
+	for i, label := range labels {
+		if i%perSheet == 0 {
+			pdf.AddPage()
+		}
+		var (
+			x = float64(i%columns) * labelWidth
+			y = marginTop + float64(i%perSheet/columns)*labelHeight
+		)
+		if err := write(pdf, label, x, y); err != nil {
+			return nil, err
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := pdf.Output(&buffer); err != nil {
+		return nil, fmt.Errorf("error writing labels: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// write writes a label with its top left corner at x, y: the QR code on the
+// left, the name, price and code on the right.
+func write(pdf *gofpdf.Fpdf, label Label, x, y float64) error {
+	var (
+		textX     = x + padding
+		textWidth = labelWidth - 2*padding
+	)
+	if label.Code != "" {
+		png, err := QRCode(label.Code, codePixels, LevelMedium)
+		if err != nil {
+			return err
+		}
+		pdf.RegisterImageOptionsReader(label.Code, gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+		pdf.ImageOptions(label.Code, x+padding, y+padding, codeSize, codeSize, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+		textX += codeSize + padding
+		textWidth -= codeSize + padding
+	}
+
+	pdf.SetFont("go", "", 9)
+	lines := pdf.SplitText(printable(label.Name), textWidth)
+	if len(lines) > 3 {
+		lines = lines[:3]
+	}
+	for i, line := range lines {
+		pdf.SetXY(textX, y+padding+float64(i)*4)
+		pdf.CellFormat(textWidth, 4, line, "", 0, "L", false, 0, "")
+	}
+
+	pdf.SetFont("go", "B", 14)
+	pdf.SetXY(textX, y+labelHeight-padding-13)
+	pdf.CellFormat(textWidth, 7, printable(label.Price), "", 0, "L", false, 0, "")
+
+	pdf.SetFont("go", "", 7)
+	pdf.SetXY(textX, y+labelHeight-padding-5)
+	pdf.CellFormat(textWidth, 5, label.Code, "", 0, "L", false, 0, "")
+
+	return pdf.Error()
+}
+
+// printable drops the runes outside the Basic Multilingual Plane, such as
+// emoji, which gofpdf can't measure and the Go fonts don't draw, and closes
+// the gaps they leave.
+func printable(text string) string {
+	if !strings.ContainsFunc(text, outsideBMP) {
+		return text
+	}
+	return strings.Join(strings.Fields(strings.Map(func(r rune) rune {
+		if outsideBMP(r) {
+			return -1
+		}
+		return r
+	}, text)), " ")
+}
+
+func outsideBMP(r rune) bool {
+	return r > 0xFFFF
+}
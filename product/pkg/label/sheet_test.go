@@ -0,0 +1,36 @@
+package label
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintable(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "latin", text: "Café crème", want: "Café crème"},
+		{name: "emoji", text: "Pizza 🍕 margherita", want: "Pizza margherita"},
+		{name: "emoji only", text: "🍕🍺", want: ""},
+		{name: "cyrillic", text: "Пицца", want: "Пицца"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := printable(tt.text); got != tt.want {
+				t.Errorf("printable(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSheetEmoji(t *testing.T) {
+	pdf, err := Sheet([]Label{{Name: "Pizza 🍕 margherita", Price: "9.50 EUR", Code: "PIZZA1"}})
+	if err != nil {
+		t.Fatalf("Sheet() error = %v", err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF")) {
+		t.Errorf("Sheet() = %.8q, want a PDF", pdf)
+	}
+}
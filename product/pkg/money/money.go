@@ -0,0 +1,89 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrency = errors.New("must be a valid ISO 4217 currency code")
+	ErrAmount   = errors.New("must be no less than 0")
+)
+
+// Money represents an amount as integer minor units (cents, for example)
+// together with its ISO 4217 currency code.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// New returns a Money value after validating the amount and currency.
+func New(amount int64, currency string) (Money, error) {
+	m := Money{
+		Amount:   amount,
+		Currency: strings.ToUpper(currency),
+	}
+	if err := m.Validate(); err != nil {
+		return Money{}, err
+	}
+	return m, nil
+}
+
+// FromFloat converts a legacy floating point price into minor units,
+// rounding half away from zero to the currency's precision.
+func FromFloat(amount float64, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	exponent, ok := currencies[currency]
+	if !ok {
+		return Money{}, ErrCurrency
+	}
+	minor := math.Round(amount * math.Pow10(exponent))
+	return New(int64(minor), currency)
+}
+
+// Validate checks that the amount is not negative and that the currency is a known ISO 4217 code.
+func (m Money) Validate() error {
+	if !IsCurrency(m.Currency) {
+		return ErrCurrency
+	}
+	if m.Amount < 0 {
+		return ErrAmount
+	}
+	return nil
+}
+
+// String formats the amount in major units followed by the currency code, e.g. "19.99 USD".
+func (m Money) String() string {
+	exponent := currencies[m.Currency]
+	if exponent == 0 {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+
+	var (
+		sign  = ""
+		abs   = m.Amount
+		scale = int64(math.Pow10(exponent))
+	)
+	if abs < 0 {
+		sign, abs = "-", -abs
+	}
+	return fmt.Sprintf("%s%d.%0*d %s", sign, abs/scale, exponent, abs%scale, m.Currency)
+}
+
+// IsCurrency reports whether code is a known ISO 4217 currency code.
+func IsCurrency(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
+
+// ParseCurrency normalizes and validates a currency code read from configuration.
+func ParseCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !IsCurrency(code) {
+		return "", fmt.Errorf("%w: %s", ErrCurrency, strconv.Quote(code))
+	}
+	return code, nil
+}
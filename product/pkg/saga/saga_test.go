@@ -0,0 +1,161 @@
+package saga
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var errStep = errors.New("step failed")
+
+type recorder struct {
+	calls []string
+}
+
+// action records name when called and returns err.
+func (r *recorder) action(name string, err error) Action {
+	return func(ctx context.Context) error {
+		if ctx.Err() != nil {
+			name += " (canceled)"
+		}
+		r.calls = append(r.calls, name)
+		return err
+	}
+}
+
+type step struct {
+	name       string
+	err        error
+	undo       bool
+	undoErr    error
+	finally    bool
+	finallyErr error
+}
+
+func TestSaga(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []step
+		cancel  bool
+		wantErr error
+		want    []string
+		wantLog []string
+	}{
+		{
+			name:  "all steps succeed",
+			steps: []step{{name: "a", undo: true}, {name: "b", undo: true}},
+			want:  []string{"a", "b"},
+		},
+		{
+			name:    "failed step compensates in reverse order",
+			steps:   []step{{name: "a", undo: true}, {name: "b", undo: true}, {name: "c", err: errStep, undo: true}, {name: "d", undo: true}},
+			wantErr: errStep,
+			want:    []string{"a", "b", "c", "undo b", "undo a"},
+		},
+		{
+			name:    "steps without compensation are skipped",
+			steps:   []step{{name: "a", undo: true}, {name: "b"}, {name: "c", err: errStep}},
+			wantErr: errStep,
+			want:    []string{"a", "b", "c", "undo a"},
+		},
+		{
+			name:    "first step failing compensates nothing",
+			steps:   []step{{name: "a", err: errStep, undo: true}},
+			wantErr: errStep,
+			want:    []string{"a"},
+		},
+		{
+			name: "compensation errors are logged and the others still run",
+			steps: []step{
+				{name: "a", undo: true},
+				{name: "b", undo: true, undoErr: errors.New("undo failed")},
+				{name: "c", err: errStep},
+			},
+			wantErr: errStep,
+			want:    []string{"a", "b", "c", "undo b", "undo a"},
+			wantLog: []string{"error compensating step", "undo failed"},
+		},
+		{
+			name:    "compensations run on a canceled context",
+			steps:   []step{{name: "a", undo: true}, {name: "b", err: errStep}},
+			cancel:  true,
+			wantErr: errStep,
+			want:    []string{"a (canceled)", "b (canceled)", "undo a"},
+		},
+		{
+			name: "finally runs after the steps and never compensates",
+			steps: []step{
+				{name: "a", undo: true},
+				{name: "b", finally: true, finallyErr: errors.New("cleanup failed")},
+				{name: "c", finally: true},
+			},
+			want:    []string{"a", "b", "c"},
+			wantLog: []string{"error completing step", "cleanup failed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				logs     bytes.Buffer
+				r        recorder
+				tx       = New(slog.New(slog.NewTextHandler(&logs, nil)))
+				ctx, end = context.WithCancel(context.Background())
+				err      error
+			)
+			defer end()
+			if tt.cancel {
+				end()
+			}
+
+			for _, s := range tt.steps {
+				if s.finally {
+					tx.Finally(ctx, s.name, r.action(s.name, s.finallyErr))
+					continue
+				}
+
+				var undo Action
+				if s.undo {
+					undo = r.action("undo "+s.name, s.undoErr)
+				}
+				if err = tx.Do(ctx, s.name, r.action(s.name, s.err), undo); err != nil {
+					break
+				}
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(r.calls, tt.want) {
+				t.Errorf("calls = %q, want %q", r.calls, tt.want)
+			}
+			for _, want := range tt.wantLog {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("logs = %q, want %q", logs.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestSagaCompensateOnce(t *testing.T) {
+	var (
+		r   recorder
+		tx  = New(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+		ctx = context.Background()
+	)
+
+	if err := tx.Do(ctx, "a", r.action("a", nil), r.action("undo a", nil)); err != nil {
+		t.Fatal(err)
+	}
+	tx.Compensate(ctx, "write", errStep)
+	tx.Compensate(ctx, "write", errStep)
+
+	want := []string{"a", "undo a"}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("calls = %q, want %q", r.calls, want)
+	}
+}
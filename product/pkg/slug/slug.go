@@ -0,0 +1,89 @@
+// Package slug turns names into lower case ASCII path segments such as
+// "cafe-creme" for "Café Crème".
+package slug
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MaxLength is the longest slug stored, generated slugs are cut to fit it.
+const MaxLength = 100
+
+var (
+	ErrSlug = errors.New("must contain only lower case letters, digits and single hyphens")
+
+	slugRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+)
+
+// Make returns the slug of name: letters are transliterated to ASCII, every
+// other run of characters becomes a single hyphen. The slug is empty when
+// name has nothing to transliterate, as for Chinese or Japanese names.
+func Make(name string) string {
+	var (
+		builder strings.Builder
+		hyphen  bool
+	)
+	for _, r := range strings.ToLower(name) {
+		ascii, ok := transliterations[r]
+		switch {
+		case ok:
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			ascii = string(r)
+		case r == '\'' || r == '’':
+			// apostrophes join words, "L'Oréal" is "loreal"
+			continue
+		default:
+			hyphen = builder.Len() > 0
+			continue
+		}
+		if ascii == "" {
+			continue
+		}
+		if hyphen {
+			builder.WriteByte('-')
+			hyphen = false
+		}
+		builder.WriteString(ascii)
+	}
+	return cut(builder.String(), MaxLength)
+}
+
+// Number returns the n-th candidate for a slug already in use, "cola-2" for
+// the second one. The first candidate is the slug itself.
+func Number(slug string, n int) string {
+	if n <= 1 {
+		return slug
+	}
+	suffix := "-" + strconv.Itoa(n)
+	return cut(slug, MaxLength-len(suffix)) + suffix
+}
+
+// Validate checks a slug sent by a client as a string or a string pointer, it
+// fits the validation.By rule.
+func Validate(value any) error {
+	var slug string
+	switch value := value.(type) {
+	case string:
+		slug = value
+	case *string:
+		if value != nil {
+			slug = *value
+		}
+	}
+	if slug != "" && !slugRegexp.MatchString(slug) {
+		return ErrSlug
+	}
+	return nil
+}
+
+// cut shortens slug to at most limit bytes without leaving a trailing hyphen.
+func cut(slug string, limit int) string {
+	if len(slug) > limit {
+		slug = slug[:limit]
+	}
+	return strings.TrimRight(slug, "-")
+}
@@ -0,0 +1,102 @@
+package slug
+
+// spellings lists the ASCII spelling of lower case Latin, Greek and Cyrillic
+// letters, accented letters lose their diacritics.
+var spellings = []struct {
+	ascii   string
+	letters string
+}{
+	// Latin
+	{"a", "àáâãäåāăąǎǟǡǻȁȃȧạảấầẩẫậắằẳẵặ"},
+	{"c", "çćĉċč"},
+	{"d", "ďđð"},
+	{"e", "èéêëēĕėęěȅȇȩẹẻẽếềểễệ"},
+	{"g", "ĝğġģǧǵ"},
+	{"h", "ĥħ"},
+	{"i", "ìíîïĩīĭįıǐȉȋỉị"},
+	{"j", "ĵǰ"},
+	{"k", "ķǩ"},
+	{"l", "ĺļľŀł"},
+	{"n", "ñńņňŉǹ"},
+	{"o", "òóôõöøōŏőơǒǫǭǿȍȏȫȭȯȱọỏốồổỗộớờởỡợ"},
+	{"r", "ŕŗřȑȓ"},
+	{"s", "śŝşšș"},
+	{"t", "ţťŧț"},
+	{"u", "ùúûüũūŭůűųưǔǖǘǚǜȕȗụủứừửữự"},
+	{"w", "ŵẁẃẅ"},
+	{"y", "ýÿŷȳỳỵỷỹ"},
+	{"z", "źżž"},
+	{"ae", "æǽǣ"},
+	{"ij", "ĳ"},
+	{"oe", "œ"},
+	{"ss", "ß"},
+	{"th", "þ"},
+
+	// Greek
+	{"a", "αά"},
+	{"v", "β"},
+	{"g", "γ"},
+	{"d", "δ"},
+	{"e", "εέ"},
+	{"z", "ζ"},
+	{"i", "ηήιίϊΐ"},
+	{"th", "θ"},
+	{"k", "κ"},
+	{"l", "λ"},
+	{"m", "μ"},
+	{"n", "ν"},
+	{"x", "ξ"},
+	{"o", "οόωώ"},
+	{"p", "π"},
+	{"r", "ρ"},
+	{"s", "σς"},
+	{"t", "τ"},
+	{"y", "υύϋΰ"},
+	{"f", "φ"},
+	{"ch", "χ"},
+	{"ps", "ψ"},
+
+	// Cyrillic
+	{"a", "а"},
+	{"b", "б"},
+	{"v", "в"},
+	{"g", "гґ"},
+	{"d", "д"},
+	{"e", "еэ"},
+	{"ye", "є"},
+	{"yo", "ё"},
+	{"zh", "ж"},
+	{"z", "з"},
+	{"i", "иіы"},
+	{"yi", "ї"},
+	{"y", "й"},
+	{"k", "к"},
+	{"l", "л"},
+	{"m", "м"},
+	{"n", "н"},
+	{"o", "о"},
+	{"p", "п"},
+	{"r", "р"},
+	{"s", "с"},
+	{"t", "т"},
+	{"u", "у"},
+	{"f", "ф"},
+	{"kh", "х"},
+	{"ts", "ц"},
+	{"ch", "ч"},
+	{"sh", "ш"},
+	{"shch", "щ"},
+	{"", "ъь"},
+	{"yu", "ю"},
+	{"ya", "я"},
+}
+
+var transliterations = func() map[rune]string {
+	table := make(map[rune]string)
+	for _, spelling := range spellings {
+		for _, letter := range spelling.letters {
+			table[letter] = spelling.ascii
+		}
+	}
+	return table
+}()
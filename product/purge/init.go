@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log"
+	"log/slog"
+	"os"
+	"shopy/internal/dynamodb"
+	"shopy/internal/s3"
+	"shopy/internal/scheduler"
+	"shopy/internal/service"
+)
+
+var handler *scheduler.Purge
+
+func init() {
+	dynamoClient, err := dynamodb.Connection()
+	if err != nil {
+		log.Fatalf("error connecting to dynamodb: %v", err)
+	}
+
+	s3Client, err := s3.Connection()
+	if err != nil {
+		log.Fatalf("error connecting to amazon S3: %v", err)
+	}
+
+	var (
+		logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+			AddSource: true,
+		}))
+		repository = dynamodb.NewProduct(logger, dynamoClient)
+		storage    = s3.NewProduct(logger, s3Client)
+		service    = service.NewProduct(logger, repository, storage)
+	)
+
+	handler = scheduler.NewPurge(logger, service)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"shopy/internal/dynamodb"
+)
+
+// Locks the QR codes of the products and variants stored before codes were
+// unique, legacy GS1 product codes are first stored in their GTIN-14 form.
+// Codes shared by several of them are logged for staff to fix.
+func main() {
+	dynamoClient, err := dynamodb.Connection()
+	if err != nil {
+		log.Fatalf("error connecting to dynamodb: %v", err)
+	}
+
+	var (
+		logger     = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+		repository = dynamodb.NewProduct(logger, dynamoClient)
+	)
+
+	locked, err := repository.LockQRCodes(context.Background())
+	if err != nil {
+		log.Fatalf("error locking qrcodes after %d items: %v", locked, err)
+	}
+
+	logger.Info("qrcode locking finished", "locked", locked)
+}
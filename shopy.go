@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigateway"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsdynamodb"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
 
@@ -33,6 +34,26 @@ func NewShopyStack(scope constructs.Construct, id string, props *ShopyStackProps
 		}),
 		AccessControl: awss3.BucketAccessControl_BUCKET_OWNER_FULL_CONTROL,
 		RemovalPolicy: awscdk.RemovalPolicy_RETAIN,
+		Cors: &[]*awss3.CorsRule{
+			{
+				AllowedMethods: &[]awss3.HttpMethods{awss3.HttpMethods_PUT},
+				AllowedOrigins: jsii.Strings("*"),
+				AllowedHeaders: jsii.Strings("*"),
+			},
+		},
+		LifecycleRules: &[]*awss3.LifecycleRule{
+			{
+				Id:         jsii.String("ExpireUploads"),
+				Prefix:     jsii.String("uploads/"),
+				Expiration: awscdk.Duration_Days(jsii.Number(1)),
+			},
+			{
+				// QR code images and label sheets are rendered again when missing
+				Id:         jsii.String("ExpireRenders"),
+				Prefix:     jsii.String("renders/"),
+				Expiration: awscdk.Duration_Days(jsii.Number(30)),
+			},
+		},
 	})
 
 	s3bucket.AddToResourcePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
@@ -44,8 +65,10 @@ func NewShopyStack(scope constructs.Construct, id string, props *ShopyStackProps
 
 	restapi := awsapigateway.NewRestApi(stack, jsii.String("ShopyApigateway"), &awsapigateway.RestApiProps{
 		RestApiName: jsii.String("shopy-restapi"),
+		// form uploads reach the lambdas base64 encoded instead of mangled as text
+		BinaryMediaTypes: jsii.Strings("multipart/form-data"),
 		DefaultCorsPreflightOptions: &awsapigateway.CorsOptions{
-			AllowHeaders: awsapigateway.Cors_DEFAULT_HEADERS(),
+			AllowHeaders: jsii.Strings("Content-Type", "X-Amz-Date", "Authorization", "X-Api-Key", "X-Amz-Security-Token", "X-Amz-User-Agent", "If-Match"),
 			AllowMethods: awsapigateway.Cors_ALL_METHODS(),
 			AllowOrigins: awsapigateway.Cors_ALL_ORIGINS(),
 		},
@@ -54,18 +77,54 @@ func NewShopyStack(scope constructs.Construct, id string, props *ShopyStackProps
 		},
 	})
 
+	// counts the products, variants and categories sharing the renditions
+	// stored under a content hash
+	imageTable := awsdynamodb.NewTable(stack, jsii.String("ImageReferenceDynamodb"), &awsdynamodb.TableProps{
+		TableName:     jsii.String("image_reference"),
+		RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("hash"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+	})
+
+	// reserves the current and former slugs of products and categories, keyed
+	// by kind and slug such as "product#cola"
+	slugTable := awsdynamodb.NewTable(stack, jsii.String("SlugDynamodb"), &awsdynamodb.TableProps{
+		TableName:     jsii.String("slug"),
+		RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("slug"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+	})
+
+	slugTable.AddGlobalSecondaryIndex(&awsdynamodb.GlobalSecondaryIndexProps{
+		IndexName: jsii.String("GSI_UUID"),
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("uuid"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		ProjectionType: awsdynamodb.ProjectionType_KEYS_ONLY,
+	})
+
 	version := restapi.Root().AddResource(jsii.String("v1"), nil)
 
-	NewCategoryStack(stack, &CategoryStackProps{
+	categoryTable := NewCategoryStack(stack, &CategoryStackProps{
 		StackProps: sprops,
 		s3bucket:   s3bucket,
+		imageTable: imageTable,
+		slugTable:  slugTable,
 		version:    version,
 	})
 
 	NewProductStack(stack, &ProductStackProps{
-		StackProps: sprops,
-		s3bucket:   s3bucket,
-		version:    version,
+		StackProps:    sprops,
+		s3bucket:      s3bucket,
+		imageTable:    imageTable,
+		categoryTable: categoryTable,
+		slugTable:     slugTable,
+		version:       version,
 	})
 
 	NewUserStack(stack, &UserStackProps{